@@ -0,0 +1,187 @@
+// Package actor provides StateOwner, the generic version of the stateful goroutine from the goroutine example.
+// the state is owned by a single goroutine, in order to read or write it other goroutines send messages
+// to the owning goroutine and receive corresponding replies, so no mutex is needed around the map.
+package actor
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by the write operations once the owner has shut down
+var ErrClosed = errors.New("actor: state owner is closed")
+
+// readOp asks for a single key, or for a copy of the whole map when all is set
+type readOp[K comparable, V any] struct {
+	key  K
+	all  bool
+	resp chan readResult[K, V]
+}
+
+type readResult[K comparable, V any] struct {
+	val      V
+	ok       bool
+	snapshot map[K]V
+}
+
+// writeOp changes a single key. apply receives the current value and whether it exists,
+// and returns the new value and whether the key should be kept; this covers Set, Delete and Update
+type writeOp[K comparable, V any] struct {
+	key   K
+	apply func(old V, ok bool) (V, bool)
+	resp  chan V
+}
+
+// StateOwner owns a map[K]V in a dedicated goroutine and serves Get, Set, Delete, Update and Snapshot
+// requests one at a time. once it shuts down, operations already received are still answered,
+// reads keep working on the final state and writes return ErrClosed
+type StateOwner[K comparable, V any] struct {
+	reads  chan readOp[K, V]
+	writes chan writeOp[K, V]
+
+	stop     chan struct{} // closed to ask the owner to shut down
+	stopOnce sync.Once
+	done     chan struct{} // closed once the owner has exited
+	final    map[K]V       // the state after shutdown, only read after done is closed
+
+	readOps  atomic.Uint64
+	writeOps atomic.Uint64
+}
+
+// New starts the owner goroutine. it runs until ctx is done or Close is called
+func New[K comparable, V any](ctx context.Context) *StateOwner[K, V] {
+	s := &StateOwner[K, V]{
+		reads:  make(chan readOp[K, V]),
+		writes: make(chan writeOp[K, V]),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.own(ctx)
+	return s
+}
+
+// own is the owning goroutine. it repeatedly selects on the reads and writes channels,
+// responding to requests as they arrive
+func (s *StateOwner[K, V]) own(ctx context.Context) {
+	state := make(map[K]V)
+	defer func() {
+		s.final = state
+		close(s.done)
+	}()
+	for {
+		select {
+		case read := <-s.reads:
+			s.serveRead(state, read)
+		case write := <-s.writes:
+			s.serveWrite(state, write)
+		case <-ctx.Done():
+			return
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *StateOwner[K, V]) serveRead(state map[K]V, read readOp[K, V]) {
+	var r readResult[K, V]
+	if read.all {
+		r.snapshot = maps.Clone(state)
+	} else {
+		r.val, r.ok = state[read.key]
+	}
+	s.readOps.Add(1)
+	read.resp <- r
+}
+
+func (s *StateOwner[K, V]) serveWrite(state map[K]V, write writeOp[K, V]) {
+	old, ok := state[write.key]
+	val, keep := write.apply(old, ok)
+	if keep {
+		state[write.key] = val
+	} else {
+		delete(state, write.key)
+	}
+	s.writeOps.Add(1)
+	write.resp <- val
+}
+
+// read sends a read request, or serves it from the final state once the owner is gone
+func (s *StateOwner[K, V]) read(op readOp[K, V]) readResult[K, V] {
+	op.resp = make(chan readResult[K, V], 1)
+	select {
+	case s.reads <- op:
+		// once the owner has received the op it always answers, even if it is shutting down
+		return <-op.resp
+	case <-s.done:
+		s.serveRead(s.final, op)
+		return <-op.resp
+	}
+}
+
+// write sends a write request, it fails with ErrClosed once the owner is gone
+func (s *StateOwner[K, V]) write(key K, apply func(V, bool) (V, bool)) (V, error) {
+	op := writeOp[K, V]{key: key, apply: apply, resp: make(chan V, 1)}
+	select {
+	case s.writes <- op:
+		return <-op.resp, nil
+	case <-s.done:
+		var zero V
+		return zero, ErrClosed
+	}
+}
+
+// Get returns the value of key and whether it exists
+func (s *StateOwner[K, V]) Get(key K) (V, bool) {
+	r := s.read(readOp[K, V]{key: key})
+	return r.val, r.ok
+}
+
+// Snapshot returns a copy of the whole state, later changes don't affect it
+func (s *StateOwner[K, V]) Snapshot() map[K]V {
+	return s.read(readOp[K, V]{all: true}).snapshot
+}
+
+// Set stores val under key
+func (s *StateOwner[K, V]) Set(key K, val V) error {
+	_, err := s.write(key, func(V, bool) (V, bool) { return val, true })
+	return err
+}
+
+// Delete removes key, deleting a missing key is not an error
+func (s *StateOwner[K, V]) Delete(key K) error {
+	_, err := s.write(key, func(V, bool) (V, bool) {
+		var zero V
+		return zero, false
+	})
+	return err
+}
+
+// Update replaces the value of key with f(old) atomically and returns the new value.
+// old is the zero value if key doesn't exist. f runs on the owner goroutine, so it must not call back into s
+func (s *StateOwner[K, V]) Update(key K, f func(V) V) (V, error) {
+	return s.write(key, func(old V, _ bool) (V, bool) { return f(old), true })
+}
+
+// Close shuts the owner down and waits for it to exit. it is safe to call Close more than once
+func (s *StateOwner[K, V]) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// Done returns a channel that is closed once the owner has exited
+func (s *StateOwner[K, V]) Done() <-chan struct{} {
+	return s.done
+}
+
+// ReadOps returns the number of Get and Snapshot operations served so far
+func (s *StateOwner[K, V]) ReadOps() uint64 {
+	return s.readOps.Load()
+}
+
+// WriteOps returns the number of Set, Delete and Update operations served so far
+func (s *StateOwner[K, V]) WriteOps() uint64 {
+	return s.writeOps.Load()
+}
@@ -0,0 +1,150 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+)
+
+func TestGetSetDelete(t *testing.T) {
+	s := New[string, int](context.Background())
+	defer s.Close()
+
+	if _, ok := s.Get("a"); ok {
+		t.Error("Get on an empty state returned ok")
+	}
+	if err := s.Set("a", 1); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, ok := s.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %t; want 1, true", v, ok)
+	}
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := s.Get("a"); ok {
+		t.Error("Get after Delete returned ok")
+	}
+	// deleting a missing key is fine
+	if err := s.Delete("missing"); err != nil {
+		t.Errorf("Delete(missing) = %v", err)
+	}
+}
+
+func TestUpdateIsAtomic(t *testing.T) {
+	s := New[string, int](context.Background())
+	defer s.Close()
+
+	// the read-modify-write happens on the owner goroutine, so no increment is lost
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				s.Update("n", func(v int) int { return v + 1 })
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := s.Get("n"); v != 5000 {
+		t.Errorf("n = %d; want 5000", v)
+	}
+	if got := s.WriteOps(); got != 5000 {
+		t.Errorf("WriteOps() = %d; want 5000", got)
+	}
+}
+
+func TestSnapshotIsACopy(t *testing.T) {
+	s := New[int, string](context.Background())
+	defer s.Close()
+	s.Set(1, "one")
+	s.Set(2, "two")
+
+	snap := s.Snapshot()
+	want := map[int]string{1: "one", 2: "two"}
+	if !maps.Equal(snap, want) {
+		t.Errorf("Snapshot() = %v; want %v", snap, want)
+	}
+	snap[3] = "three"
+	s.Set(1, "uno")
+	if _, ok := s.Get(3); ok {
+		t.Error("changing the snapshot changed the state")
+	}
+	if snap[1] != "one" {
+		t.Error("changing the state changed the snapshot")
+	}
+}
+
+func TestOpCounters(t *testing.T) {
+	s := New[int, int](context.Background())
+	defer s.Close()
+	s.Set(1, 1)
+	s.Delete(1)
+	s.Get(1)
+	s.Get(2)
+	s.Snapshot()
+	if s.ReadOps() != 3 || s.WriteOps() != 2 {
+		t.Errorf("ReadOps, WriteOps = %d, %d; want 3, 2", s.ReadOps(), s.WriteOps())
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New[string, int](ctx)
+	s.Set("a", 1)
+
+	cancel()
+	<-s.Done()
+
+	// writes fail once the owner is gone, reads are served from the final state
+	if err := s.Set("b", 2); !errors.Is(err, ErrClosed) {
+		t.Errorf("Set after shutdown = %v; want %v", err, ErrClosed)
+	}
+	if _, err := s.Update("a", func(v int) int { return v + 1 }); !errors.Is(err, ErrClosed) {
+		t.Errorf("Update after shutdown = %v; want %v", err, ErrClosed)
+	}
+	if v, ok := s.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) after shutdown = %d, %t; want 1, true", v, ok)
+	}
+	if snap := s.Snapshot(); !maps.Equal(snap, map[string]int{"a": 1}) {
+		t.Errorf("Snapshot after shutdown = %v", snap)
+	}
+	s.Close() // Close after the context already stopped the owner is fine
+}
+
+func TestShutdownDrainsInFlightOps(t *testing.T) {
+	s := New[int, int](context.Background())
+
+	// every write either lands in the final state or reports ErrClosed, none is lost in between
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := map[int]bool{}
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.Set(i, i) == nil {
+				mu.Lock()
+				accepted[i] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	s.Close()
+	wg.Wait()
+
+	final := s.Snapshot()
+	for i := range 100 {
+		if accepted[i] != hasKey(final, i) {
+			t.Errorf("key %d: accepted=%t but present in final state=%t", i, accepted[i], hasKey(final, i))
+		}
+	}
+}
+
+func hasKey(m map[int]int, k int) bool {
+	_, ok := m[k]
+	return ok
+}
@@ -0,0 +1,205 @@
+package basics
+
+import "iter"
+
+// As an example of a generic function, SlicesIndex takes a slice of any comparable
+// type and an element of that type and returns the index of the first occurrence of
+// v in s, or -1 if not present. The comparable constraint means that we can compare
+//  values of this type with the == and != operators
+func SlicesIndex[S ~[]E, E comparable](s S, v E) int {
+	for i := range s {
+		if v == s[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// As an example of a generic type, List is a doubly-linked list with values of any type.
+// the zero value is an empty list ready to use.
+type List[T any] struct {
+	head, tail *Element[T]
+	len        int // number of elements, kept up to date so Len is O(1)
+}
+
+// Element is a single node of the list, like list.Element of container/list. Push and friends return it,
+// a caller holding it reads Value or passes it back to InsertAfter and Remove.
+// prev and next make it possible to unlink a node in O(1), list points back to the owning list so that nodes from another list are rejected
+type Element[T any] struct {
+	prev, next *Element[T]
+	list       *List[T]
+	Value      T // the value stored in the element, it can be changed in place
+}
+
+// Next returns the element after e, or nil if e is the last one
+func (e *Element[T]) Next() *Element[T] {
+	return e.next
+}
+
+// Prev returns the element before e, or nil if e is the first one
+func (e *Element[T]) Prev() *Element[T] {
+	return e.prev
+}
+
+//We can define methods on generic types just like we do on regular types,
+// but we have to keep the type parameters in place. The type is List[T], not List.
+// Push appends v to the back of the list and returns its element
+func (lst *List[T]) Push(v T) *Element[T] {
+	e := &Element[T]{Value: v, list: lst}
+	if lst.tail == nil {
+		lst.head = e
+		lst.tail = lst.head
+	} else {
+		e.prev = lst.tail
+		lst.tail.next = e
+		lst.tail = lst.tail.next
+	}
+	lst.len++
+	return e
+}
+
+// PushFront inserts v at the front of the list and returns its element
+func (lst *List[T]) PushFront(v T) *Element[T] {
+	e := &Element[T]{Value: v, list: lst}
+	if lst.head == nil {
+		lst.head = e
+		lst.tail = e
+	} else {
+		e.next = lst.head
+		lst.head.prev = e
+		lst.head = e
+	}
+	lst.len++
+	return e
+}
+
+// InsertAfter inserts v right after mark and returns the new element.
+// if mark is nil or does not belong to lst, the list is left untouched and nil is returned
+func (lst *List[T]) InsertAfter(mark *Element[T], v T) *Element[T] {
+	if mark == nil || mark.list != lst {
+		return nil
+	}
+	if mark == lst.tail {
+		return lst.Push(v)
+	}
+	e := &Element[T]{Value: v, list: lst, prev: mark, next: mark.next}
+	mark.next.prev = e
+	mark.next = e
+	lst.len++
+	return e
+}
+
+// Remove unlinks e from the list in O(1) and returns its value.
+// the bool is false if e is nil or does not belong to lst
+func (lst *List[T]) Remove(e *Element[T]) (T, bool) {
+	if e == nil || e.list != lst {
+		var zero T
+		return zero, false
+	}
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		lst.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		lst.tail = e.prev
+	}
+	// clear the links so a stale element can't reach into the list anymore
+	e.prev, e.next, e.list = nil, nil, nil
+	lst.len--
+	return e.Value, true
+}
+
+// PopFront removes and returns the first value, the bool is false when the list is empty
+func (lst *List[T]) PopFront() (T, bool) {
+	return lst.Remove(lst.head)
+}
+
+// PopBack removes and returns the last value, the bool is false when the list is empty
+func (lst *List[T]) PopBack() (T, bool) {
+	return lst.Remove(lst.tail)
+}
+
+// Front returns the first element of the list, or nil if the list is empty
+func (lst *List[T]) Front() *Element[T] {
+	return lst.head
+}
+
+// Back returns the last element of the list, or nil if the list is empty
+func (lst *List[T]) Back() *Element[T] {
+	return lst.tail
+}
+
+// Len returns the number of elements in the list
+func (lst *List[T]) Len() int {
+	return lst.len
+}
+
+// Reverse reverses the list in place by swapping the links of every element
+func (lst *List[T]) Reverse() {
+	for e := lst.head; e != nil; e = e.prev { // after the swap, prev points to the old next
+		e.prev, e.next = e.next, e.prev
+	}
+	lst.head, lst.tail = lst.tail, lst.head
+}
+
+// Clear removes all the elements from the list
+func (lst *List[T]) Clear() {
+	// unlink every element so that handles kept by callers no longer belong to lst
+	for e := lst.head; e != nil; {
+		next := e.next
+		e.prev, e.next, e.list = nil, nil, nil
+		e = next
+	}
+	lst.head, lst.tail = nil, nil
+	lst.len = 0
+}
+
+// AllElements returns all the List elements as a slice.
+func (lst *List[T]) AllElements() []T {
+	var elems []T
+	for e := lst.head; e != nil; e = e.next {
+		elems = append(elems, e.Value)
+	}
+	return elems
+}
+
+// all returns an iterator
+func (lst *List[T]) All() iter.Seq[T] {
+	// the iterator function takes another function as a parameter,
+	// it will call yield for every element we want to iterate over
+	return func(yield func(T) bool) {
+		for e := lst.head; e != nil; e = e.next {
+			if !yield(e.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator that walks the list from the back to the front
+func (lst *List[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for e := lst.tail; e != nil; e = e.prev {
+			if !yield(e.Value) {
+				return
+			}
+		}
+	}
+}
+
+// iteration doesn't require underlying data structure
+func genFib() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		a, b := 1, 1
+
+		for { // doesn't need to be finite, can keep on yielding
+			if !yield(a) {
+				return
+			}
+			a, b = b, a+b
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package basics
+
+import (
+	"slices"
+	"testing"
+)
+
+// newIntList builds a list holding vals in order
+func newIntList(vals ...int) *List[int] {
+	lst := &List[int]{}
+	for _, v := range vals {
+		lst.Push(v)
+	}
+	return lst
+}
+
+// checkList compares the list against want in both directions, so broken prev links are caught too
+func checkList(t *testing.T, lst *List[int], want []int) {
+	t.Helper()
+	if got := lst.AllElements(); !slices.Equal(got, want) {
+		t.Errorf("AllElements() = %v; want %v", got, want)
+	}
+	backward := slices.Clone(want)
+	slices.Reverse(backward)
+	if got := slices.Collect(lst.Backward()); !slices.Equal(got, backward) {
+		t.Errorf("Backward() = %v; want %v", got, backward)
+	}
+	if lst.Len() != len(want) {
+		t.Errorf("Len() = %d; want %d", lst.Len(), len(want))
+	}
+}
+
+func TestListPushFront(t *testing.T) {
+	lst := &List[int]{}
+	lst.PushFront(2)
+	lst.PushFront(1)
+	lst.Push(3)
+	checkList(t, lst, []int{1, 2, 3})
+}
+
+func TestListElementValue(t *testing.T) {
+	lst := newIntList(1, 2)
+	e := lst.Push(3)
+	if e.Value != 3 || lst.Front().Next().Value != 2 || e.Prev().Value != 2 {
+		t.Errorf("values = %d, %d, %d; want 3, 2, 2", e.Value, lst.Front().Next().Value, e.Prev().Value)
+	}
+	e.Value = 30 // changed in place, like list.Element
+	checkList(t, lst, []int{1, 2, 30})
+}
+
+func TestListPop(t *testing.T) {
+	lst := newIntList(1, 2, 3)
+
+	if v, ok := lst.PopFront(); !ok || v != 1 {
+		t.Errorf("PopFront() = %d, %t; want 1, true", v, ok)
+	}
+	if v, ok := lst.PopBack(); !ok || v != 3 {
+		t.Errorf("PopBack() = %d, %t; want 3, true", v, ok)
+	}
+	checkList(t, lst, []int{2})
+
+	lst.PopBack()
+	checkList(t, lst, nil)
+	// popping an empty list reports false instead of panicking
+	if _, ok := lst.PopFront(); ok {
+		t.Error("PopFront() on empty list returned ok")
+	}
+	if _, ok := lst.PopBack(); ok {
+		t.Error("PopBack() on empty list returned ok")
+	}
+}
+
+func TestListInsertAfter(t *testing.T) {
+	var tests = []struct {
+		name string
+		mark int // index of the element to insert after
+		want []int
+	}{
+		{"head", 0, []int{1, 9, 2, 3}},
+		{"middle", 1, []int{1, 2, 9, 3}},
+		{"tail", 2, []int{1, 2, 3, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lst := newIntList(1, 2, 3)
+			mark := lst.Front()
+			for range tt.mark {
+				mark = mark.Next()
+			}
+			if e := lst.InsertAfter(mark, 9); e == nil || e.Value != 9 {
+				t.Fatalf("InsertAfter returned %v", e)
+			}
+			checkList(t, lst, tt.want)
+		})
+	}
+}
+
+func TestListInsertAfterForeignElement(t *testing.T) {
+	lst := newIntList(1)
+	other := newIntList(2)
+	if e := lst.InsertAfter(other.Front(), 9); e != nil {
+		t.Errorf("InsertAfter with a foreign mark = %v; want nil", e)
+	}
+	if e := lst.InsertAfter(nil, 9); e != nil {
+		t.Errorf("InsertAfter with a nil mark = %v; want nil", e)
+	}
+	checkList(t, lst, []int{1})
+	checkList(t, other, []int{2})
+}
+
+func TestListRemove(t *testing.T) {
+	lst := &List[int]{}
+	first := lst.Push(1)
+	middle := lst.Push(2)
+	last := lst.Push(3)
+
+	if v, ok := lst.Remove(middle); !ok || v != 2 {
+		t.Errorf("Remove(middle) = %d, %t; want 2, true", v, ok)
+	}
+	checkList(t, lst, []int{1, 3})
+
+	// removing the same element twice must not corrupt the list
+	if _, ok := lst.Remove(middle); ok {
+		t.Error("second Remove(middle) returned ok")
+	}
+	lst.Remove(first)
+	lst.Remove(last)
+	checkList(t, lst, nil)
+	if lst.Front() != nil || lst.Back() != nil {
+		t.Error("empty list still has a front or back")
+	}
+}
+
+func TestListReverse(t *testing.T) {
+	var tests = []struct {
+		in, want []int
+	}{
+		{nil, nil},
+		{[]int{1}, []int{1}},
+		{[]int{1, 2}, []int{2, 1}},
+		{[]int{1, 2, 3, 4}, []int{4, 3, 2, 1}},
+	}
+	for _, tt := range tests {
+		lst := newIntList(tt.in...)
+		lst.Reverse()
+		checkList(t, lst, tt.want)
+		// the list must keep working after a reverse
+		lst.Push(5)
+		checkList(t, lst, append(slices.Clone(tt.want), 5))
+	}
+}
+
+func TestListClear(t *testing.T) {
+	lst := newIntList(1, 2, 3)
+	e := lst.Front()
+	lst.Clear()
+	checkList(t, lst, nil)
+	// elements handed out before Clear no longer belong to the list
+	if _, ok := lst.Remove(e); ok {
+		t.Error("Remove of a cleared element returned ok")
+	}
+	lst.Push(4)
+	checkList(t, lst, []int{4})
+}
+
+func TestListIteratorsStopEarly(t *testing.T) {
+	lst := newIntList(1, 2, 3)
+	var got []int
+	for v := range lst.Backward() {
+		got = append(got, v)
+		if v == 2 {
+			break
+		}
+	}
+	if !slices.Equal(got, []int{3, 2}) {
+		t.Errorf("Backward with break = %v; want [3 2]", got)
+	}
+}
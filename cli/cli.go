@@ -0,0 +1,378 @@
+// Package cli dispatches subcommands, where the commandline example switches on its first argument by hand.
+// a Command has its own flag.FlagSet, subcommands, aliases, and global flags that the commands below it accept too:
+//
+//	root := &cli.Command{
+//		Name:   "app",
+//		Global: globalFlags, // -v works after any command name
+//		Commands: []*cli.Command{
+//			{Name: "serve", Aliases: []string{"s"}, Flags: serveFlags, Run: serve},
+//			{Name: "db", Commands: []*cli.Command{{Name: "migrate", Run: migrate}}},
+//		},
+//	}
+//	os.Exit(root.Execute(ctx, os.Stdout, os.Stderr, os.Args[1:]))
+//
+// the usage text is generated from the commands and the flag sets, a mistyped command gets a "did you mean" suggestion,
+// and the exit codes follow the exit example: 0 for success, 1 for a failure, 2 for a wrong command line, or the code of an ExitError.
+// the commands and flags complete in bash, zsh and fish, see CompletionCommand
+package cli
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// exit codes, like in the exit example a non-zero status tells the caller something went wrong
+const (
+	ExitOK      = 0
+	ExitFailure = 1 // the command returned an error
+	ExitUsage   = 2 // the command line is wrong, this is also what the flag package exits with
+)
+
+// Command is a command or a group of subcommands
+type Command struct {
+	// Name is what selects the command after its parent. for the root it is the program, which can be several words like "oneforall commandline"
+	Name    string
+	Aliases []string
+	// Summary is a line shown in the list of commands of the parent and at the top of the usage
+	Summary string
+	// Args describes the arguments after the flags in the usage line, like "[files...]"
+	Args string
+	// Flags are the flags of the command, nil for none
+	Flags *flag.FlagSet
+	// Global are flags accepted by the command and all the commands below it
+	Global   *flag.FlagSet
+	Commands []*Command
+	// Run runs the command with the arguments left after the flags. it is nil for a command that only groups subcommands
+	Run func(ctx context.Context, w io.Writer, args []string) error
+	// Hidden commands run, but aren't listed nor completed
+	Hidden bool
+	// Complete completes the values of the flags by flag name, and the arguments with the empty name
+	Complete map[string]Completer
+
+	parent *Command
+	parsed *flag.FlagSet // the flags parsed after the name of the command by the last Exec
+}
+
+// UsageError is a wrong command line: an unknown command or flag, or a missing command
+type UsageError struct {
+	Command *Command // the command whose arguments are wrong
+	Err     error
+}
+
+func (e *UsageError) Error() string {
+	return fmt.Sprintf("%v, see '%s -h'", e.Err, e.Command.Path())
+}
+
+func (e *UsageError) Unwrap() error { return e.Err }
+
+// ExitError is returned by Run to exit with Code. unlike os.Exit in the exit example, the deferred functions still run
+type ExitError struct {
+	Code int
+	Err  error // may be nil to exit without a message
+}
+
+// Exit returns an ExitError
+func Exit(code int, err error) error {
+	return &ExitError{Code: code, Err: err}
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// ExitCode returns the exit code for the error returned by Exec: ExitOK for nil and for -h,
+// ExitUsage for a UsageError, the code of an ExitError, ExitFailure otherwise
+func ExitCode(err error) int {
+	var ue *UsageError
+	var ee *ExitError
+	switch {
+	case err == nil || errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &ue):
+		return ExitUsage
+	case errors.As(err, &ee):
+		return ee.Code
+	}
+	return ExitFailure
+}
+
+// Execute runs Exec, reports its error on stderr and returns the exit code, for main to pass to os.Exit
+func (c *Command) Execute(ctx context.Context, stdout, stderr io.Writer, args []string) int {
+	err := c.Exec(ctx, stdout, args)
+	var ee *ExitError
+	if err != nil && !errors.Is(err, flag.ErrHelp) && !(errors.As(err, &ee) && ee.Err == nil) {
+		fmt.Fprintf(stderr, "%s: %v\n", c.Name, err)
+	}
+	return ExitCode(err)
+}
+
+// Exec finds the command named by args and runs it, parsing the flags on the way.
+// -h prints the usage of the command to w and returns flag.ErrHelp, "help <command>" prints the usage of the command too.
+// a wrong command line returns a *UsageError
+func (c *Command) Exec(ctx context.Context, w io.Writer, args []string) error {
+	c.link()
+	if len(args) > 0 && args[0] == CompleteCommand {
+		return c.complete(w, args[1:])
+	}
+	cmd := c
+	for {
+		fs := cmd.flagSet()
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				cmd.Usage(w)
+				return err
+			}
+			return &UsageError{Command: cmd, Err: err}
+		}
+		cmd.parsed = fs
+		args = fs.Args()
+		if len(cmd.Commands) == 0 {
+			break
+		}
+		if len(args) > 0 {
+			if sub := cmd.find(args[0]); sub != nil {
+				cmd, args = sub, args[1:]
+				continue
+			}
+			if args[0] == "help" && cmd.find("help") == nil {
+				return cmd.help(w, args[1:])
+			}
+		}
+		if cmd.Run != nil {
+			break // the command takes arguments as well as subcommands
+		}
+		if len(args) == 0 {
+			return &UsageError{Command: cmd, Err: fmt.Errorf("missing command, want one of %s", strings.Join(cmd.names(), ", "))}
+		}
+		return &UsageError{Command: cmd, Err: cmd.unknown(args[0])}
+	}
+	if cmd.Run == nil {
+		return &UsageError{Command: cmd, Err: errors.New("nothing to run")}
+	}
+	return cmd.Run(ctx, w, args)
+}
+
+// help prints the usage of the command named by args, below c
+func (c *Command) help(w io.Writer, args []string) error {
+	cmd := c
+	for _, name := range args {
+		sub := cmd.find(name)
+		if sub == nil {
+			return &UsageError{Command: cmd, Err: cmd.unknown(name)}
+		}
+		cmd = sub
+	}
+	cmd.Usage(w)
+	return nil
+}
+
+// link sets the parents of the commands below c and forgets the flags parsed by the last Exec
+func (c *Command) link() {
+	c.parsed = nil
+	for _, sub := range c.Commands {
+		sub.parent = c
+		sub.link()
+	}
+}
+
+// Visit calls fn for each flag set on the command line by the last Exec, after the name of c or of a command below it,
+// the global flags included. a flag given after several command names is visited once for each
+func (c *Command) Visit(fn func(*flag.Flag)) {
+	if c.parsed != nil {
+		c.parsed.Visit(fn)
+	}
+	for _, sub := range c.Commands {
+		sub.Visit(fn)
+	}
+}
+
+// Path returns the names of the command and its parents, like "oneforall commandline foo"
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+// find returns the subcommand named or aliased name
+func (c *Command) find(name string) *Command {
+	for _, sub := range c.Commands {
+		if sub.Name == name || slices.Contains(sub.Aliases, name) {
+			return sub
+		}
+	}
+	return nil
+}
+
+// names returns the names of the visible subcommands
+func (c *Command) names() []string {
+	var names []string
+	for _, sub := range c.Commands {
+		if !sub.Hidden {
+			names = append(names, sub.Name)
+		}
+	}
+	return names
+}
+
+// flagSet returns a flag set with the flags of the command and the global flags of the command and its parents.
+// the flags share their values with the original sets, parsing sets the variables they were declared with
+func (c *Command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.Path(), flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // the errors are returned, the usage is printed by Exec
+	add := func(from *flag.FlagSet) {
+		if from == nil {
+			return
+		}
+		from.VisitAll(func(f *flag.Flag) {
+			if fs.Lookup(f.Name) == nil { // a command's own flag hides a global one with the same name
+				fs.Var(f.Value, f.Name, f.Usage)
+				fs.Lookup(f.Name).DefValue = f.DefValue
+			}
+		})
+	}
+	add(c.Flags)
+	for p := c; p != nil; p = p.parent {
+		add(p.Global)
+	}
+	return fs
+}
+
+// globals returns the global flags that apply to the command, its own and its parents'
+func (c *Command) globals() []*flag.FlagSet {
+	var sets []*flag.FlagSet
+	for p := c; p != nil; p = p.parent {
+		if p.Global != nil {
+			sets = append(sets, p.Global)
+		}
+	}
+	return sets
+}
+
+// Usage writes the usage of the command: the usage line, the summary, the subcommands and the flags
+func (c *Command) Usage(w io.Writer) {
+	line := c.Path()
+	if c.Flags != nil || len(c.globals()) > 0 {
+		line += " [flags]"
+	}
+	if len(c.Commands) > 0 {
+		line += " <command>"
+	}
+	if c.Args != "" {
+		line += " " + c.Args
+	}
+	fmt.Fprintf(w, "usage: %s\n", line)
+	if c.Summary != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Summary)
+	}
+	if names := c.names(); len(names) > 0 {
+		fmt.Fprintln(w, "\ncommands:")
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		for _, sub := range c.Commands {
+			if !sub.Hidden {
+				fmt.Fprintf(tw, "  %s\t%s\n", strings.Join(append([]string{sub.Name}, sub.Aliases...), ", "), sub.Summary)
+			}
+		}
+		tw.Flush()
+	}
+	printFlags := func(title string, sets ...*flag.FlagSet) {
+		fs := flag.NewFlagSet("", flag.ContinueOnError)
+		for _, set := range sets {
+			set.VisitAll(func(f *flag.Flag) {
+				if fs.Lookup(f.Name) == nil && (set == c.Flags || c.Flags == nil || c.Flags.Lookup(f.Name) == nil) {
+					fs.Var(f.Value, f.Name, f.Usage)
+					fs.Lookup(f.Name).DefValue = f.DefValue
+				}
+			})
+		}
+		n := 0
+		fs.VisitAll(func(*flag.Flag) { n++ })
+		if n == 0 {
+			return
+		}
+		fmt.Fprintf(w, "\n%s:\n", title)
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+	if c.Flags != nil {
+		printFlags("flags", c.Flags)
+	}
+	printFlags("global flags", c.globals()...)
+	if len(c.Commands) > 0 {
+		fmt.Fprintf(w, "\nrun '%s help <command>' for the usage of a command\n", c.Path())
+	}
+}
+
+// unknown is the error for an unknown subcommand, with the closest names as suggestions
+func (c *Command) unknown(name string) error {
+	if s := c.suggest(name); len(s) > 0 {
+		return fmt.Errorf("unknown command %q, did you mean %s?", name, strings.Join(s, " or "))
+	}
+	return fmt.Errorf("unknown command %q, want one of %s", name, strings.Join(c.names(), ", "))
+}
+
+// suggest returns the names of the visible subcommands close to name: a prefix of it, or a few typos away, the closest first
+func (c *Command) suggest(name string) []string {
+	type match struct {
+		name string
+		dist int
+	}
+	var matches []match
+	for _, sub := range c.Commands {
+		if sub.Hidden {
+			continue
+		}
+		best := -1
+		for _, n := range append([]string{sub.Name}, sub.Aliases...) {
+			d := distance(strings.ToLower(name), strings.ToLower(n))
+			if strings.HasPrefix(n, name) {
+				d = 0
+			}
+			if d <= max(1, len(n)/3) && (best < 0 || d < best) {
+				best = d
+			}
+		}
+		if best >= 0 {
+			matches = append(matches, match{sub.Name, best})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int { return cmp.Compare(a.dist, b.dist) })
+	var out []string
+	for _, m := range matches {
+		out = append(out, m.name)
+	}
+	return out
+}
+
+// distance is the Levenshtein distance between a and b: the number of letters to insert, delete or change to turn a into b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
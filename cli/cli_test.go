@@ -0,0 +1,298 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// app is a small tree of commands recording what ran in ran, and the values of the flags
+type app struct {
+	root    *Command
+	ran     string
+	args    []string
+	verbose bool
+	port    int
+	force   bool
+}
+
+func newApp() *app {
+	a := &app{}
+	global := flag.NewFlagSet("app", flag.ContinueOnError)
+	global.BoolVar(&a.verbose, "v", false, "verbose")
+	serve := flag.NewFlagSet("serve", flag.ContinueOnError)
+	serve.IntVar(&a.port, "port", 8080, "port to listen on")
+	migrate := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	migrate.BoolVar(&a.force, "force", false, "run even if the schema is newer")
+	run := func(name string) func(context.Context, io.Writer, []string) error {
+		return func(ctx context.Context, w io.Writer, args []string) error {
+			a.ran, a.args = name, args
+			return nil
+		}
+	}
+	a.root = &Command{
+		Name:   "app",
+		Global: global,
+		Commands: []*Command{
+			{Name: "serve", Aliases: []string{"s", "server"}, Summary: "serve http", Flags: serve, Run: run("serve"),
+				Complete: map[string]Completer{"port": Values("80", "8080", "443"), "": Values("public", "private")}},
+			{Name: "db", Summary: "database commands", Commands: []*Command{
+				{Name: "migrate", Summary: "migrate the schema", Flags: migrate, Run: run("db migrate")},
+				{Name: "status", Summary: "show the schema version", Run: run("db status")},
+			}},
+			{Name: "fail", Run: func(context.Context, io.Writer, []string) error { return Exit(3, errors.New("exit 3")) }},
+			{Name: "debug", Hidden: true, Run: run("debug")},
+		},
+	}
+	a.root.Commands = append(a.root.Commands, CompletionCommand(a.root))
+	return a
+}
+
+func TestExec(t *testing.T) {
+	var tests = []struct {
+		args    []string
+		ran     string
+		rest    []string
+		verbose bool
+		port    int
+		force   bool
+	}{
+		{[]string{"serve", "-port=9000", "a", "b"}, "serve", []string{"a", "b"}, false, 9000, false},
+		{[]string{"s"}, "serve", nil, false, 8080, false},
+		{[]string{"-v", "server"}, "serve", nil, true, 8080, false},
+		{[]string{"serve", "-v", "-port", "1"}, "serve", nil, true, 1, false}, // global flags after the command too
+		{[]string{"db", "migrate", "-force", "-v"}, "db migrate", nil, true, 8080, true},
+		{[]string{"db", "-v", "status", "x"}, "db status", []string{"x"}, true, 8080, false},
+		{[]string{"debug"}, "debug", nil, false, 8080, false},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			a := newApp()
+			if err := a.root.Exec(context.Background(), io.Discard, tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if a.ran != tt.ran || !slices.Equal(a.args, tt.rest) {
+				t.Errorf("ran %q with %q; want %q with %q", a.ran, a.args, tt.ran, tt.rest)
+			}
+			if a.verbose != tt.verbose || a.port != tt.port || a.force != tt.force {
+				t.Errorf("v=%v port=%d force=%v; want v=%v port=%d force=%v", a.verbose, a.port, a.force, tt.verbose, tt.port, tt.force)
+			}
+		})
+	}
+}
+
+func TestVisit(t *testing.T) {
+	a := newApp()
+	if err := a.root.Exec(context.Background(), io.Discard, []string{"-v", "db", "migrate", "-force", "-v=false"}); err != nil {
+		t.Fatal(err)
+	}
+	var set []string
+	a.root.Visit(func(f *flag.Flag) { set = append(set, f.Name+"="+f.Value.String()) })
+	if want := []string{"v=false", "force=true", "v=false"}; !slices.Equal(set, want) {
+		t.Errorf("visited %q; want %q", set, want)
+	}
+
+	// a new Exec forgets the flags of the last one
+	if err := a.root.Exec(context.Background(), io.Discard, []string{"serve"}); err != nil {
+		t.Fatal(err)
+	}
+	set = nil
+	a.root.Visit(func(f *flag.Flag) { set = append(set, f.Name) })
+	if len(set) != 0 {
+		t.Errorf("visited %q after an Exec without flags", set)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var tests = []struct {
+		args []string
+		code int
+		want string
+	}{
+		{nil, ExitUsage, "missing command, want one of serve, db, fail, completion, see 'app -h'"},
+		{[]string{"serv"}, ExitUsage, `unknown command "serv", did you mean serve?`},
+		{[]string{"db", "statsu"}, ExitUsage, `unknown command "statsu", did you mean status?, see 'app db -h'`},
+		{[]string{"sever"}, ExitUsage, "did you mean serve?"}, // an alias is close, the name is suggested
+		{[]string{"xyzzy"}, ExitUsage, `unknown command "xyzzy", want one of serve, db, fail, completion`},
+		{[]string{"serve", "-prot=1"}, ExitUsage, "flag provided but not defined: -prot, see 'app serve -h'"},
+		{[]string{"serve", "-port=x"}, ExitUsage, `invalid value "x" for flag -port`},
+		{[]string{"help", "nope"}, ExitUsage, `unknown command "nope"`},
+		{[]string{"fail"}, 3, "exit 3"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var stderr bytes.Buffer
+			code := newApp().root.Execute(context.Background(), io.Discard, &stderr, tt.args)
+			if code != tt.code {
+				t.Errorf("exit code = %d; want %d", code, tt.code)
+			}
+			if !strings.Contains(stderr.String(), tt.want) || !strings.HasPrefix(stderr.String(), "app: ") {
+				t.Errorf("stderr = %q; want it to contain %q", stderr.String(), tt.want)
+			}
+		})
+	}
+
+	var stderr bytes.Buffer
+	quiet := &Command{Name: "quiet", Run: func(context.Context, io.Writer, []string) error { return Exit(4, nil) }}
+	if code := quiet.Execute(context.Background(), io.Discard, &stderr, nil); code != 4 || stderr.Len() > 0 {
+		t.Errorf("Exit(4, nil) = %d, %q", code, stderr.String())
+	}
+	if code := ExitCode(fmt.Errorf("wrapped: %w", flag.ErrHelp)); code != ExitOK {
+		t.Errorf("ExitCode(ErrHelp) = %d", code)
+	}
+	if code := ExitCode(errors.New("boom")); code != ExitFailure {
+		t.Errorf("ExitCode(boom) = %d", code)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var out bytes.Buffer
+	err := newApp().root.Exec(context.Background(), &out, []string{"-h"})
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("err = %v; want flag.ErrHelp", err)
+	}
+	usage := out.String()
+	for _, want := range []string{
+		"usage: app [flags] <command>\n",
+		"  serve, s, server   serve http\n",
+		"  db                 database commands\n",
+		"global flags:\n  -v\tverbose\n",
+		"run 'app help <command>'",
+	} {
+		if !strings.Contains(usage, want) {
+			t.Errorf("usage doesn't contain %q:\n%s", want, usage)
+		}
+	}
+	if strings.Contains(usage, "debug") {
+		t.Errorf("usage lists the hidden command:\n%s", usage)
+	}
+
+	out.Reset()
+	if err := newApp().root.Exec(context.Background(), &out, []string{"help", "db", "migrate"}); err != nil {
+		t.Fatal(err)
+	}
+	usage = out.String()
+	for _, want := range []string{
+		"usage: app db migrate [flags]\n\nmigrate the schema\n",
+		"flags:\n  -force\n    \trun even if the schema is newer\n",
+		"global flags:\n  -v\tverbose\n",
+	} {
+		if !strings.Contains(usage, want) {
+			t.Errorf("help db migrate doesn't contain %q:\n%s", want, usage)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0}, {"serve", "serve", 0}, {"serv", "serve", 1}, {"sevre", "serve", 2}, {"kitten", "sitting", 3}, {"", "abc", 3},
+	} {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	var tests = []struct {
+		words []string
+		want  []string
+	}{
+		{nil, []string{"serve", "db", "fail", "completion"}},
+		{[]string{"s"}, []string{"serve"}},
+		{[]string{"db", ""}, []string{"migrate", "status"}},
+		{[]string{"-v", "db", "m"}, []string{"migrate"}},
+		{[]string{"-"}, []string{"-v"}},
+		{[]string{"serve", "-"}, []string{"-port", "-v"}},
+		{[]string{"serve", "--p"}, []string{"--port"}},
+		{[]string{"serve", "-port", ""}, []string{"80", "8080", "443"}},
+		{[]string{"serve", "-port", "8"}, []string{"80", "8080"}},
+		{[]string{"serve", "-port=4"}, []string{"-port=443"}},
+		{[]string{"server", "-v", "pu"}, []string{"public"}}, // -v takes no value, pu is an argument
+		{[]string{"serve", "-port", "1", ""}, []string{"public", "private"}},
+		{[]string{"serve", "x", "-"}, nil}, // flags end at the first argument
+		{[]string{"completion", ""}, []string{"bash", "zsh", "fish"}},
+		{[]string{"nope", ""}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.words, " "), func(t *testing.T) {
+			var out bytes.Buffer
+			if err := newApp().root.Exec(context.Background(), &out, append([]string{CompleteCommand}, tt.words...)); err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Fields(out.String())
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("candidates = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range Shells {
+		var out bytes.Buffer
+		if err := newApp().root.Exec(context.Background(), &out, []string{"completion", shell}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "_app_complete") || strings.Contains(out.String(), "PROG") {
+			t.Errorf("%s script:\n%s", shell, out.String())
+		}
+	}
+	if err := newApp().root.WriteCompletion(io.Discard, "powershell"); err == nil {
+		t.Error("powershell accepted")
+	}
+}
+
+// TestBashCompletion runs the bash script with a fake program that prints the words it is given
+func TestBashCompletion(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("no bash")
+	}
+	root := &Command{Name: "prog sub"}
+	var script bytes.Buffer
+	if err := root.WriteCompletion(&script, "bash"); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "prog.bash")
+	if err := os.WriteFile(path, script.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		line string
+		want string
+	}{
+		{"prog sub ", "[sub __complete ]"},
+		{"prog sub -x=a", "[a]"}, // the candidate -x=a loses what bash doesn't replace
+		{"prog sub a b", "[sub __complete a b]"},
+		{"prog other ", "[]"}, // not the sub command, no candidates
+		{"prog sub", "[]"},
+	}
+	for _, tt := range tests {
+		// prog echoes its arguments on one line, or the word being completed when it ends with -x=
+		cmd := exec.Command(bash, "-c", `
+prog() { local IFS=" " last=${!#}; if [[ $last == -x=* ]]; then echo "$last"; else echo "$*"; fi; }
+source "$1"
+COMP_LINE=$2 COMP_POINT=${#2} _prog_sub_complete
+echo "[${COMPREPLY[*]}]"`, "bash", path, tt.line)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%q: %v\n%s", tt.line, err, out)
+		}
+		if got := strings.TrimSpace(string(out)); got != tt.want {
+			t.Errorf("%q: COMPREPLY = %s; want %s", tt.line, got, tt.want)
+		}
+	}
+}
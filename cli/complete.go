@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// CompleteCommand is the hidden command the completion scripts run: "prog __complete <words...>" prints the candidates
+// for the last word, one per line, given the words typed before it. the last word is empty after a space
+const CompleteCommand = "__complete"
+
+// Completer returns the candidates for a flag value or an argument being typed, it can return them all, the ones
+// not starting with prefix are dropped
+type Completer func(prefix string) []string
+
+// Values is a Completer of a fixed list
+func Values(values ...string) Completer {
+	return func(string) []string { return values }
+}
+
+// Shells are the shells CompletionCommand writes scripts for
+var Shells = []string{"bash", "zsh", "fish"}
+
+// CompletionCommand returns a "completion" command whose subcommands print the completion script of root for each shell:
+//
+//	source <(app completion bash)
+//	app completion zsh > "${fpath[1]}/_app"
+//	app completion fish > ~/.config/fish/completions/app.fish
+//
+// the scripts hand the words typed to the hidden __complete command, so the candidates always match the commands and flags
+func CompletionCommand(root *Command) *Command {
+	c := &Command{Name: "completion", Summary: "print the completion script for " + strings.Join(Shells, ", ")}
+	for _, shell := range Shells {
+		c.Commands = append(c.Commands, &Command{
+			Name:    shell,
+			Summary: "print the " + shell + " completion script",
+			Run: func(ctx context.Context, w io.Writer, args []string) error {
+				return root.WriteCompletion(w, shell)
+			},
+		})
+	}
+	return c
+}
+
+// WriteCompletion writes the completion script of the command for shell, one of Shells.
+// the first word of the Name is the program that completes, the other words must be typed before the command's own arguments
+func (c *Command) WriteCompletion(w io.Writer, shell string) error {
+	words := strings.Fields(c.Path())
+	if len(words) == 0 {
+		return fmt.Errorf("completion: the command has no name")
+	}
+	prog, lead := words[0], words[1:]
+	fn := "_" + regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(strings.Join(words, "_"), "_") + "_complete"
+	var script string
+	switch shell {
+	case "bash":
+		script = bashScript
+	case "zsh":
+		script = zshScript
+	case "fish":
+		script = fishScript
+	default:
+		return fmt.Errorf("completion: unknown shell %q, want one of %s", shell, strings.Join(Shells, ", "))
+	}
+	_, err := io.WriteString(w, strings.NewReplacer(
+		"PROG", prog,
+		"LEADN", fmt.Sprint(len(lead)),
+		"LEAD", strings.Join(lead, " "),
+		"FUNC", fn,
+	).Replace(script))
+	return err
+}
+
+// the scripts check that the words after the program are LEAD, then pass the words from there to PROG LEAD __complete.
+// bash splits the words itself, its COMP_WORDS would break -name=value at the =
+const bashScript = `# bash completion for PROG LEAD
+FUNC() {
+    COMPREPLY=()
+    local line=${COMP_LINE:0:COMP_POINT}
+    local -a words
+    read -ra words <<< "$line"
+    [[ $line == *[[:space:]] ]] && words+=("")
+    local lead=(LEAD)
+    local i
+    for ((i = 0; i < LEADN; i++)); do
+        [[ ${words[i+1]} == "${lead[i]}" ]] || return 0
+    done
+    (( ${#words[@]} > LEADN + 1 )) || return 0
+    local IFS=$'\n'
+    COMPREPLY=($(PROG "${lead[@]}" __complete "${words[@]:LEADN+1}" 2>/dev/null))
+    local cur=${words[${#words[@]}-1]}
+    if [[ $cur == -*=* ]]; then
+        COMPREPLY=("${COMPREPLY[@]#*=}") # bash only replaces what follows the =
+    fi
+}
+complete -o default -F FUNC PROG
+`
+
+const zshScript = `#compdef PROG
+# zsh completion for PROG LEAD
+FUNC() {
+    local -a lead candidates
+    lead=(LEAD)
+    local i
+    for ((i = 1; i <= LEADN; i++)); do
+        [[ ${words[i+1]} == ${lead[i]} ]] || return 1
+    done
+    (( CURRENT > LEADN + 1 )) || return 1
+    candidates=("${(@f)$(PROG $lead __complete "${(@)words[LEADN+2,CURRENT]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    compadd -- $candidates
+}
+if [[ $funcstack[1] == FUNC ]]; then
+    FUNC "$@"
+else
+    compdef FUNC PROG
+fi
+`
+
+const fishScript = `# fish completion for PROG LEAD
+function FUNC
+    set -l words (commandline -opc)[2..-1] (commandline -ct)
+    set -l lead LEAD
+    for i in (seq 1 LEADN)
+        test "$words[$i]" = "$lead[$i]"; or return
+    end
+    test (count $words) -gt LEADN; or return
+    PROG $lead __complete $words[(math LEADN + 1)..-1] 2>/dev/null
+end
+complete -c PROG -f -a '(FUNC)'
+`
+
+// complete prints the candidates for the last of words, walking the commands and flags named by the others like Exec does
+func (c *Command) complete(w io.Writer, words []string) error {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	typed, cur := words[:len(words)-1], words[len(words)-1]
+	cmd := c
+	var pending *flag.Flag // a flag waiting for its value
+	var pendingCmd *Command
+	positional := false
+	for i := 0; i < len(typed); i++ {
+		word := typed[i]
+		fs := cmd.flagSet()
+		if word == "--" {
+			positional = true
+			continue
+		}
+		if !positional && strings.HasPrefix(word, "-") && len(word) > 1 {
+			name, _, hasVal := strings.Cut(strings.TrimLeft(word, "-"), "=")
+			if f := fs.Lookup(name); f != nil && !hasVal && !isBool(f) {
+				if i == len(typed)-1 {
+					pending, pendingCmd = f, cmd
+				}
+				i++ // the next word is the value
+			}
+			continue
+		}
+		if !positional {
+			if sub := cmd.find(word); sub != nil && len(cmd.Commands) > 0 {
+				cmd = sub
+				continue
+			}
+		}
+		positional = true // the flag package stops at the first argument
+	}
+
+	var out []string
+	switch {
+	case pending != nil:
+		out = pendingCmd.values(pending.Name, cur)
+	case !positional && strings.HasPrefix(cur, "-"):
+		dashes := "-"
+		if strings.HasPrefix(cur, "--") {
+			dashes = "--"
+		}
+		if name, val, ok := strings.Cut(strings.TrimLeft(cur, "-"), "="); ok {
+			for _, v := range cmd.values(name, val) {
+				out = append(out, dashes+name+"="+v)
+			}
+			break
+		}
+		cmd.flagSet().VisitAll(func(f *flag.Flag) {
+			if s := dashes + f.Name; strings.HasPrefix(s, cur) {
+				out = append(out, s)
+			}
+		})
+	default:
+		if !positional {
+			for _, sub := range cmd.Commands {
+				if !sub.Hidden && strings.HasPrefix(sub.Name, cur) {
+					out = append(out, sub.Name)
+				}
+			}
+		}
+		out = append(out, cmd.values("", cur)...)
+	}
+	for _, s := range out {
+		if _, err := fmt.Fprintln(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// values completes the value of the flag name, or an argument for the empty name, from the Complete of the command or of a parent
+// declaring it as a global flag
+func (c *Command) values(name, prefix string) []string {
+	var complete Completer
+	for p := c; p != nil && complete == nil; p = p.parent {
+		complete = p.Complete[name]
+		if name == "" {
+			break // the arguments are the command's own
+		}
+	}
+	if complete == nil {
+		return nil
+	}
+	var out []string
+	for _, v := range complete(prefix) {
+		if v != "" && strings.HasPrefix(v, prefix) && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// isBool reports whether the flag takes no value, like flag.Bool
+func isBool(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
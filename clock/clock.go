@@ -0,0 +1,54 @@
+// Package clock abstracts the parts of the time package that wait: Sleep, After, timers and tickers.
+// code that takes a Clock runs on real time with Real, tests give it a Fake instead,
+// whose time only moves when the test advances it, so seconds of sleeps take no time at all
+package clock
+
+import (
+	"time"
+)
+
+// Clock is the source of time. clock.Real() and *clock.Fake implement it,
+// it also satisfies the smaller ratelimit.Clock, so the same clock can drive the limiters
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a single event in the future, like *time.Timer. C is a method rather than a field so that fakes can implement it
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker delivers ticks at intervals, like *time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// realClock is backed by the time package
+type realClock struct{}
+
+// Real returns the Clock of the time package
+func Real() Clock { return realClock{} }
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
@@ -0,0 +1,228 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when Advance is called.
+// timers, tickers, After and Sleep wait for the virtual time to pass their deadline.
+// a test usually starts the code under test on a goroutine, calls BlockUntil to know it is waiting on the clock,
+// then advances the clock and checks what happened. it is safe for concurrent use
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	changed chan struct{} // closed and replaced whenever waiters changes, BlockUntil waits on it
+}
+
+// fakeWaiter is a pending timer, ticker, After or Sleep
+type fakeWaiter struct {
+	at     time.Time
+	period time.Duration // more than 0 for tickers
+	c      chan time.Time
+}
+
+// NewFake returns a fake clock set to now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the virtual time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the virtual time elapsed since t
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// Sleep blocks until the clock has been advanced by d, it returns at once if d <= 0
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// After returns a channel that receives the virtual time once the clock has been advanced by d, at once if d <= 0
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer returns a timer that fires once the clock has been advanced by d, at once if d <= 0
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{f: f, w: &fakeWaiter{c: make(chan time.Time, 1)}}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedule(t.w, d)
+	return t
+}
+
+// NewTicker returns a ticker that ticks every time the clock passes another d. like time.NewTicker it panics if d <= 0
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	t := &fakeTicker{f: f, w: &fakeWaiter{period: d, c: make(chan time.Time, 1)}}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedule(t.w, d)
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers whose deadlines are passed, in deadline order.
+// like the time package, a tick is dropped if the previous one hasn't been received yet
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	end := f.now.Add(d)
+	for {
+		next := f.earliest()
+		if next == nil || next.at.After(end) {
+			break
+		}
+		f.now = next.at
+		select {
+		case next.c <- f.now:
+		default:
+		}
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			f.remove(next)
+		}
+	}
+	f.now = end
+	f.notify()
+}
+
+// Set moves the clock to t, or does nothing if t is not after the current time
+func (f *Fake) Set(t time.Time) {
+	if d := t.Sub(f.Now()); d > 0 {
+		f.Advance(d)
+	}
+}
+
+// Waiters returns the number of timers, tickers, Afters and Sleeps waiting on the clock
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil blocks until at least n timers, tickers, Afters or Sleeps are waiting on the clock.
+// calling it before Advance makes sure the goroutines under test got to the point where they wait
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		got, changed := len(f.waiters), f.changed
+		f.mu.Unlock()
+		if got >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// NextDeadline returns the time at which the next timer or ticker fires, ok is false if nothing is waiting
+func (f *Fake) NextDeadline() (at time.Time, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.earliest(); w != nil {
+		return w.at, true
+	}
+	return time.Time{}, false
+}
+
+// schedule adds w with a deadline d from now. a timer with d <= 0 fires at once instead, like in the time package. mu must be held
+func (f *Fake) schedule(w *fakeWaiter, d time.Duration) {
+	w.at = f.now.Add(d)
+	f.remove(w)
+	if d <= 0 && w.period == 0 {
+		select {
+		case w.c <- f.now:
+		default:
+		}
+		return
+	}
+	f.waiters = append(f.waiters, w)
+	f.notify()
+}
+
+// remove drops w from the waiters and reports whether it was there. mu must be held
+func (f *Fake) remove(w *fakeWaiter) bool {
+	for i, x := range f.waiters {
+		if x == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// earliest returns the waiter with the earliest deadline, the first one scheduled wins a tie. mu must be held
+func (f *Fake) earliest() *fakeWaiter {
+	var next *fakeWaiter
+	for _, w := range f.waiters {
+		if next == nil || w.at.Before(next.at) {
+			next = w
+		}
+	}
+	return next
+}
+
+// notify wakes up the BlockUntil calls. mu must be held
+func (f *Fake) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+type fakeTimer struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.w.c }
+
+// Stop prevents the timer from firing, it returns false if the timer already fired or was stopped
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	return t.f.remove(t.w)
+}
+
+// Reset makes the timer fire d from now, at once if d <= 0. it returns true if the timer was still pending
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	pending := t.f.remove(t.w)
+	t.f.schedule(t.w, d)
+	return pending
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.c }
+
+// Stop turns off the ticker, no more ticks are sent
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+}
+
+// Reset stops the ticker and starts it again with period d
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.w.period = d
+	t.f.schedule(t.w, d)
+}
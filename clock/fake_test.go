@@ -0,0 +1,194 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2009, 11, 17, 20, 34, 58, 0, time.UTC)
+
+// the real clock must satisfy the interface, the fake one too
+var (
+	_ Clock = Real()
+	_ Clock = (*Fake)(nil)
+)
+
+// received returns the value waiting on c, ok is false if there is none
+func received(c <-chan time.Time) (t time.Time, ok bool) {
+	select {
+	case t = <-c:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeNowAndSince(t *testing.T) {
+	f := NewFake(epoch)
+	f.Advance(3 * time.Second)
+	if got := f.Now(); !got.Equal(epoch.Add(3 * time.Second)) {
+		t.Errorf("Now = %v", got)
+	}
+	if got := f.Since(epoch); got != 3*time.Second {
+		t.Errorf("Since = %v; want 3s", got)
+	}
+	f.Set(epoch) // going back is ignored
+	if got := f.Since(epoch); got != 3*time.Second {
+		t.Errorf("Set moved the clock back, Since = %v", got)
+	}
+}
+
+func TestFakeTimer(t *testing.T) {
+	f := NewFake(epoch)
+	tm := f.NewTimer(time.Second)
+
+	f.Advance(999 * time.Millisecond)
+	if _, ok := received(tm.C()); ok {
+		t.Fatal("timer fired early")
+	}
+	f.Advance(time.Millisecond)
+	if at, ok := received(tm.C()); !ok || !at.Equal(epoch.Add(time.Second)) {
+		t.Fatalf("timer fired = %v at %v; want true at %v", ok, at, epoch.Add(time.Second))
+	}
+	if tm.Stop() {
+		t.Error("Stop of a fired timer returned true")
+	}
+
+	// Reset rearms it from the current time
+	if tm.Reset(time.Second) {
+		t.Error("Reset of a fired timer returned true")
+	}
+	f.Advance(time.Second)
+	if _, ok := received(tm.C()); !ok {
+		t.Error("reset timer didn't fire")
+	}
+}
+
+func TestFakeTimerStop(t *testing.T) {
+	f := NewFake(epoch)
+	tm := f.NewTimer(time.Second)
+	if !tm.Stop() {
+		t.Error("Stop of a pending timer returned false")
+	}
+	f.Advance(time.Hour)
+	if _, ok := received(tm.C()); ok {
+		t.Error("stopped timer fired")
+	}
+	if f.Waiters() != 0 {
+		t.Errorf("Waiters = %d; want 0", f.Waiters())
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(epoch)
+	tk := f.NewTicker(500 * time.Millisecond)
+	for i := 1; i <= 3; i++ {
+		f.Advance(500 * time.Millisecond)
+		at, ok := received(tk.C())
+		if want := epoch.Add(time.Duration(i) * 500 * time.Millisecond); !ok || !at.Equal(want) {
+			t.Fatalf("tick %d = %v, %v; want %v", i, at, ok, want)
+		}
+	}
+
+	// like time.Ticker, ticks are dropped when nobody receives them
+	f.Advance(2 * time.Second)
+	if _, ok := received(tk.C()); !ok {
+		t.Fatal("no tick after 2s")
+	}
+	if _, ok := received(tk.C()); ok {
+		t.Fatal("more than one tick was buffered")
+	}
+
+	tk.Reset(time.Second)
+	f.Advance(500 * time.Millisecond)
+	if _, ok := received(tk.C()); ok {
+		t.Fatal("tick at the old period after Reset")
+	}
+	f.Advance(500 * time.Millisecond)
+	if _, ok := received(tk.C()); !ok {
+		t.Fatal("no tick at the new period after Reset")
+	}
+
+	tk.Stop()
+	f.Advance(time.Hour)
+	if _, ok := received(tk.C()); ok {
+		t.Error("stopped ticker ticked")
+	}
+}
+
+func TestFakeFiresInDeadlineOrder(t *testing.T) {
+	f := NewFake(epoch)
+	late := f.After(2 * time.Second)
+	early := f.After(time.Second)
+	if at, ok := f.NextDeadline(); !ok || !at.Equal(epoch.Add(time.Second)) {
+		t.Errorf("NextDeadline = %v, %v; want %v", at, ok, epoch.Add(time.Second))
+	}
+	f.Advance(5 * time.Second)
+	// every waiter receives the virtual time of its own deadline, not the end of the advance
+	if at := <-early; !at.Equal(epoch.Add(time.Second)) {
+		t.Errorf("early fired at %v", at)
+	}
+	if at := <-late; !at.Equal(epoch.Add(2 * time.Second)) {
+		t.Errorf("late fired at %v", at)
+	}
+	if _, ok := f.NextDeadline(); ok {
+		t.Error("NextDeadline reports a waiter after all fired")
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	f := NewFake(epoch)
+	woke := make(chan struct{})
+	go func() {
+		f.Sleep(time.Minute) // a minute of virtual time
+		close(woke)
+	}()
+	f.BlockUntil(1)
+	f.Advance(time.Minute)
+	select {
+	case <-woke:
+	case <-time.After(time.Second):
+		t.Fatal("Sleep didn't return after the clock was advanced")
+	}
+}
+
+func TestFakeNonPositive(t *testing.T) {
+	f := NewFake(epoch)
+	// like the time package, they fire without the clock moving
+	f.Sleep(0)
+	f.Sleep(-time.Second)
+	if now, ok := received(f.After(0)); !ok || !now.Equal(epoch) {
+		t.Errorf("After(0) = %v, %v; want the current time at once", now, ok)
+	}
+	timer := f.NewTimer(-time.Minute)
+	if _, ok := received(timer.C()); !ok {
+		t.Error("a timer with a negative duration didn't fire at once")
+	}
+	if timer.Stop() {
+		t.Error("Stop of a fired timer returned true")
+	}
+	timer = f.NewTimer(time.Hour)
+	if !timer.Reset(0) {
+		t.Error("Reset of a pending timer returned false")
+	}
+	if _, ok := received(timer.C()); !ok {
+		t.Error("Reset(0) didn't fire the timer")
+	}
+	if n := f.Waiters(); n != 0 {
+		t.Errorf("%d waiters left", n)
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	c := Real()
+	start := c.Now()
+	c.Sleep(time.Millisecond)
+	if c.Since(start) < time.Millisecond {
+		t.Error("Sleep returned early")
+	}
+	tm := c.NewTimer(time.Millisecond)
+	<-tm.C()
+	tk := c.NewTicker(time.Millisecond)
+	<-tk.C()
+	tk.Stop()
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"RobotTask/clock"
+	"RobotTask/golden"
+	goroutineexample "RobotTask/goroutine"
+	"RobotTask/helper"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use, the goroutine example prints from several goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+// masks shared by several examples
+var (
+	// %p and & print addresses, which change on every run
+	maskPointers = golden.Replace(`0x[0-9a-f]{6,}`, "0xADDR")
+	// the log package prefixes its lines with the date and time
+	maskLogTimes = golden.Replace(`\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(\.\d+)?`, "YYYY/MM/DD hh:mm:ss")
+)
+
+// goldenCase runs an example through the launcher and compares its stdout with testdata/<name>.golden
+type goldenCase struct {
+	name  string
+	args  []string
+	run   func(ctx context.Context, out *syncBuffer) error // runs the example instead of the launcher, to pass it what args can't
+	setup func(t *testing.T)                               // makes the run deterministic where masking can't
+	masks func(t *testing.T) []golden.Mask                 // built per run, some depend on the setup
+}
+
+// the examples that serve until ctrl+c (embed, http, signal) or end the process (exec, exit) are not run here
+var goldenCases = []goldenCase{
+	{
+		name: "basics",
+		args: []string{"basics"},
+		setup: func(t *testing.T) {
+			now, source, local := helper.Now, helper.RandSource, time.Local
+			t.Cleanup(func() { helper.Now, helper.RandSource, time.Local = now, source, local })
+			time.Local = time.UTC // time.Unix returns local times, which depend on the time zone of the machine
+			helper.Now = func() time.Time { return time.Date(2024, 10, 1, 12, 30, 45, 123456789, time.UTC) }
+			helper.RandSource = rand.NewPCG(1, 2)
+		},
+		masks: func(t *testing.T) []golden.Mask {
+			return []golden.Mask{
+				maskPointers,
+				maskLogTimes,
+				golden.Replace(`helper\.go:\d+`, "helper.go:LINE"), // log.Lshortfile
+				// ranging over a map visits the keys in random order
+				golden.Replace(`b -> banana\na -> apple\n`, "a -> apple\nb -> banana\n"),
+			}
+		},
+	},
+	{
+		name: "commandline",
+		args: []string{"commandline", "foo", "-enable", "-name=joe", "a", "b"},
+		setup: func(t *testing.T) {
+			t.Setenv("FOO", "") // the example sets FOO, t.Setenv restores it afterwards
+			t.Setenv("BAR", "2")
+		},
+		masks: func(t *testing.T) []golden.Mask {
+			return []golden.Mask{
+				golden.Replace(regexp.QuoteMeta(os.Args[0]), "oneforall"),
+				// the names of the environment variables depend on the machine
+				golden.Replace(`(?s)os\.Environ:\n.*`, "os.Environ:\n...\n"),
+			}
+		},
+	},
+	{
+		name:  "file",
+		args:  []string{"file"},
+		setup: chdirTemp,
+		masks: func(t *testing.T) []golden.Mask {
+			return []golden.Mask{
+				// temp files and directories get a random suffix
+				golden.Replace(regexp.QuoteMeta(os.TempDir())+`/(data\.txt|sampledir)\d+`, "TMPDIR/${1}RANDOM"),
+			}
+		},
+	},
+	{
+		name: "goroutine",
+		// the sleeps, timers and limiters wait on a fake clock, only the stateful goroutine at the end takes a real second
+		run: func(ctx context.Context, out *syncBuffer) error {
+			return runWithFakeClock(func(clk clock.Clock) error { return goroutineexample.RunWithClock(ctx, out, clk) },
+				func(clk *clock.Fake) error { return driveGoroutineExample(clk, out) })
+		},
+		masks: func(t *testing.T) []golden.Mask {
+			return []golden.Mask{
+				// which worker picks up which job, and how many operations the readers and writers get through, varies
+				golden.Replace(`(?m)^worker \d+ `, "worker N "),
+				golden.Replace(`(?m)^(readOps|writeOps): \d+$`, "$1: N"),
+				golden.Replace(`(?m)^final state has \d+ keys$`, "final state has N keys"),
+				// the lines printed by goroutines running at the same time come in any order
+				golden.SortRuns(`^(goroutine : \d|going)$`),
+				golden.SortRuns(`^received (one|two)$`),
+				golden.SortRuns(`^(sent|received) (job \d|all jobs)$`),
+				golden.SortRuns(`^worker N (started |finished) job \d$`),
+				golden.SortRuns(`^Worker \d (starting|done)$`),
+			}
+		},
+	},
+	{
+		name: "recover",
+		args: []string{"recover"},
+	},
+	{
+		name: "spawn",
+		args: []string{"spawn"},
+		setup: func(t *testing.T) {
+			requireCommands(t, "date", "grep", "sort", "tr", "wc", "bash", "yes", "head", "ls")
+			chdirTemp(t)
+		},
+		masks: func(t *testing.T) []golden.Mask {
+			return []golden.Mask{
+				golden.Replace(`> date\n.*\n`, "> date\nDATE\n"),
+				golden.Replace(`(?s)> ls -a -l -h\n.*`, "> ls -a -l -h\nLISTING\n"),
+			}
+		},
+	},
+}
+
+// chdirTemp runs the test in an empty temporary directory, for examples that create files in the working directory
+func chdirTemp(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func requireCommands(t *testing.T, names ...string) {
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s is not installed", name)
+		}
+	}
+}
+
+// runWithFakeClock runs f with a fake clock that drive moves forward, then waits for f to return.
+// the sleeps and timers take no time and fire in the same order as they would on the real clock
+func runWithFakeClock(f func(clk clock.Clock) error, drive func(clk *clock.Fake) error) error {
+	clk := clock.NewFake(time.Date(2024, 10, 1, 12, 30, 45, 0, time.UTC))
+	done := make(chan error, 1)
+	go func() { done <- f(clk) }()
+	if err := drive(clk); err != nil {
+		return err
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Minute):
+		return errors.New("the example did not return within a minute")
+	}
+}
+
+// clockDriver moves a fake clock once the code using it is waiting. the first step that times out stops the driver,
+// its error is kept and the later steps do nothing
+type clockDriver struct {
+	clk *clock.Fake
+	out *syncBuffer
+	err error
+}
+
+// stepTimeout bounds every wait of a clockDriver, so an example that doesn't wait where the driver expects fails the test
+const stepTimeout = 10 * time.Second
+
+// blockUntil waits for n sleeps, timers and tickers to be pending. moving the clock before a goroutine gets to its timer
+// would change which select case wins
+func (d *clockDriver) blockUntil(n int) {
+	if d.err != nil {
+		return
+	}
+	registered := make(chan struct{})
+	go func() {
+		d.clk.BlockUntil(n)
+		close(registered)
+	}()
+	select {
+	case <-registered:
+	case <-time.After(stepTimeout):
+		d.err = fmt.Errorf("at %v: %d waiters on the clock, want %d", d.clk.Now(), d.clk.Waiters(), n)
+	}
+}
+
+// waitFor waits for the output to contain s n times, for the goroutines that print without waiting on the clock
+func (d *clockDriver) waitFor(s string, n int) {
+	deadline := time.Now().Add(stepTimeout)
+	for d.err == nil && bytes.Count(d.out.Bytes(), []byte(s)) < n {
+		if time.Now().After(deadline) {
+			d.err = fmt.Errorf("at %v: %q printed %d times, want %d", d.clk.Now(), s, bytes.Count(d.out.Bytes(), []byte(s)), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (d *clockDriver) advance(dur time.Duration) {
+	if d.err == nil {
+		d.clk.Advance(dur)
+	}
+}
+
+// driveGoroutineExample moves clk through the waits of the goroutine example, in the order the example makes them
+func driveGoroutineExample(clk *clock.Fake, out *syncBuffer) error {
+	d := &clockDriver{clk: clk, out: out}
+	// the goroutines printing before the first sleep don't wait on the clock, they must be done before it ends
+	d.waitFor("goroutine : 2\n", 1)
+	d.waitFor("going\n", 1)
+	d.blockUntil(1)
+	d.advance(time.Second)
+	d.blockUntil(1) // worker
+	d.advance(time.Second)
+	d.blockUntil(2) // the two calls of the select
+	d.advance(time.Second)
+
+	// selectTimeouts: the call sleeping for 2s and the 1s timeout, then the second call sleeping for 2s with a 3s timeout
+	d.blockUntil(2)
+	d.advance(time.Second)
+	d.blockUntil(3)
+	d.advance(2 * time.Second)
+
+	// timers: the 3s timeout left over and timer1, then the sleep once timer2 is stopped
+	d.blockUntil(2)
+	d.advance(2 * time.Second)
+	d.waitFor("Timer 2 stopped\n", 1)
+	d.blockUntil(1)
+	d.advance(2 * time.Second)
+
+	// tickers: one tick at a time, a tick that isn't received before the next one is dropped
+	d.blockUntil(2)
+	for i := 1; i <= 3; i++ {
+		d.advance(500 * time.Millisecond)
+		d.waitFor("Tick at", i)
+	}
+	d.advance(100 * time.Millisecond)
+
+	// 3 workers for 5 jobs, with worker2 and then the workerpool
+	for _, dur := range []time.Duration{time.Second, 100 * time.Millisecond} {
+		d.blockUntil(3)
+		d.advance(dur)
+		d.blockUntil(2)
+		d.advance(dur)
+	}
+	d.blockUntil(5) // worker3
+	d.advance(time.Second)
+
+	// rateLimits: 4 waits on the first limiter, 2 on the bursty one once its burst is used up
+	for range 6 {
+		d.blockUntil(1)
+		d.advance(200 * time.Millisecond)
+	}
+	return d.err
+}
+
+// go test ./cmd/oneforall -run TestGolden -update rewrites testdata/*.golden with the current output
+func TestGolden(t *testing.T) {
+	// the golden files are looked up relative to the package directory, the file and spawn cases change the working directory
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setup != nil {
+				tc.setup(t)
+			}
+			var stdout, stderr syncBuffer
+			if tc.run != nil {
+				if err := tc.run(context.Background(), &stdout); err != nil {
+					t.Fatal(err)
+				}
+			} else if code := run(context.Background(), &stdout, &stderr, tc.args); code != exitOK {
+				t.Fatalf("exit code = %d; want %d, stderr:\n%s", code, exitOK, stderr.Bytes())
+			}
+			var masks []golden.Mask
+			if tc.masks != nil {
+				masks = tc.masks(t)
+			}
+			golden.Assert(t, filepath.Join(dir, tc.name+".golden"), stdout.Bytes(), masks...)
+		})
+	}
+}
+
+func TestGoldenMasksAreStable(t *testing.T) {
+	// masking twice must give the same result, otherwise -update would write files that never match
+	for _, tc := range goldenCases {
+		if tc.masks == nil {
+			continue
+		}
+		path := filepath.Join("testdata", tc.name+".golden")
+		want, err := os.ReadFile(path)
+		if err != nil {
+			if golden.Updating() {
+				continue
+			}
+			t.Fatal(err)
+		}
+		if d := golden.Diff(string(want), golden.Apply(string(want), tc.masks(t)...)); d != "" {
+			t.Errorf("masking %s changes it:\n%s", path, d)
+		}
+	}
+}
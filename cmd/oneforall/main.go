@@ -0,0 +1,334 @@
+// oneforall runs any of the examples by name, so there is a single entrance instead of one main per example:
+//
+//	oneforall list                       list the examples
+//	oneforall describe <example>         show what an example does and how to run it
+//	oneforall <example> [arguments...]   run an example
+//
+// the log flags come before the example name, for example "oneforall -log-level=debug -log-format=json http".
+// they can also be set with environment variables: ONEFORALL_LOG_LEVEL, ONEFORALL_LOG_FORMAT, ONEFORALL_LOG_FILE and so on,
+// the flags win over the environment.
+//
+// run it with "go run ./cmd/oneforall goroutine", or install it with "go install ./cmd/oneforall"
+package main
+
+import (
+	"RobotTask/basics"
+	"RobotTask/cli"
+	commandlineexample "RobotTask/commandline"
+	"RobotTask/dirtree"
+	embedexample "RobotTask/embed"
+	execexample "RobotTask/exec"
+	exitexample "RobotTask/exit"
+	fileexample "RobotTask/file"
+	goroutineexample "RobotTask/goroutine"
+	"RobotTask/hashing"
+	httpexample "RobotTask/http"
+	"RobotTask/linefilter"
+	"RobotTask/logging"
+	recoverexample "RobotTask/recover"
+	signalexample "RobotTask/signal"
+	spawnexample "RobotTask/spawn"
+	"RobotTask/supervisor"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// exit codes, like in the exit example a non-zero status tells the caller something went wrong
+const (
+	exitOK      = 0
+	exitFailure = 1 // the example returned an error
+	exitUsage   = 2 // the command line is wrong, this is also what the flag package exits with
+)
+
+// envPrefix is the prefix of the environment variables read by the launcher
+const envPrefix = "ONEFORALL_"
+
+// example is an entry of the launcher
+type example struct {
+	name    string
+	summary string // a single line shown by list
+	about   string // shown by describe, after the summary
+	source  string // the directory the code lives in
+	args    string // the arguments the example takes, empty if it takes none
+	// run runs the example with the arguments following its name
+	run func(ctx context.Context, w io.Writer, args []string) error
+}
+
+// examples are listed in the order below
+var examples = []example{
+	{
+		name:    "basics",
+		summary: "variables, loops, slices, maps, functions, structs, generics, errors, sorting and more",
+		about:   "goes through the basics of the language, then the standard library examples of the helper package:\nregular expressions, logging, templates, hashing, random numbers, json, xml, urls and time",
+		source:  "basics",
+		run:     noArgs(basics.Run),
+	},
+	{
+		name:    "commandline",
+		summary: "command line arguments, flags, subcommands and environment variables",
+		about:   "parses its arguments with flags and the foo and bar subcommands, then prints the environment.\nthe top-level flags come before or after the subcommand, for example: oneforall commandline -fork foo -enable -name=joe a b\nshell completion: source <(oneforall commandline completion bash), or zsh, or fish",
+		source:  "commandline",
+		args:    "[flags] foo|bar|completion [subcommand flags] [arguments]",
+		run:     commandlineexample.RunArgs,
+	},
+	{
+		name:    "embed",
+		summary: "files embedded in the binary, served over http on :8080",
+		about:   "prints the embedded files, then serves them with etags and cache headers until ctrl+c",
+		source:  "embed",
+		run:     noArgs(embedexample.Run),
+	},
+	{
+		name:    "exec",
+		summary: "prints the execve call, then replaces the process with ls through syscall.Exec (linux only)",
+		about:   "the launcher itself is replaced, so nothing runs after ls",
+		source:  "exec",
+		run:     noArgs(execexample.Run),
+	},
+	{
+		name:    "exit",
+		summary: "exits with status 3, skipping deferred functions",
+		about:   "the deferred print never runs, check the status with echo $?",
+		source:  "exit",
+		run:     noArgs(exitexample.Run),
+	},
+	{
+		name:    "file",
+		summary: "file paths, directories, temporary files, reading and writing files and a line filter",
+		about:   "creates and removes subdir in the working directory. with -stdin the lines read from stdin are uppercased:\necho abcbcd | oneforall file -stdin",
+		source:  "file",
+		args:    "[-stdin]",
+		run:     fileexample.RunArgs,
+	},
+	{
+		name:    "filter",
+		summary: "filters lines from stdin or files: upper, lower, trim, grep, replace, numbers and sha256",
+		about:   "the transforms run in the order they are given, the remaining arguments are the files to read, stdin if there are none:\nprintf 'go\\nrust\\n' | oneforall filter -grep=o -replace=/o/0/ -upper -number\n-0 reads and writes NUL-delimited records, like the output of find -print0",
+		source:  "linefilter",
+		args:    "[-0] [-max-record=1048576] [-upper] [-lower] [-trim] [-grep=re] [-grep-v=re] [-replace=/re/repl/] [-number] [-sha256] [files...]",
+		run:     linefilter.RunArgs,
+	},
+	{
+		name:    "tree",
+		summary: "lists a directory as a tree, with sizes, modification times, json and csv output",
+		about:   "walks the directory given, the current one by default. the globs filter the files, the directories show the total size below them:\noneforall tree -size -include='*.go' -exclude=testdata -depth=2 .\nthe directories that can't be read are shown with their error, the walk goes on without them",
+		source:  "dirtree",
+		args:    "[-include=glob]... [-exclude=glob]... [-depth=0] [-size] [-mtime] [-symlinks=list|skip|follow] [-format=text|json|csv] [dir]",
+		run:     dirtree.RunArgs,
+	},
+	{
+		name:    "hash",
+		summary: "hashes files and directories with sha256, sha1 or blake2b, and checks manifests like sha256sum -c",
+		about:   "prints a line per file in the format of sha256sum, the files of a directory are hashed in parallel:\noneforall hash -a=blake2b embed/folder > folder.sums\noneforall hash -a=blake2b -c folder.sums\n-tree prints a single digest for a directory, without arguments stdin is hashed",
+		source:  "hashing",
+		args:    "[-a=sha256|sha1|blake2b] [-j=workers] [-tree] [-c] [files, directories or manifests...]",
+		run:     hashing.RunArgs,
+	},
+	{
+		name:    "dupes",
+		summary: "finds the identical files under directories, by size then by hash",
+		about:   "only the files sharing their size with another one are hashed, the groups wasting the most space come first:\noneforall dupes ~/Downloads ~/Documents",
+		source:  "hashing",
+		args:    "[-a=sha256|sha1|blake2b] [-j=workers] [dirs...]",
+		run:     hashing.RunDupesArgs,
+	},
+	{
+		name:    "supervise",
+		summary: "keeps a command running, restarting it with a backoff and checking its health",
+		about:   "runs the command until ctrl+c, its output and the restarts go to the log:\noneforall supervise -restart=always -backoff=500ms bash -c 'date; sleep 2; exit 1'\noneforall supervise -health-url=http://localhost:8000/ python3 -m http.server 8000\nctrl+c sends SIGTERM to the command, and SIGKILL after -grace",
+		source:  "supervisor",
+		args:    "[-name=n] [-restart=never|on-failure|always] [-max-restarts=0] [-backoff=1s] [-max-backoff=1m] [-grace=10s] [-health-cmd=cmd] [-health-url=url] [-health-interval=10s] command [args...]",
+		run:     supervisor.RunArgs,
+	},
+	{
+		name:    "goroutine",
+		summary: "goroutines, channels, select, timers, tickers, worker pools, rate limits, atomics and mutexes",
+		about:   "takes about 15 seconds, most examples sleep to simulate work",
+		source:  "goroutine",
+		run:     noArgs(goroutineexample.Run),
+	},
+	{
+		name:    "http",
+		summary: "http server with middlewares and the plant api, and a client calling it",
+		about:   "serves on :8090 until ctrl+c or a request to /end, the flags change the address and timeouts",
+		source:  "http",
+		args:    "[-addr=:8090] [-read-timeout=0s] [-write-timeout=15s] [-idle-timeout=0s] [-shutdown-timeout=15s] [-context-timeout=12s]",
+		run:     httpexample.RunArgs,
+	},
+	{
+		name:    "recover",
+		summary: "recovering from a panic in a deferred function",
+		source:  "recover",
+		run:     noArgs(recoverexample.Run),
+	},
+	{
+		name:    "signal",
+		summary: "reloads on SIGHUP, dumps the goroutines on SIGUSR1 and shuts down on SIGINT or SIGTERM",
+		about:   "press ctrl+c to send SIGINT, twice to skip the shutdown hooks. kill -HUP or -USR1 the pid for the others",
+		source:  "signal",
+		run:     noArgs(signalexample.Run),
+	},
+	{
+		name:    "spawn",
+		summary: "runs date, grep, a grep | sort | tr pipeline, bounded runs and ls as child processes (linux only)",
+		source:  "spawn",
+		run:     noArgs(spawnexample.Run),
+	},
+}
+
+// usageError is returned for a wrong command line, it makes the launcher exit with exitUsage
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string { return e.msg }
+
+// noArgs adapts the Run function of an example that takes no arguments
+func noArgs(run func(ctx context.Context, w io.Writer) error) func(context.Context, io.Writer, []string) error {
+	return func(ctx context.Context, w io.Writer, args []string) error {
+		if len(args) > 0 {
+			return usageError{fmt.Sprintf("unexpected arguments %q", args)}
+		}
+		return run(ctx, w)
+	}
+}
+
+func lookup(name string) (example, bool) {
+	for _, ex := range examples {
+		if ex.name == name {
+			return ex, true
+		}
+	}
+	return example{}, false
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage:")
+	fmt.Fprintln(w, "  oneforall list                       list the examples")
+	fmt.Fprintln(w, "  oneforall describe <example>         show what an example does and how to run it")
+	fmt.Fprintln(w, "  oneforall [log flags] <example> [arguments...]   run an example")
+	fmt.Fprintln(w, "log flags, also read from "+envPrefix+"LOG_LEVEL and the like:")
+	fs := flag.NewFlagSet("oneforall", flag.ContinueOnError)
+	cfg := logging.DefaultConfig()
+	cfg.RegisterFlags(fs)
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+func list(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, ex := range examples {
+		fmt.Fprintf(tw, "%s\t%s\n", ex.name, ex.summary)
+	}
+	tw.Flush()
+}
+
+func describe(w io.Writer, ex example) {
+	fmt.Fprintf(w, "%s: %s\n", ex.name, ex.summary)
+	if ex.about != "" {
+		fmt.Fprintf(w, "\n%s\n", ex.about)
+	}
+	fmt.Fprintf(w, "\nsource: %s/\n", ex.source)
+	if ex.args == "" {
+		fmt.Fprintf(w, "usage:  oneforall %s\n", ex.name)
+	} else {
+		fmt.Fprintf(w, "usage:  oneforall %s %s\n", ex.name, ex.args)
+	}
+}
+
+// run runs the launcher with the arguments following the program name and returns the exit code
+func run(ctx context.Context, stdout, stderr io.Writer, args []string) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		usage(stdout)
+		return exitOK
+	case "list":
+		list(stdout)
+		return exitOK
+	case "describe":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "usage: oneforall describe <example>")
+			return exitUsage
+		}
+		ex, ok := lookup(args[1])
+		if !ok {
+			fmt.Fprintf(stderr, "oneforall: unknown example %q, see oneforall list\n", args[1])
+			return exitUsage
+		}
+		describe(stdout, ex)
+		return exitOK
+	}
+
+	// the log settings come from the environment first, then from the flags before the example name
+	cfg := logging.DefaultConfig()
+	if err := cfg.LoadEnv(envPrefix, os.LookupEnv); err != nil {
+		fmt.Fprintf(stderr, "oneforall: %v\n", err)
+		return exitUsage
+	}
+	fs := flag.NewFlagSet("oneforall", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(stderr) }
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage // the flag set already reported the error
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	ex, ok := lookup(args[0])
+	if !ok {
+		fmt.Fprintf(stderr, "oneforall: unknown example %q, see oneforall list\n", args[0])
+		return exitUsage
+	}
+
+	logger, closer, err := logging.Open(cfg, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "oneforall: %v\n", err)
+		return exitUsage
+	}
+	defer closer.Close()
+	// the examples find the logger in the context, the default slog logger is left alone
+	ctx = logging.NewContext(ctx, logger)
+
+	err = ex.run(ctx, stdout, args[1:])
+	var ue usageError
+	var cue *cli.UsageError
+	var cee *cli.ExitError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		// the flag set of the example already printed its help
+		return exitOK
+	case errors.As(err, &ue), errors.As(err, &cue):
+		fmt.Fprintf(stderr, "oneforall %s: %v\n", ex.name, err)
+		return exitUsage
+	case errors.As(err, &cee):
+		// the example chose its exit code, like os.Exit in the exit example but after its defers ran
+		if cee.Err != nil {
+			fmt.Fprintf(stderr, "oneforall %s: %v\n", ex.name, err)
+		}
+		return cee.Code
+	default:
+		fmt.Fprintf(stderr, "oneforall %s: %v\n", ex.name, err)
+		return exitFailure
+	}
+}
+
+func main() {
+	// the examples that serve until ctrl+c set up their own signal handling, so a plain background context is enough here
+	os.Exit(run(context.Background(), os.Stdout, os.Stderr, os.Args[1:]))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	var tests = []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{"no arguments", nil, exitUsage, "", "usage:"},
+		{"help", []string{"help"}, exitOK, "usage:", ""},
+		{"list", []string{"list"}, exitOK, "goroutines, channels", ""},
+		{"describe", []string{"describe", "file"}, exitOK, "usage:  oneforall file [-stdin]", ""},
+		{"describe unknown", []string{"describe", "nope"}, exitUsage, "", `unknown example "nope"`},
+		{"describe without name", []string{"describe"}, exitUsage, "", "usage: oneforall describe"},
+		{"unknown example", []string{"nope"}, exitUsage, "", `unknown example "nope"`},
+		{"unexpected arguments", []string{"recover", "x"}, exitUsage, "", `unexpected arguments ["x"]`},
+		{"run an example", []string{"recover"}, exitOK, "Recovered. Error:\n a problem", ""},
+		{"example error", []string{"hash", "/nonexistent"}, exitFailure, "", "no such file or directory"},
+		{"example usage error", []string{"commandline", "baz"}, exitUsage, "", `unknown command "baz", did you mean bar?`},
+		{"example without subcommand", []string{"commandline"}, exitUsage, "", "missing command, want one of foo, bar, completion"},
+		{"example help", []string{"commandline", "-h"}, exitOK, "", ""},
+		{"example bad flag before the command", []string{"commandline", "-bogus", "foo"}, exitUsage, "", "flag provided but not defined: -bogus"},
+		{"example bad flag after the command", []string{"commandline", "foo", "-bogus"}, exitUsage, "", "flag provided but not defined: -bogus"},
+		{"example global flag after the command", []string{"commandline", "foo", "-numb=3"}, exitOK, "numb: 3\n", ""},
+		{"log flags", []string{"-log-level=debug", "-log-format=json", "recover"}, exitOK, "Recovered.", ""},
+		{"log flags without example", []string{"-log-level=debug"}, exitUsage, "", "usage:"},
+		{"bad log flag", []string{"-log-level=loud", "recover"}, exitUsage, "", "invalid value"},
+		{"bad log format", []string{"-log-format=xml", "recover"}, exitUsage, "", `unknown format "xml"`},
+		{"logs go to stderr", []string{"-log-format=json", "basics"}, exitOK, "", `"msg":"hi there"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(context.Background(), &stdout, &stderr, tt.args)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d; want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("stdout = %q; want it to contain %q", stdout.String(), tt.wantStdout)
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q; want it to contain %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestExamplesAreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, ex := range examples {
+		if seen[ex.name] || ex.name == "list" || ex.name == "describe" || ex.name == "help" {
+			t.Errorf("example name %q is already taken", ex.name)
+		}
+		seen[ex.name] = true
+		if ex.summary == "" || ex.source == "" || ex.run == nil {
+			t.Errorf("example %q is incomplete", ex.name)
+		}
+	}
+}
+
+func TestRunLogEnv(t *testing.T) {
+	t.Setenv(envPrefix+"LOG_FORMAT", "json")
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), &stdout, &stderr, []string{"basics"}); code != exitOK {
+		t.Fatalf("exit code = %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), `"msg":"hi there"`) {
+		t.Errorf("%sLOG_FORMAT=json was ignored, stderr: %s", envPrefix, stderr.String())
+	}
+
+	// a flag wins over the environment
+	stderr.Reset()
+	run(context.Background(), &stdout, &stderr, []string{"-log-format=text", "basics"})
+	if !strings.Contains(stderr.String(), `msg="hi there"`) {
+		t.Errorf("-log-format=text didn't override the environment, stderr: %s", stderr.String())
+	}
+
+	t.Setenv(envPrefix+"LOG_LEVEL", "loud")
+	stderr.Reset()
+	if code := run(context.Background(), &stdout, &stderr, []string{"recover"}); code != exitUsage {
+		t.Errorf("exit code with a bad %sLOG_LEVEL = %d; want %d", envPrefix, code, exitUsage)
+	}
+}
+
+func TestCommandlineConfig(t *testing.T) {
+	t.Setenv("FOO", "") // the example sets FOO, t.Setenv restores it afterwards
+	dir := t.TempDir()
+	file := filepath.Join(dir, "c.json")
+	if err := os.WriteFile(file, []byte(`{"word": "file", "numb": 7}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-config=" + file, "foo"}, "config: {Word:file Numb:7 Fork:false Svar:bar}"},
+		{[]string{"foo", "-config", file, "-numb=3"}, "config: {Word:file Numb:3 Fork:false Svar:bar}"},
+		{[]string{"-numb=42", "-config=" + file, "f", "-fork"}, "config: {Word:file Numb:42 Fork:true Svar:bar}"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run(context.Background(), &stdout, &stderr, append([]string{"commandline"}, tt.args...)); code != exitOK {
+			t.Errorf("%q: exit code = %d, stderr: %s", tt.args, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), tt.want) {
+			t.Errorf("%q: stdout = %q; want it to contain %q", tt.args, stdout.String(), tt.want)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), &stdout, &stderr, []string{"commandline", "-config=" + filepath.Join(dir, "nope.json"), "foo"}); code != exitUsage {
+		t.Errorf("exit code with a missing config file = %d; want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr.String(), "no such file") {
+		t.Errorf("stderr = %q; want the missing file", stderr.String())
+	}
+
+	// the help lists -config, and comes once
+	stdout.Reset()
+	stderr.Reset()
+	run(context.Background(), &stdout, &stderr, []string{"commandline", "-h"})
+	if out := stdout.String(); strings.Count(out, "usage: oneforall commandline") != 1 || !strings.Contains(out, "-config file") || stderr.Len() != 0 {
+		t.Errorf("help printed\n%s\nand on stderr\n%s", out, stderr.String())
+	}
+}
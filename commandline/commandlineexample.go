@@ -1,95 +1,207 @@
-package main
+// Package commandlineexample parses command line arguments, flags and subcommands, and reads environment variables
+package commandlineexample
 
 import (
+	"RobotTask/cli"
+	"RobotTask/config"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
 
-// go run commandline/commandlineexample.go -word=opt -numb=7 -fork -svar=flag -bar=1
-func main() {
+// Run runs the example with the arguments of the process
+func Run(ctx context.Context, w io.Writer) error {
+	return RunArgs(ctx, w, os.Args[1:])
+}
+
+// RunArgs runs the example with args, the arguments following the program name.
+// oneforall commandline -word=opt -numb=7 -fork -svar=flag foo -enable -name=joe a b
+func RunArgs(ctx context.Context, w io.Writer, args []string) error {
+	// the completion scripts and the shell run these, their output must be the script or the candidates alone
+	if len(args) > 0 && (args[0] == cli.CompleteCommand || args[0] == "completion") {
+		return commands(topLevelFlags().set, nil).Exec(ctx, w, args)
+	}
+
 	// use "./command-line-arguments a b c d" to intake arguments ,note that it is best to build first
 	// os.Args is a slice of the command-line arguments, starting with the program name.
-	argsWithProg := os.Args
+	// when run from the launcher, args holds what follows the example name instead
+	argsWithProg := append([]string{os.Args[0]}, args...)
 	// os.Args[1:] holds the argument to the program(lose the command name)
-	argsWithoutProg := os.Args[1:]
-	// get individual argument with normal indexing
-	arg := os.Args[1]
+	argsWithoutProg := args
 	// output these arguments
-	fmt.Println("arguments with program: ", argsWithProg)
-	fmt.Println("arguments without program: ", argsWithoutProg)
-	fmt.Println("first argument: ", arg)
+	fmt.Fprintln(w, "arguments with program: ", argsWithProg)
+	fmt.Fprintln(w, "arguments without program: ", argsWithoutProg)
+	// get individual argument with normal indexing, after checking there is one: args[0] panics on an empty slice
+	if len(args) > 0 {
+		fmt.Fprintln(w, "first argument: ", args[0])
+	}
+
+	top := topLevelFlags()
+	var root *cli.Command
+	// the top-level flags are global flags of the cli package, which parses them before or after the subcommand.
+	// their values are known once it has, so they are printed right before the subcommand runs
+	root = commands(top.set, func(w io.Writer) error {
+		// Here we’ll just dump out the parsed options. Note that we need to dereference the pointers with e.g. *top.word to get the actual option values.
+		fmt.Fprintln(w, "word:", *top.word)
+		fmt.Fprintln(w, "numb:", *top.numb)
+		fmt.Fprintln(w, "fork:", *top.fork)
+		fmt.Fprintln(w, "svar:", *top.svar)
+		// do this to get the flags: ./command-line-flags -word=opt -numb=7 -fork -svar=flag
+		// if the commandline flags are nil, they automatically take their default values
+		// note that the flag package requires all flags to appear before positional arguments(or they will be interpreteed as positional arguments)
+		// -h will automatically stop the program and print the help text, the one the cli package generates here
+		// if you provide a flag that wasn't specified, the program will print an error message and exit with status 2, wherever the flag is
+
+		// declaring the flags one by one, and reading the environment on the side, gets long with many options.
+		// the config package fills a struct from its tags instead: the defaults, a file named with -config, the COMMANDLINE_ environment
+		// variables, then the flags, each one overriding the previous ones. the same flags as above, COMMANDLINE_NUMB=7 sets numb.
+		// the loader gets back the top-level flags given on the command line, wherever they were
+		var opts struct {
+			Word string `default:"foo" help:"a string"`
+			Numb int    `default:"42" help:"an int"`
+			Fork bool   `help:"a bool"`
+			Svar string `default:"bar" help:"a string var"`
+		}
+		given := map[string]bool{}
+		root.Visit(func(f *flag.Flag) { given[f.Name] = true })
+		var flagArgs []string
+		top.set.VisitAll(func(f *flag.Flag) {
+			if given[f.Name] {
+				flagArgs = append(flagArgs, "-"+f.Name+"="+f.Value.String())
+			}
+		})
+		loader := config.Loader{Name: "commandline", EnvPrefix: "COMMANDLINE_", Args: flagArgs, Output: io.Discard}
+		if _, err := loader.Load(&opts); err != nil {
+			return &cli.UsageError{Command: root, Err: err}
+		}
+		fmt.Fprintf(w, "config: %+v\n", opts)
+		return nil
+	})
+	// the subcommand is expected after the top-level flags. the cli package finds it, parses its flags and runs it,
+	// a wrong flag, a missing or mistyped subcommand is a usage error: the launcher exits with status 2, like the flag package
+	if err := root.Exec(ctx, w, args); err != nil {
+		return err
+	}
 
+	// environment variables
+	// use os.setenv to set a key/value pair.
+	os.Setenv("FOO", "1")
+	// use os.getenv to get a value of a key
+	fmt.Fprintln(w, "FOO:", os.Getenv("FOO"))
+	fmt.Fprintln(w, "BAR:", os.Getenv("BAR"))
+	// Use os.Environ to list all key/value pairs in the environment. This returns a slice of strings in the form KEY=value.
+	// You can strings.SplitN them to get the key and value. Here we print all the keys.
+	fmt.Fprintln(w, "os.Environ:")
+	for _, e := range os.Environ() {
+		pair := strings.SplitN(e, "=", 2)
+		fmt.Fprintln(w, pair[0])
+	}
+	// BAR=2 oneforall commandline foo , set the bar in the environment first
+	return nil
+}
+
+// topFlags are the flags that come before or after the subcommand
+type topFlags struct {
+	set        *flag.FlagSet
+	word, svar *string
+	numb       *int
+	fork       *bool
+}
+
+// topLevelFlags declares the flags that come before or after the subcommand
+func topLevelFlags() topFlags {
 	// format of a flag: -word=opt
 	// basic flag declarations are available for string ,integer and boolean options.
 	// here we declare a string flag word with a default value "foo" and a short description.
 	// this flag.String function returns a string pointer (not a string value);
-	wordPtr := flag.String("word", "foo", "a string")
+	// the launcher has flags of its own, so the example declares its flags on a separate flag set rather than the global one.
+	// flag.String and friends work the same way on the global set, which flag.Parse parses from os.Args
+	flags := flag.NewFlagSet("commandline", flag.ContinueOnError)
+	word := flags.String("word", "foo", "a string")
 	// then we declare an int flag
-	numbPtr := flag.Int("numb", 42, "an int")
-	forkPtr := flag.Bool("fork", false, "a bool")
+	numb := flags.Int("numb", 42, "an int")
+	fork := flags.Bool("fork", false, "a bool")
 	// note that it is possible to declare an option that uses an existing var declared elsewhere in the program. note that we need to pass in a pointer to the flag declaration function.
-	var svar string
-	flag.StringVar(&svar, "svar", "bar", "a string var")
-	flag.Parse() // once all the flags are declared, call flag.parse to execute the command-line parsing
-	// Here we’ll just dump out the parsed options and any trailing positional arguments. Note that we need to dereference the pointers with e.g. *wordPtr to get the actual option values.
-	fmt.Println("word:", *wordPtr)
-	fmt.Println("numb:", *numbPtr)
-	fmt.Println("fork:", *forkPtr)
-	fmt.Println("svar:", svar)
-	fmt.Println("tail:", flag.Args())
-	// do this to get the flags: ./command-line-flags -word=opt -numb=7 -fork -svar=flag
-	// if the commandline flags are nil, they automatically take their default values
-	// note that the flag package requires all flags to appear before positional arguments(or they will be interpreteed as positional arguments)
-	// -h will automatically stop the program and print the help text
-	// if you provide a flag that wasn't specified to the falg package, the program will print an error message and show the help text again
+	var svarVar string
+	flags.StringVar(&svarVar, "svar", "bar", "a string var")
+	// the config file of the config loader, which reads the flags above from it too
+	flags.String(config.ConfigFlag, "", "config `file`, json, yaml or toml (env COMMANDLINE_CONFIG)")
+	return topFlags{set: flags, word: word, svar: &svarVar, numb: numb, fork: fork}
+}
 
+// commands returns the subcommands of the example, the top-level flags are global: foo -numb=7 works too.
+// before runs ahead of each subcommand, once all the flags are parsed, it is nil for the completion
+func commands(flags *flag.FlagSet, before func(w io.Writer) error) *cli.Command {
 	// we declare a subcommand using the NewFlagSet function, and proceed to declare flags specific to this subcommand.
-	fooCmd := flag.NewFlagSet("foo", flag.ExitOnError)
+	fooCmd := flag.NewFlagSet("foo", flag.ContinueOnError)
 	fooEnable := fooCmd.Bool("enable", false, "enable")
 	fooName := fooCmd.String("name", "", "name")
 
 	// For a different subcommand we can define different supported flags.
-	barCmd := flag.NewFlagSet("bar", flag.ExitOnError)
+	barCmd := flag.NewFlagSet("bar", flag.ContinueOnError)
 	barLevel := barCmd.Int("level", 0, "level")
-	//The subcommand is expected as the first argument to the program.
-	if len(os.Args) < 2 {
-		fmt.Println("expected 'foo' or 'bar' subcommands")
-		os.Exit(1)
-	}
 
-	// check which subcommand is invoked
-	switch os.Args[1] {
-	// for every subcommand, we parse its own flags and have access to trailing positional arguments
-	case "foo":
-		fooCmd.Parse(os.Args[2:])
-		fmt.Println("subcommand 'foo'")
-		fmt.Println("  enable:", *fooEnable)
-		fmt.Println("  name:", *fooName)
-		fmt.Println("  tail:", fooCmd.Args())
-	case "bar":
-		barCmd.Parse(os.Args[2:])
-		fmt.Println("subcommand 'bar'")
-		fmt.Println("  level:", *barLevel)
-		fmt.Println("  tail:", barCmd.Args())
-	default:
-		fmt.Println("expected 'foo' or 'bar' subcommands")
-		os.Exit(1)
+	// for every subcommand, the cli package parses its own flags and passes the trailing positional arguments
+	root := &cli.Command{
+		Name:    "oneforall commandline",
+		Summary: "command line arguments, flags, subcommands and environment variables",
+		Global:  flags,
+		Commands: []*cli.Command{
+			{
+				Name:    "foo",
+				Aliases: []string{"f"},
+				Summary: "print the foo flags and arguments",
+				Args:    "[arguments]",
+				Flags:   fooCmd,
+				// -name completes with the user name, the arguments with the names of the environment variables
+				Complete: map[string]cli.Completer{
+					"name": func(string) []string { return []string{os.Getenv("USER")} },
+					"":     envNames,
+				},
+				Run: func(ctx context.Context, w io.Writer, args []string) error {
+					if err := before(w); err != nil {
+						return err
+					}
+					fmt.Fprintln(w, "subcommand 'foo'")
+					fmt.Fprintln(w, "  enable:", *fooEnable)
+					fmt.Fprintln(w, "  name:", *fooName)
+					fmt.Fprintln(w, "  tail:", args)
+					return nil
+				},
+			},
+			{
+				Name:     "bar",
+				Aliases:  []string{"b"},
+				Summary:  "print the bar level and arguments",
+				Args:     "[arguments]",
+				Flags:    barCmd,
+				Complete: map[string]cli.Completer{"level": cli.Values("0", "1", "2", "3")},
+				Run: func(ctx context.Context, w io.Writer, args []string) error {
+					if err := before(w); err != nil {
+						return err
+					}
+					fmt.Fprintln(w, "subcommand 'bar'")
+					fmt.Fprintln(w, "  level:", *barLevel)
+					fmt.Fprintln(w, "  tail:", args)
+					return nil
+				},
+			},
+		},
 	}
+	// source <(oneforall commandline completion bash) completes the subcommands and their flags
+	root.Commands = append(root.Commands, cli.CompletionCommand(root))
+	return root
+}
 
-	// environment variables
-	// use os.setenv to set a key/value pair.
-	os.Setenv("FOO", "1")
-	// use os.getenv to get a value of a key
-	fmt.Println("FOO:", os.Getenv("FOO"))
-	fmt.Println("BAR:", os.Getenv("BAR"))
-	// Use os.Environ to list all key/value pairs in the environment. This returns a slice of strings in the form KEY=value.
-	// You can strings.SplitN them to get the key and value. Here we print all the keys.
-	fmt.Println("os.Environ:")
+// envNames returns the names of the environment variables
+func envNames(string) []string {
+	var names []string
 	for _, e := range os.Environ() {
-		pair := strings.SplitN(e, "=", 2)
-		fmt.Println(pair[0])
+		name, _, _ := strings.Cut(e, "=")
+		names = append(names, name)
 	}
-	// BAR=2 go run environment-variables.go , set the bar in the environment first
+	return names
 }
@@ -0,0 +1,379 @@
+// Package config fills a struct from four sources, each one overriding the previous ones:
+// the defaults in the struct tags, a json, yaml or toml file, environment variables with a prefix, then the command line flags.
+// the commandline example declares its flags one by one and reads the environment on its own, here the struct declares both:
+//
+//	type Options struct {
+//		Addr    string        `default:":8090" help:"address to listen on"`
+//		Timeout time.Duration `default:"15s" help:"request timeout"`
+//		Token   string        `required:"true" help:"api token"`
+//		DB      struct {
+//			Host string `default:"localhost"`
+//		}
+//	}
+//
+//	var opts Options
+//	args, err := config.Loader{Name: "app", EnvPrefix: "APP_", Args: os.Args[1:]}.Load(&opts)
+//
+// every field has a name, from the config tag or the field name in kebab case: "addr", "timeout", "token", "db.host".
+// the name is the flag (-db.host), the key in the file (db: {host: ...}) and, upper cased with dots and dashes turned into
+// underscores, the environment variable after the prefix (APP_DB_HOST). the env tag replaces the variable name, prefix included.
+// the file is named with the -config flag, the <prefix>CONFIG variable or Loader.File, its extension gives its format.
+// the fields can be strings, bools, numbers, time.Duration, slices of those (comma separated in flags and variables),
+// any encoding.TextUnmarshaler, and structs of those
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Loader loads a struct, its zero value reads the environment without prefix and no flags
+type Loader struct {
+	// Name is the name of the program in the help, the file must be given with Args, not through os.Args
+	Name string
+	// EnvPrefix is put before the names of the environment variables, like "APP_"
+	EnvPrefix string
+	// File is the config file read when neither -config nor <prefix>CONFIG name one, empty for none
+	File string
+	// Args are the command line arguments, without the program name
+	Args []string
+	// LookupEnv reads the environment, os.LookupEnv if nil
+	LookupEnv func(string) (string, bool)
+	// Output is where -h writes the help and the flag errors are reported, os.Stderr if nil
+	Output io.Writer
+}
+
+// ConfigFlag is the flag naming the config file, the environment variable is the prefix followed by CONFIG
+const ConfigFlag = "config"
+
+// field is a settable leaf of the struct
+type field struct {
+	name     string // dotted, like "db.host"
+	env      string
+	def      string
+	help     string
+	required bool
+	v        reflect.Value
+}
+
+// Load fills dst, a pointer to a struct, and returns the arguments left after the flags.
+// -h and -help print the help and return flag.ErrHelp. the errors name the source of the wrong value,
+// and all the required fields left empty are reported together
+func (l Loader) Load(dst any) ([]string, error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: Load wants a pointer to a struct, got %T", dst)
+	}
+	lookup := l.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	out := l.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	fields, err := l.fields(rv.Elem(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	// the names of the fields a source gave a value, even a zero one like -verbose=false
+	given := map[string]bool{}
+
+	// defaults
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err := set(f.v, f.def); err != nil {
+			return nil, fmt.Errorf("config: default of %s: %w", f.name, err)
+		}
+		given[f.name] = true
+	}
+
+	// file
+	file := l.File
+	if v, ok := lookup(l.EnvPrefix + "CONFIG"); ok {
+		file = v
+	}
+	if v, ok := configArg(l.Args, fields); ok {
+		file = v
+	}
+	if file != "" {
+		keys, err := loadFile(file, fields)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %w", file, err)
+		}
+		for _, k := range keys {
+			given[k] = true
+		}
+	}
+
+	// environment
+	var errs []error
+	for _, f := range fields {
+		if s, ok := lookup(f.env); ok {
+			if err := set(f.v, s); err != nil {
+				errs = append(errs, fmt.Errorf("environment variable %s: %w", f.env, err))
+			}
+			given[f.name] = true
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	// flags, declared once the other sources are in so that the help shows the values they gave
+	fs := flag.NewFlagSet(l.Name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.String(ConfigFlag, file, "config `file`, json, yaml or toml (env "+l.EnvPrefix+"CONFIG)")
+	for _, f := range fields {
+		fs.Var(value{f.v}, f.name, usage(f))
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(out, "usage of %s:\n", l.Name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(l.Args); err != nil {
+		return nil, err
+	}
+	fs.Visit(func(fl *flag.Flag) { given[fl.Name] = true })
+
+	// required fields
+	var missing []string
+	for _, f := range fields {
+		if f.required && !given[f.name] {
+			missing = append(missing, fmt.Sprintf("%s (-%s or %s)", f.name, f.name, f.env))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("config: missing required %s", strings.Join(missing, ", "))
+	}
+	return fs.Args(), nil
+}
+
+// usage is the help of a flag: the help tag, the variable, and whether it is required
+func usage(f field) string {
+	s := f.help
+	if s != "" {
+		s += " "
+	}
+	s += "(env " + f.env
+	if f.required {
+		s += ", required"
+	}
+	return s + ")"
+}
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// fields lists the leaves of the struct v, their names prefixed with prefix
+func (l Loader) fields(v reflect.Value, prefix string) ([]field, error) {
+	var out []field
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("config")
+		if !sf.IsExported() || tag == "-" {
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = kebab(sf.Name)
+		}
+		name = prefix + name
+		fv := v.Field(i)
+		if sf.Type.Kind() == reflect.Struct && !reflect.PointerTo(sf.Type).Implements(textUnmarshaler) {
+			nested, err := l.fields(fv, name+".")
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, nested...)
+			continue
+		}
+		if !settable(sf.Type) {
+			return nil, fmt.Errorf("config: field %s has the unsupported type %s", sf.Name, sf.Type)
+		}
+		env := sf.Tag.Get("env")
+		if env == "" {
+			env = l.EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(name))
+		}
+		out = append(out, field{
+			name:     name,
+			env:      env,
+			def:      sf.Tag.Get("default"),
+			help:     sf.Tag.Get("help"),
+			required: sf.Tag.Get("required") == "true",
+			v:        fv,
+		})
+	}
+	return out, nil
+}
+
+// kebab turns MaxSize into max-size and HTTPAddr into http-addr
+func kebab(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// a word starts at an upper case letter following a lower case one, or followed by one after a run of upper case
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// configArg finds the value of the -config flag in args, before the flags are parsed. it walks them like the flag
+// package does: the flags of the fields other than bools take the next argument as their value when they have no =,
+// and the flags end at the first other argument or at --. the last -config wins, like it does for fs.Parse
+func configArg(args []string, fields []field) (string, bool) {
+	takesValue := map[string]bool{ConfigFlag: true}
+	for _, f := range fields {
+		takesValue[f.name] = f.v.Kind() != reflect.Bool
+	}
+	file, found := "", false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" || len(a) < 2 || a[0] != '-' {
+			break
+		}
+		name, val, hasVal := strings.Cut(strings.TrimPrefix(a[1:], "-"), "=")
+		if hasVal || !takesValue[name] {
+			if name == ConfigFlag {
+				file, found = val, true
+			}
+			continue
+		}
+		if i+1 >= len(args) {
+			break
+		}
+		i++
+		if name == ConfigFlag {
+			file, found = args[i], true
+		}
+	}
+	return file, found
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// settable reports whether set can parse a string into a value of type t
+func settable(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Slice && settable(t.Elem())
+	}
+	return false
+}
+
+// set parses s into v, a slice takes comma separated values
+func set(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+		return setSlice(v, parts)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// setSlice replaces the content of the slice v with the parsed parts
+func setSlice(v reflect.Value, parts []string) error {
+	sl := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+	for i, p := range parts {
+		if err := set(sl.Index(i), strings.TrimSpace(p)); err != nil {
+			return err
+		}
+	}
+	v.Set(sl)
+	return nil
+}
+
+// value is the flag.Value of a field
+type value struct {
+	v reflect.Value
+}
+
+func (f value) String() string {
+	if !f.v.IsValid() || f.v.IsZero() {
+		return "" // the flag package leaves out the zero defaults
+	}
+	if m, ok := f.v.Interface().(encoding.TextMarshaler); ok {
+		b, _ := m.MarshalText()
+		return string(b)
+	}
+	if f.v.Kind() == reflect.Slice {
+		parts := make([]string, f.v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(f.v.Index(i).Interface())
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(f.v.Interface())
+}
+
+func (f value) Set(s string) error { return set(f.v, s) }
+
+// IsBoolFlag lets the bool fields be set with -name alone, like flag.Bool
+func (f value) IsBoolFlag() bool { return f.v.Kind() == reflect.Bool }
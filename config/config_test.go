@@ -0,0 +1,288 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type options struct {
+	Addr     string        `default:":8090" help:"address to listen on"`
+	Timeout  time.Duration `default:"15s" help:"request timeout"`
+	Workers  int           `default:"4"`
+	Verbose  bool
+	Ratio    float64    `default:"0.5"`
+	Tags     []string   `help:"tags, comma separated"`
+	Level    slog.Level `default:"info"` // an encoding.TextUnmarshaler
+	Token    string     `required:"true" help:"api token"`
+	Home     string     `config:"home-dir" env:"HOME_DIR"`
+	Internal string     `config:"-"`
+	DB       struct {
+		Host    string `default:"localhost"`
+		MaxConn int    `default:"10"`
+	}
+}
+
+// env returns a lookup function over a fixed environment
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+// writeFile writes content to name in a new temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPrecedence(t *testing.T) {
+	file := writeFile(t, "app.yaml", `
+addr: ":9000"
+timeout: 30s
+workers: 8
+tags: [a, b]
+db:
+  host: db.internal
+  max-conn: 20
+`)
+	l := Loader{
+		Name:      "app",
+		EnvPrefix: "APP_",
+		Args:      []string{"-config", file, "-workers=16", "-verbose", "rest"},
+		LookupEnv: env(map[string]string{
+			"APP_TIMEOUT":  "1m", // env over the file
+			"APP_WORKERS":  "12", // the flag wins over it
+			"APP_TOKEN":    "s3cr3t",
+			"APP_DB_HOST":  "db.env", // nested names get underscores
+			"HOME_DIR":     "/home/joe",
+			"APP_INTERNAL": "ignored",
+		}),
+	}
+	var o options
+	args, err := l.Load(&o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 1 || args[0] != "rest" {
+		t.Errorf("args = %q; want [rest]", args)
+	}
+	var tests = []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"addr from the file", o.Addr, ":9000"},
+		{"timeout from env", o.Timeout, time.Minute},
+		{"workers from the flag", o.Workers, 16},
+		{"verbose from the flag", o.Verbose, true},
+		{"ratio default", o.Ratio, 0.5},
+		{"tags from the file", o.Tags, []string{"a", "b"}},
+		{"level default", o.Level, slog.LevelInfo},
+		{"token from env", o.Token, "s3cr3t"},
+		{"home from the env tag", o.Home, "/home/joe"},
+		{"internal untouched", o.Internal, ""},
+		{"db.host from env", o.DB.Host, "db.env"},
+		{"db.max-conn from the file", o.DB.MaxConn, 20},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: got %v; want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestFileFormats(t *testing.T) {
+	var tests = []struct {
+		name    string
+		content string
+	}{
+		{"app.json", `{"workers": 3, "tags": ["x", "y"], "level": "debug", "db": {"host": "h", "max-conn": 5}, "token": "t"}`},
+		{"app.yaml", "workers: 3\ntags: [x, y]\nlevel: debug\ndb:\n  host: h\n  max-conn: 5\ntoken: t\n"},
+		{"app.toml", "workers = 3\ntags = ['x', 'y']\nlevel = 'debug'\ntoken = 't'\n[db]\nhost = 'h'\nmax-conn = 5\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o options
+			l := Loader{File: writeFile(t, tt.name, tt.content), LookupEnv: env(nil)}
+			if _, err := l.Load(&o); err != nil {
+				t.Fatal(err)
+			}
+			if o.Workers != 3 || !reflect.DeepEqual(o.Tags, []string{"x", "y"}) || o.Level != slog.LevelDebug || o.DB.Host != "h" || o.DB.MaxConn != 5 {
+				t.Errorf("options = %+v", o)
+			}
+			if o.Addr != ":8090" {
+				t.Errorf("addr = %q; the default was lost", o.Addr)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	file := writeFile(t, "app.json", `{"token": "from-file"}`)
+	var o options
+	l := Loader{EnvPrefix: "APP_", LookupEnv: env(map[string]string{"APP_CONFIG": file})}
+	if _, err := l.Load(&o); err != nil || o.Token != "from-file" {
+		t.Errorf("token = %q, %v", o.Token, err)
+	}
+}
+
+func TestConfigAfterFlags(t *testing.T) {
+	file := writeFile(t, "app.json", `{"workers": 7}`)
+	var tests = [][]string{
+		{"-addr", ":1", "-config", file},           // the value of -addr is not the end of the flags
+		{"-verbose", "-config=" + file},            // a bool takes no value
+		{"--addr=:1", "--config", file},            // two dashes
+		{"-config", "other.json", "-config", file}, // the last one wins
+	}
+	for _, args := range tests {
+		var o options
+		l := Loader{Args: append(args, "-token=t"), LookupEnv: env(nil)}
+		if _, err := l.Load(&o); err != nil || o.Workers != 7 {
+			t.Errorf("%q: workers = %d, %v; want 7 from the file", args, o.Workers, err)
+		}
+	}
+
+	// after the flags, -config is an argument
+	var o options
+	args, err := (Loader{Args: []string{"-token=t", "rest", "-config", file}, LookupEnv: env(nil)}).Load(&o)
+	if err != nil || o.Workers != 4 || len(args) != 3 {
+		t.Errorf("workers = %d, args %q, %v; want the default and 3 args", o.Workers, args, err)
+	}
+}
+
+func TestRequiredZeroValues(t *testing.T) {
+	type required struct {
+		On   bool `required:"true"`
+		Numb int  `required:"true"`
+	}
+	var o required
+	if _, err := (Loader{Args: []string{"-on=false", "-numb=0"}, LookupEnv: env(nil)}).Load(&o); err != nil {
+		t.Errorf("zero values given as flags: %v", err)
+	}
+	file := writeFile(t, "app.yaml", "on: false\n")
+	l := Loader{File: file, LookupEnv: env(map[string]string{"NUMB": "0"})}
+	if _, err := l.Load(&o); err != nil {
+		t.Errorf("zero values from the file and the environment: %v", err)
+	}
+	_, err := (Loader{Args: []string{"-numb=0"}, LookupEnv: env(nil)}).Load(&o)
+	if err == nil || !strings.Contains(err.Error(), "missing required on (-on or ON)") || strings.Contains(err.Error(), "numb") {
+		t.Errorf("err = %v; want on missing, not numb", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var tests = []struct {
+		name string
+		l    Loader
+		want []string
+	}{
+		{
+			"missing required",
+			Loader{EnvPrefix: "APP_", LookupEnv: env(nil)},
+			[]string{"missing required token (-token or APP_TOKEN)"},
+		},
+		{
+			"bad env values, all of them",
+			Loader{EnvPrefix: "APP_", LookupEnv: env(map[string]string{"APP_WORKERS": "many", "APP_TIMEOUT": "soon"})},
+			[]string{"APP_WORKERS", "APP_TIMEOUT"},
+		},
+		{
+			"bad flag",
+			Loader{Args: []string{"-ratio=half"}, LookupEnv: env(nil), Output: &bytes.Buffer{}},
+			[]string{`invalid value "half" for flag -ratio`},
+		},
+		{
+			"unknown key in the file",
+			Loader{File: writeFile(t, "app.json", `{"db": {"hots": "typo"}}`), LookupEnv: env(nil)},
+			[]string{"unknown key db.hots"},
+		},
+		{
+			"wrong type in the file",
+			Loader{File: writeFile(t, "app.yaml", "workers: [1, 2]\n"), LookupEnv: env(nil)},
+			[]string{"workers: got a list for a single value"},
+		},
+		{
+			"unknown format",
+			Loader{File: writeFile(t, "app.ini", "workers=1"), LookupEnv: env(nil)},
+			[]string{`unknown format ".ini"`},
+		},
+		{
+			"missing file",
+			Loader{File: "/nonexistent/app.json", LookupEnv: env(nil)},
+			[]string{"config file /nonexistent/app.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o options
+			_, err := tt.l.Load(&o)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("err = %v; want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+
+	var notStruct int
+	if _, err := (Loader{}).Load(&notStruct); err == nil {
+		t.Error("a pointer to an int was accepted")
+	}
+	var unsupported struct{ C chan int }
+	if _, err := (Loader{LookupEnv: env(nil)}).Load(&unsupported); err == nil {
+		t.Error("a chan field was accepted")
+	}
+}
+
+func TestHelp(t *testing.T) {
+	var out bytes.Buffer
+	var o options
+	l := Loader{
+		Name:      "app",
+		EnvPrefix: "APP_",
+		Args:      []string{"-h"},
+		LookupEnv: env(map[string]string{"APP_WORKERS": "12"}),
+		Output:    &out,
+	}
+	if _, err := l.Load(&o); !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("err = %v; want flag.ErrHelp", err)
+	}
+	help := out.String()
+	for _, want := range []string{
+		"usage of app:",
+		"-addr value\n    \taddress to listen on (env APP_ADDR) (default :8090)",
+		"(env APP_WORKERS) (default 12)", // the default shown is the value from the other sources
+		"-db.max-conn value",
+		"api token (env APP_TOKEN, required)",
+		"-config file\n    \tconfig file, json, yaml or toml (env APP_CONFIG)",
+		"-verbose\n",
+	} {
+		if !strings.Contains(help, want) {
+			t.Errorf("help doesn't contain %q:\n%s", want, help)
+		}
+	}
+}
+
+func TestKebab(t *testing.T) {
+	for in, want := range map[string]string{"Addr": "addr", "MaxSize": "max-size", "HTTPAddr": "http-addr", "DB": "db", "UserID": "user-id"} {
+		if got := kebab(in); got != want {
+			t.Errorf("kebab(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadFile sets the fields from the file at path, a json, yaml or toml file by its extension.
+// the file is decoded to maps first, so that its keys are the names of the fields whatever the format.
+// a key that isn't a field is an error, it is most likely a typo. it returns the names of the fields it set
+func loadFile(path string, fields []field) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber() // keeps the large integers exact
+		err = dec.Decode(&m)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &m)
+	case ".toml":
+		err = toml.Unmarshal(data, &m)
+	default:
+		return nil, fmt.Errorf("unknown format %q, want .json, .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+	flatten(m, "", values)
+	byName := map[string]field{}
+	for _, f := range fields {
+		byName[f.name] = f
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys) // the errors come in the same order every time
+	for _, k := range keys {
+		f, ok := byName[k]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %s", k)
+		}
+		if err := setAny(f, values[k]); err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return keys, nil
+}
+
+// flatten collects the leaves of the nested maps m into out, keyed by their dotted path
+func flatten(m map[string]any, prefix string, out map[string]any) {
+	for k, v := range m {
+		if nested, ok := v.(map[string]any); ok {
+			flatten(nested, prefix+k+".", out)
+			continue
+		}
+		out[prefix+k] = v
+	}
+}
+
+// setAny sets the field from a decoded value, a list for a slice, a scalar otherwise.
+// a slice can also be given a single comma separated string, like in the environment
+func setAny(f field, v any) error {
+	list, ok := v.([]any)
+	if !ok {
+		return set(f.v, scalar(v))
+	}
+	if f.v.Kind() != reflect.Slice || isTextField(f) {
+		return errors.New("got a list for a single value")
+	}
+	parts := make([]string, len(list))
+	for i, e := range list {
+		parts[i] = scalar(e)
+	}
+	return setSlice(f.v, parts)
+}
+
+// isTextField reports whether the field parses itself from text, a slice type can
+func isTextField(f field) bool {
+	_, ok := f.v.Addr().Interface().(encoding.TextUnmarshaler)
+	return ok
+}
+
+// scalar formats a decoded value the way set parses it
+func scalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64) // not 1e+06, which ParseInt refuses
+	case time.Time:
+		return v.Format(time.RFC3339Nano) // yaml and toml decode dates themselves
+	}
+	return fmt.Sprint(v)
+}
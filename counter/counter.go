@@ -0,0 +1,143 @@
+// Package counter provides Sharded, a concurrent map of named counters.
+// the Container in the goroutine example guards a single map with a single mutex, so every increment
+// contends on the same lock. Sharded splits the keys over several maps, each with its own mutex,
+// so goroutines working on different keys rarely wait for each other.
+package counter
+
+import (
+	"cmp"
+	"hash/maphash"
+	"runtime"
+	"slices"
+	"sync"
+	"unsafe"
+)
+
+// cacheLine is the size of a cache line on most cpus
+const cacheLine = 64
+
+// shardFields are the fields of a shard, without its padding
+type shardFields struct {
+	mu       sync.Mutex
+	counters map[string]int64
+}
+
+// shard is one lock and the counters it guards.
+// the padding rounds its size up to a multiple of a cache line, so that neighbouring shards in the slice
+// don't share one and their mutexes don't slow each other down
+type shard struct {
+	shardFields
+	_ [cacheLine - unsafe.Sizeof(shardFields{})%cacheLine]byte
+}
+
+// Sharded is a set of named counters that is safe for concurrent use.
+// note that it must not be copied after first use, so pass it around by pointer
+type Sharded struct {
+	seed   maphash.Seed
+	shards []shard
+}
+
+// Entry is a single counter, as returned by TopN
+type Entry struct {
+	Key   string
+	Count int64
+}
+
+// New returns a Sharded counter with the given number of shards.
+// a number less than 1 picks a default based on GOMAXPROCS
+func New(shards int) *Sharded {
+	if shards < 1 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+	c := &Sharded{seed: maphash.MakeSeed(), shards: make([]shard, shards)}
+	for i := range c.shards {
+		c.shards[i].counters = make(map[string]int64)
+	}
+	return c
+}
+
+// shardFor picks the shard a key lives in
+func (c *Sharded) shardFor(key string) *shard {
+	return &c.shards[maphash.String(c.seed, key)%uint64(len(c.shards))]
+}
+
+// Inc increments key by one
+func (c *Sharded) Inc(key string) {
+	c.Add(key, 1)
+}
+
+// Add adds n to key, n may be negative
+func (c *Sharded) Add(key string, n int64) {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters[key] += n
+}
+
+// Get returns the current value of key, 0 if it was never set
+func (c *Sharded) Get(key string) int64 {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counters[key]
+}
+
+// Reset sets every counter back to zero, forgetting all the keys
+func (c *Sharded) Reset() {
+	c.lockAll()
+	defer c.unlockAll()
+	for i := range c.shards {
+		clear(c.shards[i].counters)
+	}
+}
+
+// Snapshot returns a copy of all the counters.
+// every shard is locked while copying, so the result is consistent: no increment is half seen
+func (c *Sharded) Snapshot() map[string]int64 {
+	c.lockAll()
+	defer c.unlockAll()
+	n := 0
+	for i := range c.shards {
+		n += len(c.shards[i].counters)
+	}
+	snap := make(map[string]int64, n)
+	for i := range c.shards {
+		for k, v := range c.shards[i].counters {
+			snap[k] = v
+		}
+	}
+	return snap
+}
+
+// TopN returns the n counters with the highest values, highest first.
+// counters with the same value are ordered by key so the result is stable
+func (c *Sharded) TopN(n int) []Entry {
+	if n <= 0 {
+		return nil
+	}
+	snap := c.Snapshot()
+	entries := make([]Entry, 0, len(snap))
+	for k, v := range snap {
+		entries = append(entries, Entry{Key: k, Count: v})
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return entries[:min(n, len(entries))]
+}
+
+// lockAll locks the shards, always in the same order so two callers can't deadlock
+func (c *Sharded) lockAll() {
+	for i := range c.shards {
+		c.shards[i].mu.Lock()
+	}
+}
+
+func (c *Sharded) unlockAll() {
+	for i := range c.shards {
+		c.shards[i].mu.Unlock()
+	}
+}
@@ -0,0 +1,106 @@
+package counter
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestIncAddGet(t *testing.T) {
+	c := New(4)
+	c.Inc("a")
+	c.Inc("a")
+	c.Add("b", 10)
+	c.Add("b", -3)
+
+	var tests = []struct {
+		key  string
+		want int64
+	}{
+		{"a", 2},
+		{"b", 7},
+		{"missing", 0},
+	}
+	for _, tt := range tests {
+		if got := c.Get(tt.key); got != tt.want {
+			t.Errorf("Get(%q) = %d; want %d", tt.key, got, tt.want)
+		}
+	}
+}
+
+// this is the workload of the Container example: three goroutines, two of them on the same key
+func TestConcurrentInc(t *testing.T) {
+	c := New(0)
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "a", "b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10000 {
+				c.Inc(key)
+			}
+		}()
+	}
+	wg.Wait()
+	want := map[string]int64{"a": 20000, "b": 10000}
+	if got := c.Snapshot(); !maps.Equal(got, want) {
+		t.Errorf("Snapshot() = %v; want %v", got, want)
+	}
+}
+
+func TestSnapshotIsACopy(t *testing.T) {
+	c := New(2)
+	c.Inc("a")
+	snap := c.Snapshot()
+	c.Inc("a")
+	snap["a"] = 100
+	if snap["a"] != 100 || c.Get("a") != 2 {
+		t.Errorf("snapshot and counter are linked: snapshot %d, counter %d", snap["a"], c.Get("a"))
+	}
+}
+
+func TestReset(t *testing.T) {
+	c := New(2)
+	c.Add("a", 5)
+	c.Add("b", 5)
+	c.Reset()
+	if got := c.Snapshot(); len(got) != 0 {
+		t.Errorf("Snapshot() after Reset = %v; want empty", got)
+	}
+	c.Inc("a")
+	if c.Get("a") != 1 {
+		t.Errorf("Get(a) after Reset and Inc = %d; want 1", c.Get("a"))
+	}
+}
+
+func TestTopN(t *testing.T) {
+	c := New(3)
+	c.Add("low", 1)
+	c.Add("high", 10)
+	c.Add("mid-b", 5)
+	c.Add("mid-a", 5)
+
+	var tests = []struct {
+		n    int
+		want []Entry
+	}{
+		{0, nil},
+		{1, []Entry{{"high", 10}}},
+		// ties are broken by key
+		{3, []Entry{{"high", 10}, {"mid-a", 5}, {"mid-b", 5}}},
+		{10, []Entry{{"high", 10}, {"mid-a", 5}, {"mid-b", 5}, {"low", 1}}},
+	}
+	for _, tt := range tests {
+		if got := c.TopN(tt.n); !slices.Equal(got, tt.want) {
+			t.Errorf("TopN(%d) = %v; want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestShardSize(t *testing.T) {
+	if size := unsafe.Sizeof(shard{}); size%cacheLine != 0 {
+		t.Errorf("a shard takes %d bytes, not a multiple of %d", size, cacheLine)
+	}
+}
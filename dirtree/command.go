@@ -0,0 +1,70 @@
+package dirtree
+
+import (
+	"RobotTask/logging"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// RunArgs runs the tree command on the directory named in args, the current directory if there is none:
+//
+//	oneforall tree [-include=glob]... [-exclude=glob]... [-depth=n] [-size] [-mtime] [-symlinks=list|skip|follow] [-format=text|json|csv] [dir]
+//
+// the directories that can't be read are shown with their error, the walk goes on without them
+func RunArgs(ctx context.Context, w io.Writer, args []string) error {
+	var (
+		opts   Options
+		cols   Columns
+		format string
+	)
+	fs := flag.NewFlagSet("tree", flag.ContinueOnError)
+	fs.Func("include", "keep only the files matching the glob, can be repeated", func(s string) error {
+		opts.Include = append(opts.Include, s)
+		return nil
+	})
+	fs.Func("exclude", "leave out the files and directories matching the glob, can be repeated", func(s string) error {
+		opts.Exclude = append(opts.Exclude, s)
+		return nil
+	})
+	fs.IntVar(&opts.MaxDepth, "depth", 0, "how many levels below the directory to walk, 0 for all of them")
+	fs.BoolVar(&cols.Size, "size", false, "show the sizes, the total below them for directories")
+	fs.BoolVar(&cols.ModTime, "mtime", false, "show the modification times")
+	fs.Func("symlinks", "what to do with symbolic links: list, skip or follow (default list)", func(s string) error {
+		p, err := ParseSymlinkPolicy(s)
+		opts.Symlinks = p
+		return err
+	})
+	fs.StringVar(&format, "format", "text", "output format: text, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("tree takes a single directory, got %q", fs.Args())
+	}
+	write, ok := map[string]func(*Entry) error{
+		"text": func(e *Entry) error { return WriteText(w, e, cols) },
+		"json": func(e *Entry) error { return WriteJSON(w, e) },
+		"csv":  func(e *Entry) error { return WriteCSV(w, e) },
+	}[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, want text, json or csv", format)
+	}
+
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+	tree, err := Walk(ctx, root, opts)
+	if err != nil {
+		return err
+	}
+	if err := write(tree.Root); err != nil {
+		return err
+	}
+	if len(tree.Errors) > 0 {
+		logging.FromContext(ctx).Warn("some entries couldn't be read", "root", root, "errors", len(tree.Errors))
+	}
+	return nil
+}
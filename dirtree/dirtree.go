@@ -0,0 +1,326 @@
+// Package dirtree grows the visit function of the file example into a tree tool: it walks a directory with filepath.WalkDir
+// and keeps what it finds as a tree of entries, with their sizes and modification times.
+// directories carry the total size and the number of files and directories below them.
+// the walk can be limited with include and exclude globs and a maximum depth, symbolic links are listed, skipped or followed,
+// and a directory that can't be read is recorded on its entry instead of stopping the walk.
+// the tree is written as text, like the tree command, as json or as csv
+package dirtree
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Kind is the type of an entry
+type Kind string
+
+const (
+	File    Kind = "file"
+	Dir     Kind = "dir"
+	Symlink Kind = "symlink" // a link that is listed, not followed
+	Other   Kind = "other"   // devices, sockets, pipes
+)
+
+// SymlinkPolicy tells the walk what to do with symbolic links
+type SymlinkPolicy int
+
+const (
+	SymlinksList   SymlinkPolicy = iota // list the link and its target without following it, like the tree command
+	SymlinksSkip                        // leave the links out
+	SymlinksFollow                      // walk the target as if it were in place of the link, links back to an ancestor are not followed
+)
+
+var symlinkPolicies = []string{"list", "skip", "follow"}
+
+func (p SymlinkPolicy) String() string {
+	if int(p) < len(symlinkPolicies) {
+		return symlinkPolicies[p]
+	}
+	return fmt.Sprintf("SymlinkPolicy(%d)", int(p))
+}
+
+// ParseSymlinkPolicy parses "list", "skip" or "follow"
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	for i, name := range symlinkPolicies {
+		if s == name {
+			return SymlinkPolicy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown symlink policy %q, want one of %s", s, strings.Join(symlinkPolicies, ", "))
+}
+
+// Options controls the walk
+type Options struct {
+	// Include keeps only the files matching one of the globs, directories are always walked.
+	// a glob without a slash matches the name of the file, one with a slash matches its path relative to the root, like "cmd/*/main.go"
+	Include []string
+	// Exclude leaves out the files and directories matching one of the globs, an excluded directory isn't walked
+	Exclude []string
+	// MaxDepth stops the walk at that many levels below the root, 0 means no limit.
+	// the sizes of the directories at the limit only count what was walked, which is nothing below them
+	MaxDepth int
+	Symlinks SymlinkPolicy
+}
+
+// Entry is a file or a directory of the tree
+type Entry struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"` // the root joined with the path below it
+	Kind    Kind      `json:"kind"`
+	Size    int64     `json:"size"` // for a directory, the sum of the sizes of the entries below it
+	ModTime time.Time `json:"mtime"`
+	Target  string    `json:"target,omitempty"` // where a symbolic link points to
+	Files   int       `json:"files,omitempty"`  // for a directory, the number of files below it
+	Dirs    int       `json:"dirs,omitempty"`   // for a directory, the number of directories below it
+	// Err is why the entry couldn't be read, the walk goes on without it. for a directory, its content is missing
+	Err      string   `json:"error,omitempty"`
+	Children []*Entry `json:"children,omitempty"`
+}
+
+// Tree is the result of a walk
+type Tree struct {
+	Root *Entry
+	// Errors are the errors recorded on the entries, in the order they were met
+	Errors []error
+}
+
+// Walk walks root and returns its tree. it fails only if root itself can't be read, the patterns are wrong or ctx is done,
+// the other errors, like a directory without read permission, are recorded in the tree
+func Walk(ctx context.Context, root string, opts Options) (*Tree, error) {
+	for _, p := range append(opts.Include, opts.Exclude...) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("dirtree: pattern %q: %w", p, err)
+		}
+	}
+	// the walk reads the real path of root, so that the paths it meets have no symbolic links in them,
+	// which is what finding the links back to an ancestor relies on. the entries show the path as given
+	real, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	w := &walker{ctx: ctx, opts: opts, following: []string{real}}
+	e, err := w.walk(real, root, 0)
+	if err != nil {
+		return nil, err
+	}
+	e.aggregate()
+	return &Tree{Root: e, Errors: w.errs}, nil
+}
+
+// walker holds the state of a walk, a followed link walks its target with a nested walk
+type walker struct {
+	ctx       context.Context
+	opts      Options
+	errs      []error
+	following []string // the real paths of the root, and of the links being followed and their directories
+}
+
+// walk walks the directory or file at the real path fsRoot, naming the entries after display, at depth below the root of the tree
+func (w *walker) walk(fsRoot, display string, depth int) (*Entry, error) {
+	var root *Entry
+	dirs := map[string]*Entry{} // the directories met so far by real path, to find the parents
+	err := filepath.WalkDir(fsRoot, func(path string, d fs.DirEntry, err error) error {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(fsRoot, path)
+		shown := filepath.Join(display, rel)
+		level := depth
+		if rel != "." {
+			level += strings.Count(rel, string(filepath.Separator)) + 1
+		}
+		if err != nil {
+			if path == fsRoot && d == nil {
+				return err // root itself is missing
+			}
+			// a directory that can't be read is reported twice: once before reading it, then with the error
+			e := dirs[path]
+			if e == nil {
+				e = &Entry{Name: d.Name(), Path: shown, Kind: kindOf(d.Type())}
+				w.attach(dirs, root, path, e)
+			}
+			w.fail(e, err)
+			return nil
+		}
+
+		if path != fsRoot && matchAny(w.opts.Exclude, d.Name(), rel) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 && path != fsRoot {
+			if w.opts.Symlinks == SymlinksSkip {
+				return nil
+			}
+			e := w.symlink(path, shown, rel, level)
+			if e != nil {
+				w.attach(dirs, root, path, e)
+			}
+			return nil
+		}
+		if !d.IsDir() && len(w.opts.Include) > 0 && !matchAny(w.opts.Include, d.Name(), rel) {
+			return nil
+		}
+
+		e := &Entry{Name: d.Name(), Path: shown, Kind: kindOf(d.Type())}
+		if path == fsRoot {
+			e.Name = filepath.Base(display)
+			root = e
+		} else {
+			w.attach(dirs, root, path, e)
+		}
+		if info, err := d.Info(); err != nil {
+			w.fail(e, err) // removed during the walk
+		} else {
+			e.Size, e.ModTime = info.Size(), info.ModTime()
+		}
+		if d.IsDir() {
+			dirs[path] = e
+			if w.opts.MaxDepth > 0 && level >= w.opts.MaxDepth {
+				return fs.SkipDir
+			}
+		}
+		return nil
+	})
+	return root, err
+}
+
+// symlink returns the entry of the link at path, nil if the link is left out.
+// with SymlinksFollow, the entry is the one of the target, walked in place of the link
+func (w *walker) symlink(path, shown, rel string, level int) *Entry {
+	e := &Entry{Name: filepath.Base(path), Path: shown, Kind: Symlink}
+	e.Target, _ = os.Readlink(path)
+	// list describes the link itself, without following it
+	list := func() *Entry {
+		if len(w.opts.Include) > 0 && !matchAny(w.opts.Include, e.Name, rel) {
+			return nil
+		}
+		if info, err := os.Lstat(path); err != nil {
+			w.fail(e, err)
+		} else {
+			e.Size, e.ModTime = info.Size(), info.ModTime()
+		}
+		return e
+	}
+	if w.opts.Symlinks != SymlinksFollow {
+		return list()
+	}
+
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		w.fail(e, err) // a broken link
+		return e
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		w.fail(e, err)
+		return e
+	}
+	if !info.IsDir() {
+		if len(w.opts.Include) > 0 && !matchAny(w.opts.Include, e.Name, rel) {
+			return nil
+		}
+		e.Kind, e.Size, e.ModTime = kindOf(info.Mode().Type()), info.Size(), info.ModTime()
+		return e
+	}
+	// a link to an ancestor would be walked forever, it is listed instead
+	if w.loops(filepath.Dir(path), real) {
+		return list()
+	}
+	w.following = append(w.following, filepath.Dir(path), real)
+	sub, err := w.walk(real, shown, level)
+	w.following = w.following[:len(w.following)-2]
+	if err != nil {
+		w.fail(e, err)
+		return e
+	}
+	sub.Name, sub.Target = e.Name, e.Target
+	return sub
+}
+
+// loops reports whether following a link in dir to target would walk an ancestor again.
+// the walked paths have no links in them, so target is an ancestor if dir, or a path recorded in following, is below it
+func (w *walker) loops(dir, target string) bool {
+	for _, p := range append(w.following, dir) {
+		if p == target || strings.HasPrefix(p, target+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// attach adds e to the children of the directory containing path
+func (w *walker) attach(dirs map[string]*Entry, root *Entry, path string, e *Entry) {
+	parent := dirs[filepath.Dir(path)]
+	if parent == nil {
+		parent = root
+	}
+	if parent != nil {
+		parent.Children = append(parent.Children, e)
+	}
+}
+
+// fail records err on e and in the errors of the walk
+func (w *walker) fail(e *Entry, err error) {
+	e.Err = err.Error()
+	w.errs = append(w.errs, err)
+}
+
+// aggregate sums the sizes and counts the entries below the directories of the tree
+func (e *Entry) aggregate() {
+	if e.Kind != Dir {
+		return
+	}
+	e.Size, e.Files, e.Dirs = 0, 0, 0
+	for _, c := range e.Children {
+		c.aggregate()
+		e.Size += c.Size
+		if c.Kind == Dir {
+			e.Dirs += c.Dirs + 1
+			e.Files += c.Files
+		} else {
+			e.Files++
+		}
+	}
+}
+
+// All returns the entries of the tree rooted at e, e first, parents before their children
+func (e *Entry) All() []*Entry {
+	out := []*Entry{e}
+	for _, c := range e.Children {
+		out = append(out, c.All()...)
+	}
+	return out
+}
+
+func kindOf(t fs.FileMode) Kind {
+	switch {
+	case t.IsDir():
+		return Dir
+	case t.IsRegular():
+		return File
+	case t&fs.ModeSymlink != 0:
+		return Symlink
+	}
+	return Other
+}
+
+// matchAny reports whether one of the globs matches name, or rel for the globs with a slash
+func matchAny(globs []string, name, rel string) bool {
+	for _, g := range globs {
+		target := name
+		if strings.Contains(g, "/") {
+			target = filepath.ToSlash(rel)
+		}
+		if ok, _ := filepath.Match(g, target); ok {
+			return true
+		}
+	}
+	return false
+}
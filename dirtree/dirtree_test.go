@@ -0,0 +1,270 @@
+package dirtree
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mkTree creates the files of files under a new temporary directory, a name ending with a slash is a directory,
+// the content of a name with " -> " is the target of a symbolic link
+func mkTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, f := range files {
+		name, content, _ := strings.Cut(f, "=")
+		path := filepath.Join(root, name)
+		var err error
+		switch {
+		case strings.HasSuffix(name, "/"):
+			err = os.MkdirAll(path, 0755)
+		case strings.Contains(name, " -> "):
+			link, target, _ := strings.Cut(name, " -> ")
+			err = os.Symlink(target, filepath.Join(root, link))
+		default:
+			err = os.WriteFile(path, []byte(content), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// text walks root and returns the text output without the first line, which is the name of the temporary directory
+func text(t *testing.T, root string, opts Options, cols Columns) string {
+	t.Helper()
+	tree, err := Walk(context.Background(), root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, tree.Root, cols); err != nil {
+		t.Fatal(err)
+	}
+	_, rest, _ := strings.Cut(buf.String(), "\n")
+	return rest
+}
+
+var sample = []string{"a/", "a/b/", "a/b/deep.txt=12345", "a/x.go=hello", "c/", "c/y.go=hi", "z.md=#"}
+
+func TestWriteText(t *testing.T) {
+	root := mkTree(t, sample...)
+	// the directories are always walked, the include globs only filter files
+	got := text(t, root, Options{Include: []string{"*.txt", "c/*", "z.*"}}, Columns{Size: true})
+	want := `├── [5]  a
+│   └── [5]  b
+│       └── [5]  deep.txt
+├── [2]  c
+│   └── [2]  y.go
+└── [1]  z.md
+
+3 directories, 3 files
+`
+	if got != want {
+		t.Errorf("include:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = text(t, root, Options{Exclude: []string{"b", "*.md"}}, Columns{})
+	want = `├── a
+│   └── x.go
+└── c
+    └── y.go
+
+2 directories, 2 files
+`
+	if got != want {
+		t.Errorf("exclude:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = text(t, root, Options{MaxDepth: 1}, Columns{Size: true})
+	want = `├── [0]  a
+├── [0]  c
+└── [1]  z.md
+
+2 directories, 1 files
+`
+	if got != want {
+		t.Errorf("depth 1:\n%s\nwant:\n%s", got, want)
+	}
+
+	mtime := time.Date(2024, 5, 6, 7, 8, 0, 0, time.Local)
+	os.Chtimes(filepath.Join(root, "z.md"), mtime, mtime)
+	if got := text(t, root, Options{Include: []string{"z.md"}, MaxDepth: 1}, Columns{ModTime: true}); !strings.Contains(got, "└── [2024-05-06 07:08]  z.md\n") {
+		t.Errorf("mtime column missing:\n%s", got)
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	root := mkTree(t, sample...)
+	tree, err := Walk(context.Background(), root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := tree.Root
+	if r.Size != 13 || r.Files != 4 || r.Dirs != 3 {
+		t.Errorf("root size, files, dirs = %d, %d, %d; want 13, 4, 3", r.Size, r.Files, r.Dirs)
+	}
+	a := r.Children[0]
+	if a.Name != "a" || a.Kind != Dir || a.Size != 10 || a.Files != 2 || a.Dirs != 1 {
+		t.Errorf("a = %+v", a)
+	}
+	if len(tree.Errors) != 0 {
+		t.Errorf("errors = %v", tree.Errors)
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	root := mkTree(t, "a/", "a/f=abc", "a/up -> ..", "b/", "b/link -> ../a", "broken -> nope")
+
+	list := text(t, root, Options{}, Columns{})
+	for _, want := range []string{"│   └── up -> ..\n", "│   └── link -> ../a\n", "└── broken -> nope\n"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("list doesn't contain %q:\n%s", want, list)
+		}
+	}
+
+	if skip := text(t, root, Options{Symlinks: SymlinksSkip}, Columns{}); strings.Contains(skip, "->") {
+		t.Errorf("skip kept links:\n%s", skip)
+	}
+
+	// b/link is walked as the directory a, the links back to an ancestor are listed, not walked
+	tree, err := Walk(context.Background(), root, Options{Symlinks: SymlinksFollow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var follow bytes.Buffer
+	WriteText(&follow, tree.Root, Columns{})
+	_, got, _ := strings.Cut(follow.String(), "\n")
+	want := `├── a
+│   ├── f
+│   └── up -> ..
+├── b
+│   └── link -> ../a
+│       ├── f
+│       └── up -> ..
+└── broken -> nope  [error: lstat ` + filepath.Join(root, "nope") + `: no such file or directory]
+
+3 directories, 5 files
+`
+	if got != want {
+		t.Errorf("follow:\n%s\nwant:\n%s", got, want)
+	}
+	if len(tree.Errors) != 1 || !errors.Is(tree.Errors[0], fs.ErrNotExist) {
+		t.Errorf("errors = %v; want the broken link", tree.Errors)
+	}
+}
+
+func TestSymlinkCycle(t *testing.T) {
+	// x/l1 and y/l2 point at each other's directories
+	root := mkTree(t, "x/", "y/", "x/l1 -> ../y", "y/l2 -> ../x")
+	done := make(chan *Tree)
+	go func() {
+		tree, _ := Walk(context.Background(), root, Options{Symlinks: SymlinksFollow})
+		done <- tree
+	}()
+	select {
+	case tree := <-done:
+		if tree.Root.Dirs != 4 { // x, x/l1, y, y/l2
+			t.Errorf("dirs = %d; want 4", tree.Root.Dirs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the walk didn't stop")
+	}
+}
+
+func TestPermissionError(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root reads directories without permission")
+	}
+	root := mkTree(t, "locked/", "locked/secret", "open/", "open/f=1")
+	locked := filepath.Join(root, "locked")
+	os.Chmod(locked, 0)
+	defer os.Chmod(locked, 0755)
+
+	tree, err := Walk(context.Background(), root, Options{})
+	if err != nil {
+		t.Fatalf("the walk stopped: %v", err)
+	}
+	if len(tree.Errors) != 1 || !errors.Is(tree.Errors[0], fs.ErrPermission) {
+		t.Errorf("errors = %v; want a permission error", tree.Errors)
+	}
+	if e := tree.Root.Children[0]; e.Name != "locked" || e.Err == "" || len(e.Children) != 0 {
+		t.Errorf("locked = %+v", e)
+	}
+	if e := tree.Root.Children[1]; e.Name != "open" || e.Files != 1 {
+		t.Errorf("the walk didn't go on after the error, open = %+v", e)
+	}
+}
+
+func TestWalkErrors(t *testing.T) {
+	if _, err := Walk(context.Background(), filepath.Join(t.TempDir(), "missing"), Options{}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing root: err = %v", err)
+	}
+	if _, err := Walk(context.Background(), t.TempDir(), Options{Include: []string{"["}}); !errors.Is(err, filepath.ErrBadPattern) {
+		t.Errorf("bad pattern: err = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Walk(ctx, t.TempDir(), Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled: err = %v", err)
+	}
+}
+
+func TestWriteJSONAndCSV(t *testing.T) {
+	root := mkTree(t, "a/", "a/x.go=hello")
+	tree, err := Walk(context.Background(), root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, tree.Root); err != nil {
+		t.Fatal(err)
+	}
+	var got Entry
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	x := got.Children[0].Children[0]
+	if got.Size != 5 || got.Kind != Dir || x.Name != "x.go" || x.Path != filepath.Join(root, "a", "x.go") || x.Kind != File || x.ModTime.IsZero() {
+		t.Errorf("json = %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteCSV(&buf, tree.Root); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0][0] != "path" || rows[3][0] != filepath.Join(root, "a", "x.go") || rows[3][1] != "file" || rows[3][2] != "5" {
+		t.Errorf("csv = %q", rows)
+	}
+}
+
+func TestRunArgs(t *testing.T) {
+	root := mkTree(t, sample...)
+	var buf bytes.Buffer
+	if err := RunArgs(context.Background(), &buf, []string{"-format=csv", "-exclude=a", root}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 5 { // header, root, c, c/y.go, z.md
+		t.Errorf("csv has %d lines:\n%s", got, buf.String())
+	}
+
+	for _, args := range [][]string{{"-format=xml"}, {"-symlinks=maybe"}, {"a", "b"}} {
+		if err := RunArgs(context.Background(), &buf, args); err == nil {
+			t.Errorf("%q accepted", args)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package dirtree
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Columns are the optional columns of the text output
+type Columns struct {
+	Size    bool // the size in bytes, the total below them for directories
+	ModTime bool
+}
+
+// timeLayout is the layout of the modification times in the text output, the json and csv use RFC 3339
+const timeLayout = "2006-01-02 15:04"
+
+// WriteText writes the tree rooted at root like the tree command, with the columns in brackets before the names,
+// and ends with the number of directories and files
+func WriteText(w io.Writer, root *Entry, cols Columns) error {
+	bw := bufio.NewWriter(w)
+	width := 0
+	if cols.Size {
+		for _, e := range root.All() {
+			width = max(width, len(strconv.FormatInt(e.Size, 10)))
+		}
+	}
+	var line func(e *Entry, prefix, branch, next string)
+	line = func(e *Entry, prefix, branch, next string) {
+		bw.WriteString(prefix + branch)
+		if cols.Size || cols.ModTime {
+			bw.WriteString("[")
+			if cols.Size {
+				fmt.Fprintf(bw, "%*d", width, e.Size)
+			}
+			if cols.Size && cols.ModTime {
+				bw.WriteString("  ")
+			}
+			if cols.ModTime {
+				bw.WriteString(e.ModTime.Format(timeLayout))
+			}
+			bw.WriteString("]  ")
+		}
+		bw.WriteString(e.Name)
+		if e.Target != "" {
+			bw.WriteString(" -> " + e.Target)
+		}
+		if e.Err != "" {
+			bw.WriteString("  [error: " + e.Err + "]")
+		}
+		bw.WriteString("\n")
+		for i, c := range e.Children {
+			if i == len(e.Children)-1 {
+				line(c, prefix+next, "└── ", "    ")
+			} else {
+				line(c, prefix+next, "├── ", "│   ")
+			}
+		}
+	}
+	line(root, "", "", "")
+	fmt.Fprintf(bw, "\n%d directories, %d files\n", root.Dirs, root.Files)
+	return bw.Flush()
+}
+
+// WriteJSON writes the tree rooted at root as a single indented json object, the children nested in their directories
+func WriteJSON(w io.Writer, root *Entry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(root)
+}
+
+// WriteCSV writes the entries of the tree rooted at root as csv, one row per entry with a header first
+func WriteCSV(w io.Writer, root *Entry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"path", "kind", "size", "mtime", "files", "dirs", "target", "error"})
+	for _, e := range root.All() {
+		mtime := ""
+		if !e.ModTime.IsZero() {
+			mtime = e.ModTime.Format(time.RFC3339)
+		}
+		cw.Write([]string{
+			e.Path, string(e.Kind), strconv.FormatInt(e.Size, 10), mtime,
+			strconv.Itoa(e.Files), strconv.Itoa(e.Dirs), e.Target, e.Err,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
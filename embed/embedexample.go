@@ -1,9 +1,14 @@
-package main
+// Package embedexample embeds files and folders into the binary and serves them over http
+package embedexample
 
 import (
+	"RobotTask/httpserver"
+	"RobotTask/logging"
+	"RobotTask/staticfs"
+	"context"
 	"embed"
 	"fmt"
-	"net/http"
+	"io"
 )
 
 // embed is a compiler directive that allows programs to include arbitrary files and folders in the go binary at build time
@@ -36,26 +41,41 @@ var folder embed.FS
 //go:embed folder
 var folder2 embed.FS
 
-func main() {
+// Run prints the embedded files, then serves them on :8080 until ctx is done or the process receives SIGINT or SIGTERM
+func Run(ctx context.Context, w io.Writer) error {
 
 	// print out the contents of single_file.txt
-	println("fileString embeds single_file.txt(hello go): ", fileString)
+	fmt.Fprintln(w, "fileString embeds single_file.txt(hello go): ", fileString)
 	// byte array format requires string conversion to print
-	println("fileByte is []byte format: ", string(fileByte))
+	fmt.Fprintln(w, "fileByte is []byte format: ", string(fileByte))
 
 	// folder embeds multiple files
 	files, _ := folder.ReadDir("folder") // use ReadDir to get all files in the folder
 	for _, file := range files {         // range over all the files, file name only returns the name
-		data, _ := folder.ReadFile("folder/" + file.Name())     // use ReadFile to read a single file
-		fmt.Printf("File: %s Content: %s\n", file.Name(), data) // output the data in the file
+		data, _ := folder.ReadFile("folder/" + file.Name())         // use ReadFile to read a single file
+		fmt.Fprintf(w, "File: %s Content: %s\n", file.Name(), data) // output the data in the file
 	}
 
 	// folder 2 embeds the folder directly, the reading of the embedded file is the same
 	data, _ := folder2.ReadFile(("folder/single_file.txt"))
-	fmt.Println("got data from single_file.txt: ", string(data))
+	fmt.Fprintln(w, "got data from single_file.txt: ", string(data))
+
+	// we could use http.FileServer to serve the embedded files, staticfs adds etags, cache headers,
+	// pre-compressed variants and control over directory listings on top of it
+	opts := staticfs.DefaultOptions()
+	opts.CacheControl[".hash"] = "no-cache" // hashes are small and should always be fresh
+	opts.DisableListing = true              // http://localhost:8080/folder/ is a 404 instead of a file list
+
+	cfg := httpserver.DefaultConfig()
+	cfg.Addr = ":8080"
+	srv, err := httpserver.Listen(cfg, staticfs.New(folder2, opts)) // access the file using http://localhost:8080/folder/single_file.txt
+	if err != nil {
+		return fmt.Errorf("server failed to start: %w", err)
+	}
+	logging.FromContext(ctx).Info("serving the embedded folder", "addr", srv.Addr().String())
 
-	// we can use http.FileServer to serve the embedded files
-	// TODO: move this to http example
-	http.Handle("/", http.FileServer(http.FS(folder2))) // access the file using http://localhost:8080/folder/single_file.txt
-	http.ListenAndServe(":8080", nil)
+	// ctrl+c stops the server
+	ctx, stop := httpserver.NotifyShutdown(ctx)
+	defer stop()
+	return srv.Serve(ctx)
 }
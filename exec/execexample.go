@@ -1,32 +1,39 @@
-package main
+// Package execexample replaces the running process with ls through syscall.Exec
+package execexample
 
 import (
+	"RobotTask/executil"
+	"context"
+	"fmt"
+	"io"
 	"os"
-	"os/exec"
-	"syscall"
 )
 
-func main() {
+// Run never returns if the exec succeeds, ls takes over the process and prints to its stdout, not to w
+func Run(ctx context.Context, w io.Writer) error {
 
 	// exec completely replace the current go process with another one.
 
-	// go requires an absolute path to the binary we want to execute,
-	// so we'll use exec.Lookpath to find it
-	binary, lookErr := exec.LookPath("ls")
-	if lookErr != nil {
-		panic(lookErr)
+	// syscall.Exec requires an absolute path to the binary we want to execute, the arguments in slice form
+	// with the program name first, and a set of environment variables. executil.ExecReplace builds them:
+	// ls is looked up in the PATH of the new environment, and the environment is ours or built from nothing
+	opts := executil.Options{
+		ClearEnv: true,
+		Env:      []string{"PATH=" + os.Getenv("PATH"), "LANG=C"},
+		// the descriptors we inherited are closed for ls, it only gets stdin, stdout and stderr
+		CloseFDs: true,
+		// a dry run prints the call instead of making it
+		DryRun: w,
 	}
-	// exec requires arguments in slice form, note that the first argument should be the program name
-	args := []string{"ls", "-a", "-l", "-h"}
-
-	//exec also needs a set of environment variables, here we just provide our current environment
-	env := os.Environ()
+	if err := executil.ExecReplace(opts, "ls", "-a", "-l", "-h"); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
 
 	// here's the actual syscall.Exec. if this call is successful, the execution of
 	// our process will end here and be replaced by the /bin/ls -a -l -h process
-	execErr := syscall.Exec(binary, args, env)
+	opts.DryRun = nil
+	execErr := executil.ExecReplace(opts, "ls", "-a", "-l", "-h")
 	// if there is an error we'll get a return value
-	if execErr != nil {
-		panic(execErr)
-	}
+	return execErr
 }
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package executil
+
+import "errors"
+
+// execve can't replace the process outside unix, a dry run still works
+func execve(path string, argv, env []string) error {
+	return errors.ErrUnsupported
+}
+
+func closeOnExec() {}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package executil
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+func execve(path string, argv, env []string) error {
+	return syscall.Exec(path, argv, env)
+}
+
+// closeOnExec marks every descriptor above stderr close-on-exec. the files go has opened are already, the inherited ones
+// aren't. they stay open in the process, which keeps working if the exec fails
+func closeOnExec() {
+	entries, err := os.ReadDir("/dev/fd")
+	if err == nil {
+		for _, e := range entries {
+			if fd, err := strconv.Atoi(e.Name()); err == nil && fd > 2 {
+				syscall.CloseOnExec(fd)
+			}
+		}
+		return
+	}
+	// no /dev/fd, every descriptor the process can have is tried
+	var lim syscall.Rlimit
+	if syscall.Getrlimit(syscall.RLIMIT_NOFILE, &lim) != nil {
+		return
+	}
+	n := min(uint64(lim.Cur), 1<<20) // unlimited would take forever
+	for fd := 3; uint64(fd) < n; fd++ {
+		syscall.CloseOnExec(fd)
+	}
+}
@@ -0,0 +1,192 @@
+// Package executil replaces the running process with another program, where the exec example calls syscall.Exec by hand:
+//
+//	err := executil.ExecReplace(executil.Options{
+//		ClearEnv: true,
+//		Env:      []string{"PATH=/usr/bin:/bin", "LANG=C"},
+//		Argv0:    "ls",
+//		Dir:      "/tmp",
+//		CloseFDs: true,
+//	}, "ls", "-a", "-l", "-h")
+//
+// everything is checked before anything changes: the program is looked up and must be an executable file,
+// the directory must exist and the variables must be well formed. with DryRun set the execve call is printed instead of made
+package executil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Options are the environment, the arguments and the state the new program starts with,
+// the zero value inherits the environment, the working directory and the open files like a bare syscall.Exec
+type Options struct {
+	// ClearEnv starts from an empty environment instead of the one of the caller
+	ClearEnv bool
+	// Unset removes variables of the inherited environment by name
+	Unset []string
+	// Env are variables as "KEY=value", they override the inherited ones with the same name and the others are added
+	Env []string
+	// Argv0 is the first argument the program sees, the name given to ExecReplace if empty
+	Argv0 string
+	// Dir is the working directory of the program, the process changes to it right before the exec
+	Dir string
+	// CloseFDs closes the file descriptors above stderr in the program, the ones the caller inherited included
+	CloseFDs bool
+	// DryRun gets the chdir and the execve calls instead of the process making them, ExecReplace returns nil then
+	DryRun io.Writer
+}
+
+// ExecReplace replaces the process with the program name, run with args. a name without a slash is looked up in the PATH
+// of the new environment, a relative one is taken from Dir. it only returns on an error, or after a dry run
+func ExecReplace(opts Options, name string, args ...string) error {
+	if name == "" {
+		return errors.New("executil: no program")
+	}
+	for _, kv := range opts.Env {
+		if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+			return fmt.Errorf("executil: variable %q is not KEY=value", kv)
+		}
+	}
+	base := os.Environ()
+	if opts.ClearEnv {
+		base = nil
+	}
+	env := Environ(base, opts.Unset, opts.Env)
+	for _, kv := range env {
+		if strings.IndexByte(kv, 0) >= 0 {
+			return fmt.Errorf("executil: variable %q has a NUL byte", kv)
+		}
+	}
+	if opts.Dir != "" {
+		info, err := os.Stat(opts.Dir)
+		if err != nil {
+			return fmt.Errorf("executil: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("executil: %s is not a directory", opts.Dir)
+		}
+	}
+	path, err := lookPath(name, opts.Dir, getenv(env, "PATH"))
+	if err != nil {
+		return fmt.Errorf("executil: %w", err)
+	}
+	argv0 := opts.Argv0
+	if argv0 == "" {
+		argv0 = name
+	}
+	argv := append([]string{argv0}, args...)
+
+	if opts.DryRun != nil {
+		if opts.Dir != "" {
+			fmt.Fprintf(opts.DryRun, "chdir(%q)\n", opts.Dir)
+		}
+		fmt.Fprintf(opts.DryRun, "execve(%q, %s, %s)\n", path, quoteList(argv), quoteList(env))
+		return nil
+	}
+
+	if opts.CloseFDs {
+		closeOnExec()
+	}
+	if opts.Dir != "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("executil: %w", err)
+		}
+		if err := os.Chdir(opts.Dir); err != nil {
+			return fmt.Errorf("executil: %w", err)
+		}
+		// the exec failed if it returns, the caller keeps running in its own directory
+		defer os.Chdir(wd)
+	}
+	if err := execve(path, argv, env); err != nil {
+		return fmt.Errorf("executil: exec %s: %w", path, err)
+	}
+	return nil
+}
+
+// Environ returns base without the variables named in unset, then env: a variable of env replaces the one of base with
+// the same name in place, the others are appended. a name set twice keeps its last value
+func Environ(base, unset, env []string) []string {
+	out := []string{}
+	index := map[string]int{}
+	add := func(kv string) {
+		k, _, _ := strings.Cut(kv, "=")
+		if i, ok := index[k]; ok {
+			out[i] = kv
+			return
+		}
+		index[k] = len(out)
+		out = append(out, kv)
+	}
+	for _, kv := range base {
+		k, _, _ := strings.Cut(kv, "=")
+		if !slices.Contains(unset, k) {
+			add(kv)
+		}
+	}
+	for _, kv := range env {
+		add(kv)
+	}
+	return out
+}
+
+// getenv returns the value of key in env, empty if it isn't set
+func getenv(env []string, key string) string {
+	for _, kv := range env {
+		if k, v, _ := strings.Cut(kv, "="); k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// lookPath returns the absolute path of the program name: as is with a slash, relative to dir, or found in the
+// absolute directories of path. it must be an executable file
+func lookPath(name, dir, path string) (string, error) {
+	if strings.Contains(name, "/") {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return "", err
+		}
+		return abs, executable(abs)
+	}
+	for _, d := range filepath.SplitList(path) {
+		// a relative entry would depend on the directory, like "." in PATH, it is skipped
+		if !filepath.IsAbs(d) {
+			continue
+		}
+		if p := filepath.Join(d, name); executable(p) == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in PATH %q", name, path)
+}
+
+// executable returns an error unless path is a regular file with an execute bit
+func executable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("%s is not an executable file", path)
+	}
+	return nil
+}
+
+// quoteList formats list like strace does, ["a", "b"]
+func quoteList(list []string) string {
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
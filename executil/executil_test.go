@@ -0,0 +1,147 @@
+package executil
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEnviron(t *testing.T) {
+	got := Environ(
+		[]string{"PATH=/bin", "HOME=/root", "SECRET=x", "LANG=fr", "HOME=/home"},
+		[]string{"SECRET"},
+		[]string{"LANG=de", "EXTRA=1", "EXTRA=2"},
+	)
+	if want := []string{"PATH=/bin", "HOME=/home", "LANG=de", "EXTRA=2"}; !slices.Equal(got, want) {
+		t.Errorf("Environ = %q; want %q", got, want)
+	}
+	if got := Environ(nil, nil, nil); got == nil || len(got) != 0 {
+		t.Errorf("Environ of nothing = %#v; want empty, not nil", got)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	bin := t.TempDir()
+	prog := filepath.Join(bin, "prog")
+	if err := os.WriteFile(prog, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	opts := Options{ClearEnv: true, Env: []string{"PATH=relative:" + bin, "A=b c"}, Argv0: "-prog", Dir: dir, DryRun: &buf}
+	if err := ExecReplace(opts, "prog", "x", `"y"`); err != nil {
+		t.Fatal(err)
+	}
+	want := `chdir("` + dir + `")` + "\n" +
+		`execve("` + prog + `", ["-prog", "x", "\"y\""], ["PATH=relative:` + bin + `", "A=b c"])` + "\n"
+	if buf.String() != want {
+		t.Errorf("dry run printed\n%s\nwant\n%s", buf.String(), want)
+	}
+	if wd, _ := os.Getwd(); wd == dir {
+		t.Error("the dry run changed the directory")
+	}
+
+	// a relative path is taken from Dir, the inherited environment is kept
+	if err := os.WriteFile(filepath.Join(dir, "local"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EXECUTIL_TEST_UNSET", "1")
+	buf.Reset()
+	opts = Options{Dir: dir, Unset: []string{"EXECUTIL_TEST_UNSET"}, DryRun: &buf}
+	if err := ExecReplace(opts, "./local"); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, `execve("`+filepath.Join(dir, "local")+`", ["./local"], [`) ||
+		strings.Contains(out, "EXECUTIL_TEST_UNSET") || !strings.Contains(out, `"PATH=`) {
+		t.Errorf("dry run printed %s", out)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain")
+	if err := os.WriteFile(plain, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	for _, c := range []struct {
+		name string
+		opts Options
+		prog string
+		want string
+	}{
+		{"no program", Options{}, "", "no program"},
+		{"not found", Options{ClearEnv: true, Env: []string{"PATH=" + dir}}, "plain", "not found in PATH"},
+		{"not executable", Options{}, plain, "not an executable file"},
+		{"a directory", Options{}, dir, "not an executable file"},
+		{"missing file", Options{}, "/nonexistent/prog", "no such file"},
+		{"dir is a file", Options{Dir: plain}, "/bin/sh", "not a directory"},
+		{"missing dir", Options{Dir: "/nonexistent"}, "/bin/sh", "no such file"},
+		{"bad variable", Options{Env: []string{"NOVALUE"}}, "/bin/sh", "not KEY=value"},
+		{"empty name", Options{Env: []string{"=x"}}, "/bin/sh", "not KEY=value"},
+		{"nul byte", Options{Env: []string{"A=b\x00c"}}, "/bin/sh", "NUL byte"},
+	} {
+		c.opts.DryRun = &buf
+		err := ExecReplace(c.opts, c.prog)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v; want %q", c.name, err, c.want)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("a failed check printed %q", buf.String())
+	}
+}
+
+// TestHelperProcess is the process TestExecReplace replaces with sh, it doesn't run on its own
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("EXECUTIL_TEST_HELPER") != "1" {
+		t.Skip("run by TestExecReplace")
+	}
+	opts := Options{
+		Unset:    []string{"EXECUTIL_TEST_HELPER"},
+		Env:      []string{"ADDED=yes"},
+		Argv0:    "renamed",
+		Dir:      os.Getenv("EXECUTIL_TEST_DIR"),
+		CloseFDs: os.Getenv("EXECUTIL_TEST_CLOSE") == "1",
+	}
+	// fd 3 is a file the test passed on, sh writes to it if it is still open
+	script := `echo "$0|$ADDED|$EXECUTIL_TEST_HELPER"; pwd; { echo x >&3; } 2>/dev/null && echo open || echo closed`
+	err := ExecReplace(opts, "sh", "-c", script)
+	t.Fatalf("ExecReplace returned: %v", err)
+}
+
+func TestExecReplace(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	dir := t.TempDir()
+	for _, closeFDs := range []bool{false, true} {
+		extra, err := os.CreateTemp(t.TempDir(), "fd3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+		cmd.Env = append(os.Environ(), "EXECUTIL_TEST_HELPER=1", "EXECUTIL_TEST_DIR="+dir)
+		if closeFDs {
+			cmd.Env = append(cmd.Env, "EXECUTIL_TEST_CLOSE=1")
+		}
+		cmd.ExtraFiles = []*os.File{extra}
+		out, err := cmd.CombinedOutput()
+		extra.Close()
+		if err != nil {
+			t.Fatalf("helper: %v\n%s", err, out)
+		}
+		resolved, _ := filepath.EvalSymlinks(dir)
+		state := "open"
+		if closeFDs {
+			state = "closed"
+		}
+		if want := "renamed|yes|\n" + resolved + "\n" + state + "\n"; string(out) != want {
+			t.Errorf("close %v: output %q; want %q", closeFDs, out, want)
+		}
+	}
+}
@@ -1,16 +1,21 @@
-package main
+// Package exitexample exits with a non-zero status
+package exitexample
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 )
 
-func main() {
+// Run never returns, it ends the whole process with status 3
+func Run(ctx context.Context, w io.Writer) error {
 	// normally defers run at the end of the program, but if you us os.Exit, then
 	// the defers will never be called.
-	defer fmt.Println("!")
+	defer fmt.Fprintln(w, "!")
 
 	// use os.Exit to exit the program with a non-zero status
 	// the exit will be picked up by go and printed
 	os.Exit(3)
+	return nil // never reached, but the compiler doesn't know os.Exit doesn't return
 }
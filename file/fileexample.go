@@ -1,7 +1,14 @@
-package main
+// Package fileexample works with file paths, directories, temporary files, reading and writing files,
+// and ends with a line filter
+package fileexample
 
 import (
+	"RobotTask/dirtree"
+	"RobotTask/fileutil"
+	"RobotTask/linefilter"
 	"bufio"
+	"context"
+	"flag"
 	"fmt"
 	"io"
 	"io/fs"
@@ -17,52 +24,67 @@ func check(e error) {
 	}
 }
 
-func visit(path string, d fs.DirEntry, err error) error {
-	if err != nil {
-		return err
+// visit returns the function called by filepath.WalkDir for every file, it prints the path to w
+func visit(w io.Writer) fs.WalkDirFunc {
+	return func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, " ", path, d.IsDir())
+		return nil
 	}
-	fmt.Println(" ", path, d.IsDir())
-	return nil
 }
 
-// run this program using "echo abcbcd| go run file/fileexample.go"
-func main() {
+// Run runs the example without the line filter, which needs -stdin
+func Run(ctx context.Context, w io.Writer) error {
+	return RunArgs(ctx, w, nil)
+}
+
+// RunArgs runs the example, the -stdin flag turns on the line filter at the end.
+// run it using "echo abcbcd| oneforall file -stdin"
+func RunArgs(ctx context.Context, w io.Writer, args []string) error {
+	fset := flag.NewFlagSet("file", flag.ContinueOnError)
+	stdin := fset.Bool("stdin", false, "uppercase the lines read from stdin")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
 	/******************************* file paths **************************************/
 
 	// use filepath.join to construct paths
 	path := filepath.Join("dir1", "dir2", "filename") // construct a path in hierarchial order
-	fmt.Println("join dir1, dir2 and filename: ", path)
+	fmt.Fprintln(w, "join dir1, dir2 and filename: ", path)
 	// you should always use Join instead of concatenating /s or \s manually
 	// the following two joins both return "dir1/filename"
-	fmt.Println(filepath.Join("dir1//", "filename"))
-	fmt.Println(filepath.Join("dir1/../dir1", "filename"))
+	fmt.Fprintln(w, filepath.Join("dir1//", "filename"))
+	fmt.Fprintln(w, filepath.Join("dir1/../dir1", "filename"))
 	// dir and base ca nbe used to split a path to the directory and the file.alternatively. split will return both in the same call
-	fmt.Println("Dir(p):", filepath.Dir(path))   // dir gets the directory
-	fmt.Println("Base(p):", filepath.Base(path)) // base gets the final file
+	fmt.Fprintln(w, "Dir(p):", filepath.Dir(path))   // dir gets the directory
+	fmt.Fprintln(w, "Base(p):", filepath.Base(path)) // base gets the final file
 	// check if a path is absolute, both returns false
-	fmt.Println("dir/file is absolute: ", filepath.IsAbs("dir/file"))       // just false
-	fmt.Println("/dir/file is absolute: ", filepath.IsAbs("/dir/file"))     // true in linux
-	fmt.Println("D:/dir/file is absolute: ", filepath.IsAbs("D:/dir/file")) // true in windows
+	fmt.Fprintln(w, "dir/file is absolute: ", filepath.IsAbs("dir/file"))       // just false
+	fmt.Fprintln(w, "/dir/file is absolute: ", filepath.IsAbs("/dir/file"))     // true in linux
+	fmt.Fprintln(w, "D:/dir/file is absolute: ", filepath.IsAbs("D:/dir/file")) // true in windows
 
 	// extensions
 	filename := "config.json"
 	ext := filepath.Ext(filename) // get the extension of a path
-	fmt.Println("extension of config.json is: ", ext)
+	fmt.Fprintln(w, "extension of config.json is: ", ext)
 	// to find the file's name with the extension removed, use strings.TrimSuffix
-	fmt.Println("file name with extension removed: ", strings.TrimSuffix(filename, ext))
+	fmt.Fprintln(w, "file name with extension removed: ", strings.TrimSuffix(filename, ext))
 
 	// rel finds a relative path between a base and a target, it returns an error if the target cannot be made relative to the base
 	rel, err := filepath.Rel("a/b", "a/b/t/file")
 	if err != nil {
 		panic(err)
 	}
-	fmt.Println("relative path between a/b and a/b/t/file: ", rel)
+	fmt.Fprintln(w, "relative path between a/b and a/b/t/file: ", rel)
 
 	rel, err = filepath.Rel("a/b", "a/c/t/file")
 	if err != nil {
 		panic(err)
 	}
-	fmt.Println("relative path between a/b and a/c/t/file: ", rel)
+	fmt.Fprintln(w, "relative path between a/b and a/c/t/file: ", rel)
 
 	/******************************* directories **************************************/
 
@@ -91,9 +113,9 @@ func main() {
 	cmkdir, err := os.ReadDir("subdir/parent")
 	check(err)
 
-	fmt.Println("Listing subdir/parent")
+	fmt.Fprintln(w, "Listing subdir/parent")
 	for _, entry := range cmkdir {
-		fmt.Println(" ", entry.Name(), entry.IsDir())
+		fmt.Fprintln(w, " ", entry.Name(), entry.IsDir())
 	}
 
 	// chdir lets us change the current working directory, similar to cd
@@ -104,23 +126,30 @@ func main() {
 	c, err := os.ReadDir(".")
 	check(err)
 
-	fmt.Println("Listing subdir/parent/child")
+	fmt.Fprintln(w, "Listing subdir/parent/child")
 	for _, entry := range c {
-		fmt.Println(" ", entry.Name(), entry.IsDir())
+		fmt.Fprintln(w, " ", entry.Name(), entry.IsDir())
 	}
 
 	err = os.Chdir("../../..") // return to original location ,allowing the successful deletion of subdir
 	check(err)
 
 	//We can also visit a directory recursively, including all its sub-directories. WalkDir accepts a callback function to handle every file or directory visited.
-	fmt.Println("Visiting subdir")
-	err = filepath.WalkDir("subdir", visit)
+	fmt.Fprintln(w, "Visiting subdir")
+	err = filepath.WalkDir("subdir", visit(w))
+	check(err)
+
+	// the dirtree package builds on WalkDir: it keeps what it visits as a tree, with the size of every file and the total below every directory.
+	// oneforall tree adds globs, a maximum depth, modification times, json and csv
+	tree, err := dirtree.Walk(ctx, "subdir", dirtree.Options{})
+	check(err)
+	check(dirtree.WriteText(w, tree.Root, dirtree.Columns{Size: true}))
 
 	/********************************** temporary files ********************************/
 	// creating temporary files
 	f, err := os.CreateTemp("", "data.txt")
 	check(err)
-	fmt.Println("Temp file name:", f.Name())
+	fmt.Fprintln(w, "Temp file name:", f.Name())
 	defer os.Remove(f.Name()) // always nice to remove the temporary file using defer
 
 	_, err = f.Write([]byte{1, 2, 3, 4}) // write to file
@@ -128,7 +157,7 @@ func main() {
 
 	dname, err := os.MkdirTemp("", "sampledir") // create temporary directory
 	check(err)
-	fmt.Println("Temp dir name:", dname)
+	fmt.Fprintln(w, "Temp dir name:", dname)
 
 	defer os.RemoveAll(dname) // remove the temporary directory
 
@@ -138,44 +167,47 @@ func main() {
 
 	/************************* file write **********************************************/
 	filePath := "data.txt"
-	// write s string to file directly
-	d1 := []byte("hello world\ngo\n")        // notice that d1 must be a byte array and not a string
-	errw := os.WriteFile(filePath, d1, 0644) // 0644 if the permission of the file
+	// write s string to file directly. os.WriteFile truncates the file before writing it, a crash in between leaves it empty,
+	// AtomicWriteFile writes a temporary file next to it instead and renames it over data.txt, which holds either the old or the new content
+	d1 := []byte("hello world\ngo\n")                    // notice that d1 must be a byte array and not a string
+	errw := fileutil.AtomicWriteFile(filePath, d1, 0644) // 0644 if the permission of the file
 	check(errw)
 
 	// for more granular writes, open a file for writing
 	filewrite, errw := os.Create("tempfile") // this creates a temp file
 	check(errw)
+	// deferred calls run last in first out, so the remove is registered first to run after the close.
 	// it's idiomatic to defer a close immediately after opening a file
+	defer os.Remove("tempfile")
 	defer filewrite.Close()
 
 	// d2 is an array of bytes
 	d2 := []byte{115, 111, 109, 101, 10} // translates to "some"
 	fn2, err := filewrite.Write(d2)      // write bytes to file
 	check(err)
-	fmt.Printf("wrote %d bytes to tempfile\n", fn2)
+	fmt.Fprintf(w, "wrote %d bytes to tempfile\n", fn2)
 
 	// write a string to the file
 	fn3, err := filewrite.WriteString("writes\n")
 	check(err)
-	fmt.Printf("wrote %d bytes\n", fn3)
+	fmt.Fprintf(w, "wrote %d bytes\n", fn3)
 	// issue a sync to flush writes to stable storage
 	filewrite.Sync()
 
 	// bufio provides buffered writers too
-	w := bufio.NewWriter(filewrite)
-	n4, err := w.WriteString("buffered\n")
+	bw := bufio.NewWriter(filewrite)
+	n4, err := bw.WriteString("buffered\n")
 	check(err)
-	fmt.Printf("wrote %d bytes\n", n4)
+	fmt.Fprintf(w, "wrote %d bytes\n", n4)
 	// use flush to ensure all buffered operatiosn have been applied to the underlying writer
-	w.Flush()
+	bw.Flush()
 
 	/*********************** file read **********************************************/
 
 	// read afile's entire contents into memory
 	dataf, errf := os.ReadFile(filePath)
 	check(errf)
-	fmt.Println("./data.txt contains: ", string(dataf))
+	fmt.Fprintln(w, "./data.txt contains: ", string(dataf))
 
 	// more often, you'll want to control over how and what parts of a file are read.
 	// first ,start by opening a file
@@ -186,7 +218,7 @@ func main() {
 	b1 := make([]byte, 14)    // create a slice holding 14 bytes
 	n1, err := filef.Read(b1) // read 14 bytes into the file, n1 is the number of the bytes
 	check(err)                // check for error
-	fmt.Printf("%d bytes: %s\n", n1, string(b1[:n1]))
+	fmt.Fprintf(w, "%d bytes: %s\n", n1, string(b1[:n1]))
 
 	// you can also seek to a know location in the file and read from there
 	o2, err := filef.Seek(6, io.SeekStart)
@@ -195,8 +227,8 @@ func main() {
 	b2 := make([]byte, 2)
 	n2, err := filef.Read(b2)
 	check(err)
-	fmt.Printf("%d bytes @ %d: ", n2, o2)
-	fmt.Printf("%v\n", string(b2[:n2]))
+	fmt.Fprintf(w, "%d bytes @ %d: ", n2, o2)
+	fmt.Fprintf(w, "%v\n", string(b2[:n2]))
 
 	// other methods of seeking are relative to the current cursor position
 	_, err = filef.Seek(4, io.SeekCurrent) // set file current position to 4 bytes
@@ -214,30 +246,44 @@ func main() {
 	b3 := make([]byte, 2)
 	n3, err := io.ReadAtLeast(filef, b3, 2)
 	check(err)
-	fmt.Printf("%d bytes @ %d: %s\n", n3, o3, string(b3))
+	fmt.Fprintf(w, "%d bytes @ %d: %s\n", n3, o3, string(b3))
 
 	// the bufio package implements a buffered reader that may be useful both for its efficiency
 	// with many small reads and because of the additional reading methods it provides
 	r4 := bufio.NewReader(filef)
 	b4, err := r4.Peek(5) // peek returns the next n bytes without advancing the reader
 	check(err)
-	fmt.Printf("5 bytes: %s\n", string(b4))
+	fmt.Fprintf(w, "5 bytes: %s\n", string(b4))
 
-	/****************************** line filters *********************************/
+	/*********************** crash safe writes **********************************/
 
-	// wrappping the unbuffered os.stdin with a buffered scanner gives us a convenient scan method that
-	//advances the scanner to the next token; which is the next line in the default scanner
-	scanner := bufio.NewScanner(os.Stdin) // scans stdin, if you didn't give any from the running of the program, it will have you input directly.
-	//Text returns the current token, here the next line, from the input.
-	for scanner.Scan() {
-		ucl := strings.ToUpper(scanner.Text()) // scanner.Text() returns each line
-		//Write out the uppercased line.
-		fmt.Println(ucl) // turns each line into upper case variants
-	}
-	//Check for errors during Scan. End of file is expected and not reported by Scan as an error.
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintln(os.Stderr, "error:", err)
-		os.Exit(1)
+	// a config file rewritten in place is lost if the program crashes halfway. SafeReplace writes the new content atomically
+	// and keeps the previous one in config.json.bak, to go back to it if the new one turns out wrong
+	config := filepath.Join(dname, "config.json")
+	check(fileutil.AtomicWriteFile(config, []byte(`{"version": 1}`), 0644))
+	backup, err := fileutil.SafeReplace(config, []byte(`{"version": 2}`), 0644)
+	check(err)
+	current, err := os.ReadFile(config)
+	check(err)
+	previous, err := os.ReadFile(backup)
+	check(err)
+	fmt.Fprintf(w, "config.json: %s, %s: %s\n", current, filepath.Base(backup), previous)
+
+	// an AppendWriter appends under a lock, the lines written by several goroutines, or processes, don't interleave
+	journal, err := fileutil.OpenAppend(filepath.Join(dname, "journal.log"), 0644)
+	check(err)
+	fmt.Fprintln(journal, "config.json replaced, version 2")
+	check(journal.Close()) // close syncs the file too
+
+	/****************************** line filters *********************************/
+	if !*stdin {
+		return nil
 	}
 
+	// wrappping the unbuffered os.stdin with a buffered scanner gives us a convenient scan method that
+	// advances the scanner to the next token; which is the next line in the default scanner.
+	// the linefilter package does that scanning for us, with a buffer that grows past the 64KB a scanner allows by default,
+	// and runs every line through its transforms. Upper turns each line into its upper case variant.
+	// oneforall filter has the other transforms, like grep, replace and line numbers
+	return linefilter.Filter(ctx, w, os.Stdin, linefilter.Options{}, linefilter.Upper()) // if you didn't pipe anything in, type the input directly
 }
@@ -0,0 +1,152 @@
+// Package fileutil writes files so that a crash never leaves them half written.
+// os.WriteFile truncates the file before writing it, a crash in between leaves an empty or partial file behind.
+// AtomicWriteFile writes a temporary file next to the target instead, syncs it, renames it over the target and syncs the directory,
+// so that the target holds either the old or the new content. SafeReplace does the same and keeps the old content as a backup.
+// AppendWriter appends to a file under a lock, so that the writes of several goroutines or processes don't interleave
+package fileutil
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// AtomicWriteFile writes data to the file name, replacing it atomically if it exists.
+// perm is the mode of the new file, unlike os.WriteFile it isn't masked by the umask
+func AtomicWriteFile(name string, data []byte, perm fs.FileMode) error {
+	return AtomicWrite(name, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// AtomicWrite is AtomicWriteFile for content that is streamed: write writes the content to a temporary file in the directory of name,
+// which replaces name once write returns without an error. if write or a later step fails, name is left as it was and the temporary file is removed
+func AtomicWrite(name string, perm fs.FileMode, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(name)
+	// the temporary file must be on the same file system as name for the rename to be atomic, hence the same directory.
+	// the leading dot hides it from ls while it's being written
+	f, err := os.CreateTemp(dir, "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err := write(f); err != nil {
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	// the content must be on the disk before the rename, otherwise a crash can leave name renamed but empty
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return err
+	}
+	// the rename itself is only durable once the directory is synced
+	return syncDir(dir)
+}
+
+// BackupSuffix is appended to the name of a file to name its backup
+const BackupSuffix = ".bak"
+
+// SafeReplace is AtomicWriteFile keeping the previous content of name in name+BackupSuffix, which replaces an older backup.
+// it returns the name of the backup, or an empty string if name didn't exist
+func SafeReplace(name string, data []byte, perm fs.FileMode) (backup string, err error) {
+	info, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", AtomicWriteFile(name, data, perm)
+	}
+	if err != nil {
+		return "", err
+	}
+	backup = name + BackupSuffix
+	if err := makeBackup(name, backup, info.Mode().Perm()); err != nil {
+		return "", err
+	}
+	return backup, AtomicWriteFile(name, data, perm)
+}
+
+// makeBackup makes backup a copy of name, atomically too so that a crash doesn't lose the previous backup.
+// a hard link shares the content instead of copying it: the new file replaces name with another inode, the backup keeps the old one
+func makeBackup(name, backup string, perm fs.FileMode) error {
+	tmp := backup + ".tmp"
+	os.Remove(tmp) // left by a crash
+	if err := os.Link(name, tmp); err == nil {
+		if err := os.Rename(tmp, backup); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return syncDir(filepath.Dir(backup))
+	}
+	// the file system doesn't have hard links
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return AtomicWriteFile(backup, data, perm)
+}
+
+// AppendWriter appends to a file, every Write is done under a lock and at the end of the file.
+// the lock is a mutex between goroutines, and on unix an flock between processes, so that the records written
+// with a single Write, like the lines of a log, never interleave
+type AppendWriter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// OpenAppend opens the file name for appending, creating it with perm if it doesn't exist
+func OpenAppend(name string, perm fs.FileMode) (*AppendWriter, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &AppendWriter{f: f}, nil
+}
+
+// Write appends p to the file under the lock
+func (a *AppendWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := lockFile(a.f); err != nil {
+		return 0, err
+	}
+	defer unlockFile(a.f)
+	return a.f.Write(p)
+}
+
+// WriteString appends s to the file under the lock
+func (a *AppendWriter) WriteString(s string) (int, error) {
+	return a.Write([]byte(s))
+}
+
+// Sync commits what was written to the disk
+func (a *AppendWriter) Sync() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.f.Sync()
+}
+
+// Close syncs and closes the file
+func (a *AppendWriter) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return errors.Join(a.f.Sync(), a.f.Close())
+}
+
+// Name returns the name of the file
+func (a *AppendWriter) Name() string {
+	return a.f.Name()
+}
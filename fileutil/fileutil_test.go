@@ -0,0 +1,160 @@
+package fileutil
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// names returns the names of the files in dir
+func names(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Name())
+	}
+	return out
+}
+
+func TestAtomicWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.json")
+	if err := AtomicWriteFile(name, []byte("one"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := AtomicWriteFile(name, []byte("two"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(name)
+	if err != nil || string(got) != "two" {
+		t.Errorf("content = %q, %v; want two", got, err)
+	}
+	if info, _ := os.Stat(name); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v; want 0640", info.Mode().Perm())
+	}
+	if got := names(t, dir); len(got) != 1 {
+		t.Errorf("files left in the directory: %v", got)
+	}
+}
+
+func TestAtomicWriteFailure(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.json")
+	AtomicWriteFile(name, []byte("old"), 0644)
+
+	boom := errors.New("boom")
+	err := AtomicWrite(name, 0644, func(w io.Writer) error {
+		io.WriteString(w, "half of the new content")
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("err = %v; want boom", err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "old" {
+		t.Errorf("content = %q; want the old one", got)
+	}
+	if got := names(t, dir); len(got) != 1 {
+		t.Errorf("the temporary file was left: %v", got)
+	}
+
+	if err := AtomicWriteFile(filepath.Join(dir, "missing", "f"), nil, 0644); err == nil {
+		t.Error("write in a missing directory succeeded")
+	}
+}
+
+func TestSafeReplace(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.json")
+
+	backup, err := SafeReplace(name, []byte("v1"), 0644)
+	if err != nil || backup != "" {
+		t.Fatalf("first write: backup %q, err %v; want no backup", backup, err)
+	}
+	for _, v := range []string{"v2", "v3"} {
+		if backup, err = SafeReplace(name, []byte(v), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backup != name+BackupSuffix {
+		t.Errorf("backup = %q", backup)
+	}
+	for file, want := range map[string]string{name: "v3", backup: "v2"} {
+		if got, _ := os.ReadFile(file); string(got) != want {
+			t.Errorf("%s = %q; want %q", filepath.Base(file), got, want)
+		}
+	}
+	if got := names(t, dir); len(got) != 2 {
+		t.Errorf("files in the directory: %v; want the file and its backup", got)
+	}
+}
+
+func TestAppendWriter(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "journal.log")
+	os.WriteFile(name, []byte("existing\n"), 0644)
+
+	const writers, lines = 8, 200
+	// two writers on the same file, like two processes would have
+	var aws []*AppendWriter
+	for range 2 {
+		aw, err := OpenAppend(name, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aws = append(aws, aw)
+	}
+	long := strings.Repeat("x", 4096) // long lines make a torn or interleaved write likely
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range lines {
+				fmt.Fprintf(aws[i%2], "%d %d %s\n", i, j, long)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, aw := range aws {
+		if err := aw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	sc.Scan()
+	if sc.Text() != "existing" {
+		t.Errorf("first line = %q; the existing content was overwritten", sc.Text())
+	}
+	n := 0
+	for sc.Scan() {
+		var i, j int
+		var rest string
+		if _, err := fmt.Sscanf(sc.Text(), "%d %d %s", &i, &j, &rest); err != nil || rest != long {
+			t.Fatalf("line %d is torn: %.40q", n, sc.Text())
+		}
+		n++
+	}
+	if n != writers*lines {
+		t.Errorf("%d lines; want %d", n, writers*lines)
+	}
+
+	if _, err := aws[0].Write([]byte("late")); err == nil {
+		t.Error("write after Close succeeded")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package fileutil
+
+import "os"
+
+// lockFile only has the mutex of AppendWriter outside unix, the writes of other processes may interleave
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
+
+// syncDir does nothing, directories can't be synced outside unix
+func syncDir(dir string) error { return nil }
@@ -0,0 +1,173 @@
+// Package golden compares the output of a test with a golden file checked in next to the test, usually under testdata.
+// the parts of an output that change from run to run, such as timestamps or temp file names, are masked before comparing.
+// run the tests with -update to write the current output to the golden files instead of comparing:
+//
+//	go test ./cmd/oneforall -run TestGolden -update
+package golden
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// the flag is registered on the global flag set, which the test binary parses, so every test importing golden gets it
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+// Mask rewrites the parts of an output that are not the same on every run, so that it can be compared with a golden file
+type Mask func(string) string
+
+// Replace returns a Mask replacing every match of the regular expression pattern with repl,
+// repl can refer to submatches like regexp.ReplaceAllString does
+func Replace(pattern, repl string) Mask {
+	re := regexp.MustCompile(pattern)
+	return func(s string) string {
+		return re.ReplaceAllString(s, repl)
+	}
+}
+
+// SortLines is a Mask for output written by several goroutines: the lines are sorted,
+// so only what is printed is compared and not the order in which the goroutines got to print it
+func SortLines(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n" // a missing final newline must not change where the last line sorts
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "")
+}
+
+// SortRuns returns a Mask sorting each run of consecutive lines matching the regular expression pattern.
+// it is for the parts of an output that several goroutines print at once, the lines around them keep their order
+func SortRuns(pattern string) Mask {
+	re := regexp.MustCompile(pattern)
+	return func(s string) string {
+		// like SortLines, a missing final newline must not change where the last line sorts
+		unterminated := s != "" && !strings.HasSuffix(s, "\n")
+		if unterminated {
+			s += "\n"
+		}
+		lines := strings.SplitAfter(s, "\n")
+		for i := 0; i < len(lines); {
+			j := i
+			for j < len(lines) && lines[j] != "" && re.MatchString(strings.TrimSuffix(lines[j], "\n")) {
+				j++
+			}
+			slices.Sort(lines[i:j])
+			i = max(j, i+1)
+		}
+		s = strings.Join(lines, "")
+		if unterminated {
+			s = strings.TrimSuffix(s, "\n")
+		}
+		return s
+	}
+}
+
+// Apply runs the masks on s in order
+func Apply(s string, masks ...Mask) string {
+	for _, m := range masks {
+		s = m(s)
+	}
+	return s
+}
+
+// Updating reports whether the tests were run with -update
+func Updating() bool {
+	return *update
+}
+
+// Assert masks got and compares it with the golden file at path, failing t with a line diff if they differ.
+// with -update the masked output is written to path instead, creating its directory if needed
+func Assert(t testing.TB, path string, got []byte, masks ...Mask) {
+	t.Helper()
+	masked := Apply(string(got), masks...)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(masked), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run the test with -update to create it", err)
+	}
+	if d := Diff(string(want), masked); d != "" {
+		t.Errorf("output differs from %s (-want +got):\n%s", path, d)
+	}
+}
+
+// Diff returns a line diff turning want into got, lines only in want start with "-" and lines only in got with "+".
+// it returns an empty string if they are equal
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// walk the table to get the edit script, then print the changed lines with a few lines of context
+	type op struct {
+		kind byte // ' ', '-' or '+'
+		line string
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+
+	const context = 2
+	var sb strings.Builder
+	lastPrinted := -1
+	for k, o := range ops {
+		near := false
+		for n := max(k-context, 0); n <= min(k+context, len(ops)-1); n++ {
+			near = near || ops[n].kind != ' '
+		}
+		if !near {
+			continue
+		}
+		if lastPrinted >= 0 && k > lastPrinted+1 {
+			sb.WriteString("...\n")
+		}
+		sb.WriteString(string(o.kind) + " " + o.line + "\n")
+		lastPrinted = k
+	}
+	return sb.String()
+}
@@ -0,0 +1,94 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	m := Replace(`(data\.txt)\d+`, "${1}RANDOM")
+	if got := m("/tmp/data.txt12345 and data.txt9"); got != "/tmp/data.txtRANDOM and data.txtRANDOM" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSortLines(t *testing.T) {
+	var tests = []struct {
+		in, want string
+	}{
+		{"b\na\nc\n", "a\nb\nc\n"},
+		{"b\na", "a\nb\n"}, // the last line gets its newline so it sorts like the others
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := SortLines(tt.in); got != tt.want {
+			t.Errorf("SortLines(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSortRuns(t *testing.T) {
+	m := SortRuns(`^worker \d`)
+	var tests = []struct {
+		in, want string
+	}{
+		{"start\nworker 2\nworker 1\nmiddle\nworker 3\nworker 1\nend\n", "start\nworker 1\nworker 2\nmiddle\nworker 1\nworker 3\nend\n"},
+		{"worker 2\nworker 1", "worker 1\nworker 2"}, // the last line keeps missing its newline
+		{"b\na\n", "b\na\n"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := m(tt.in); got != tt.want {
+			t.Errorf("SortRuns(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	got := Apply("worker 3 started", Replace(`\d`, "N"), Replace("started", "done"))
+	if got != "worker N done" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if d := Diff("a\nb\n", "a\nb\n"); d != "" {
+		t.Errorf("equal inputs give diff %q", d)
+	}
+	want := "  a\n- b\n+ B\n  c\n"
+	if d := Diff("a\nb\nc", "a\nB\nc"); d != want {
+		t.Errorf("Diff = %q; want %q", d, want)
+	}
+	// lines far from a change are left out
+	d := Diff("1\n2\n3\n4\n5\n6\n7\n8\nx\n", "1\n2\n3\n4\n5\n6\n7\n8\ny\n")
+	if want := "  7\n  8\n- x\n+ y\n  \n"; d != want {
+		t.Errorf("Diff = %q; want %q", d, want)
+	}
+}
+
+func TestAssert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.golden")
+	if err := os.WriteFile(path, []byte("took Ns\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// a passing comparison, after masking
+	Assert(t, path, []byte("took 42s\n"), Replace(`\d+`, "N"))
+
+	// a failing one is reported on the test it is given
+	rec := &recorder{TB: t}
+	Assert(rec, path, []byte("took 42s\n"))
+	if !rec.failed {
+		t.Error("Assert passed on output that differs from the golden file")
+	}
+}
+
+// recorder notes the failures instead of failing the test it wraps
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper()               {}
+func (r *recorder) Errorf(string, ...any) { r.failed = true }
+func (r *recorder) Fatalf(string, ...any) { r.failed = true }
@@ -1,38 +1,35 @@
-package main
+// Package goroutineexample shows goroutines, channels, select, timers, tickers, worker pools,
+// rate limiting, atomic counters, mutexes and stateful goroutines
+package goroutineexample
 
 import (
+	"RobotTask/actor"
+	"RobotTask/clock"
+	"RobotTask/counter"
+	"RobotTask/ratelimit"
+	"RobotTask/workerpool"
+	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// in this example our state will b owned by a single goroutine, in order to read or write that state,
-// other goroutines will ssend messages to the owning goroutine and receive corresponding replies.
-type readOp struct {
-	key  int
-	resp chan int
-}
-type writeOp struct {
-	key  int
-	val  int
-	resp chan bool
-}
-
 // function that prints string 3 times
-func fun3(from string) {
+func fun3(w io.Writer, from string) {
 	for i := 0; i < 3; i++ {
-		fmt.Println(from, ":", i)
+		fmt.Fprintln(w, from, ":", i)
 	}
 }
 
 // this is the function we'll run in a goroutine. the done channel
 // will be used to notify another goroutine that this function's work is done
-func worker(done chan bool) {
-	fmt.Print("working...")
-	time.Sleep(time.Second)
-	fmt.Println("done")
+func worker(w io.Writer, clk clock.Clock, done chan bool) {
+	fmt.Fprint(w, "working...")
+	clk.Sleep(time.Second)
+	fmt.Fprintln(w, "done")
 	done <- true // send true to channel
 }
 
@@ -48,20 +45,20 @@ func pong(pings <-chan string, pongs chan<- string) {
 }
 
 // workers receive work on the jobs channel and send the result on results channel
-func worker2(id int, jobs <-chan int, results chan<- int) {
+func worker2(w io.Writer, clk clock.Clock, id int, jobs <-chan int, results chan<- int) {
 	for j := range jobs {
-		fmt.Println("worker", id, "started  job", j)
-		time.Sleep(time.Second) // sleep a second per job to simulate an expensive task
-		fmt.Println("worker", id, "finished job", j)
+		fmt.Fprintln(w, "worker", id, "started  job", j)
+		clk.Sleep(time.Second) // sleep a second per job to simulate an expensive task
+		fmt.Fprintln(w, "worker", id, "finished job", j)
 		results <- j * 2
 	}
 }
 
 // this is the function to simulate an expensive task
-func worker3(id int) {
-	fmt.Printf("Worker %d starting\n", id)
-	time.Sleep(time.Second)
-	fmt.Printf("Worker %d done\n", id)
+func worker3(w io.Writer, clk clock.Clock, id int) {
+	fmt.Fprintf(w, "Worker %d starting\n", id)
+	clk.Sleep(time.Second)
+	fmt.Fprintf(w, "Worker %d done\n", id)
 }
 
 // container holds a map fo counters,since we want to update it concurrently from multiple goroutines, we add a mutext to synchronize access.
@@ -78,18 +75,151 @@ func (c *Container) inc(name string) {
 	c.counters[name]++  // update key
 }
 
-func main() {
+// selectTimeouts uses select with clk.After to give up on a call that takes too long
+func selectTimeouts(w io.Writer, clk clock.Clock) {
+	// execute a call that returns on channel c1 after 2s, c1 is buffered so the call doesn't block forever once we stopped listening
+	c1 := make(chan string, 1)
+	go func() {
+		clk.Sleep(2 * time.Second)
+		c1 <- "result 1"
+	}()
+
+	// perform select to catch c1 or a timeout
+	select {
+	case res := <-c1:
+		fmt.Fprintln(w, res)
+	// time.After is a channel as well, clk.After is time.After unless clk is a fake
+	case <-clk.After(1 * time.Second): // timeout happens
+		fmt.Fprintln(w, "timeout 1")
+	}
+
+	c2 := make(chan string, 1)
+	go func() {
+		clk.Sleep(2 * time.Second)
+		c2 <- "result 2"
+	}()
+	// for c2, the time to sleep is 2 seconds, the receive will succceed
+	select {
+	case res := <-c2:
+		fmt.Fprintln(w, res)
+	case <-clk.After(3 * time.Second):
+		fmt.Fprintln(w, "timeout 2")
+	}
+}
+
+// timers fires one timer and stops another before it fires
+func timers(w io.Writer, clk clock.Clock) {
+	// timer represents a single event in the future. you tell the timer how long you want to wait,
+	// and it provides a channel that will be notified at that time. this timer will wait 2 seconds
+	timer1 := clk.NewTimer(2 * time.Second)
+	<-timer1.C() // blocks on the timer's channel C(must be C, C stands for channel) until it sends a value indicating that the timer fired
+	fmt.Fprintln(w, "Timer 1 fired")
+	// if you just wanted to wait, you could have used time.Sleep,
+	// one reason a timer may be useful is that you can cancel the timer before it fires.
+	timer2 := clk.NewTimer(time.Second)
+	go func() {
+		<-timer2.C()
+		fmt.Fprintln(w, "Timer 2 fired")
+	}()
+	stop2 := timer2.Stop()
+	if stop2 {
+		fmt.Fprintln(w, "Timer 2 stopped")
+	}
+	// Give the timer2 enough time to fire,
+	// if it ever was going to, to show it is in fact stopped.
+
+	// the first timer will fire 2s after we start the program, but the second should be stopped before it has a chance to fire
+	clk.Sleep(2 * time.Second)
+}
+
+// tickers prints the ticks of a 500ms ticker for 1600ms
+func tickers(w io.Writer, clk clock.Clock) {
+	// tickers use a similar mechanism to timers:a channel that is sent values.
+	done := make(chan bool)
+	ticker := clk.NewTicker(500 * time.Millisecond)
+	// tickers can be stopped like timers, once a tiker is stopped it wont received any more values on its channel
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case t := <-ticker.C():
+				fmt.Fprintln(w, "Tick at", t)
+			}
+		}
+	}()
+	// stop the ticker after 1600ms
+	clk.Sleep(1600 * time.Millisecond)
+	ticker.Stop()
+	// synchronously wait till done
+	done <- true
+	fmt.Fprintln(w, "Ticker stopped")
+}
+
+// rateLimits serves requests at the pace of a rate limiter, first one request every 200ms, then with bursts of 3
+func rateLimits(ctx context.Context, w io.Writer, clk clock.Clock) {
+	requests := make(chan int, 5) // create a buffering channel that takes in at most 5 ints
+	// buffer 1 to 5 into requests
+	for i := 1; i <= 5; i++ {
+		requests <- i
+	}
+	close(requests) // close the channel, 1-5 is stored in che channel buffer
+
+	// the classic regulator is a time.Tick channel that receives a value every 200ms, but its ticker can never be stopped
+	// or reconfigured. ratelimit.RateLimiter is a token bucket that gains one token every 200ms instead, with room for 1 token
+	limiter := ratelimit.NewWithClock(200*time.Millisecond, 1, clk)
+
+	// by waiting on the limiter before serving each request, we limit ourselves to 1 request every 200 ms
+	for req := range requests {
+		limiter.Wait(ctx) // Wait takes a context, so a cancelled request stops waiting
+		fmt.Fprintln(w, "request", req, clk.Now())
+	}
+
+	// a bucket with room for 3 tokens allows bursts of up to 3 events, it starts full to represent allowed bursting.
+	// every 200ms a new token is added, up to the limit of 3
+	burstyLimiter := ratelimit.NewWithClock(200*time.Millisecond, 3, clk)
+
+	// now simulate 5 more incoming requests. the first 3 of these will benefit from the burst capability of burstyLimiter
+	burstyRequests := make(chan int, 5)
+	for i := 1; i <= 5; i++ {
+		burstyRequests <- i
+	}
+	close(burstyRequests) // close channel
+
+	// ranging over bursty requests, which is five ints
+	for req := range burstyRequests {
+		burstyLimiter.Wait(ctx) // first three executes immediately, because the bucket is full, the next two waits at 200ms intervals
+		fmt.Fprintln(w, "request", req, clk.Now())
+	}
+
+	// Allow doesn't wait, it reports whether a token is available right now
+	fmt.Fprintln(w, "burst used up, allowed right away:", burstyLimiter.Allow())
+
+	// KeyedLimiter keeps one bucket per key, for example per client, and forgets the clients that went quiet
+	perClient := ratelimit.NewKeyedWithClock[string](200*time.Millisecond, 1, time.Minute, clk)
+	fmt.Fprintln(w, "client a allowed:", perClient.Allow("a"), "client a allowed again:", perClient.Allow("a"), "client b allowed:", perClient.Allow("b"))
+}
+
+// Run runs the examples one after another, printing to w. it takes about 15 seconds because of the sleeps, timers and tickers.
+// the goroutines of the examples print concurrently, so w must be safe for concurrent use, like os.Stdout
+func Run(ctx context.Context, w io.Writer) error {
+	return RunWithClock(ctx, w, clock.Real())
+}
+
+// RunWithClock is like Run but the sleeps, timers, tickers and rate limiters wait on clk.
+// the stateful goroutine at the end is stopped by a context deadline, which always uses real time
+func RunWithClock(ctx context.Context, w io.Writer, clk clock.Clock) error {
 	// goroutines, lightweight threads of execution
-	fun3("direct")        // running a function synchronously
-	go fun3("goroutine")  // funning a function using goroutine, will execute concurrently with the calling one
-	go func(msg string) { // running an anonymous function
-		fmt.Println(msg)
+	fun3(w, "direct")       // running a function synchronously
+	go fun3(w, "goroutine") // funning a function using goroutine, will execute concurrently with the calling one
+	go func(msg string) {   // running an anonymous function
+		fmt.Fprintln(w, msg)
 	}("going") // this is the input parameter
 
 	// our two calls are running in seperate goroutines now, wait for them to finish,otherwise the function will end before the output
 	// sleep function
-	time.Sleep(time.Second)
-	fmt.Println("fun3 and anonymous goroutine done")
+	clk.Sleep(time.Second)
+	fmt.Fprintln(w, "fun3 and anonymous goroutine done")
 
 	// by default channels are unbuffered, meaning that they will only
 	// accept sends(chan<-) if there is a corresponding receive(<-chan) ready to receive the sent value
@@ -98,7 +228,7 @@ func main() {
 	go func() { messages <- "ping" }()
 	// get the message from the channel
 	msg := <-messages // by default sends and receives block until both the sender and receiver are ready
-	fmt.Println(msg)
+	fmt.Fprintln(w, msg)
 
 	// buffered channels accept a limited number of values without a cooresponding receiver for those values
 	messages2 := make(chan string, 2) // this means the channel allows buffering up to 2 values
@@ -106,15 +236,15 @@ func main() {
 	messages2 <- "channel"
 	//messages2 <- "third" // do not attempt to enter another value, buffer is full
 	// first in first out
-	fmt.Println("getting the first message from messages2: ", <-messages2)  // get s buffered
-	fmt.Println("getting the second message from messages2: ", <-messages2) // gets channel
+	fmt.Fprintln(w, "getting the first message from messages2: ", <-messages2)  // get s buffered
+	fmt.Fprintln(w, "getting the second message from messages2: ", <-messages2) // gets channel
 
 	messages2 <- "buffered again" // buffered channels can receive extra input after buffer is cleared
-	fmt.Println(<-messages2)
+	fmt.Fprintln(w, <-messages2)
 
 	// channel synchronization
 	done := make(chan bool, 1) // create a buffered channel
-	go worker(done)            // start the goroutine, giving it the channel to notify on
+	go worker(w, clk, done)    // start the goroutine, giving it the channel to notify on
 	// if we remove <-done, the program would exit before the worker even started
 	<-done // blocks until we receive a notification from the worker on the channel
 
@@ -123,7 +253,7 @@ func main() {
 	pongs := make(chan string, 1)
 	ping(pings, "passed message")
 	pong(pings, pongs)
-	fmt.Println(<-pongs)
+	fmt.Fprintln(w, <-pongs)
 
 	// using select across two channels
 	c1 := make(chan string)
@@ -132,11 +262,11 @@ func main() {
 	// each channel will receive a value after some amount of time to
 	// simulate blocking rpc operations executing in concurrent goroutines
 	go func() {
-		time.Sleep(1 * time.Second)
+		clk.Sleep(1 * time.Second)
 		c1 <- "one"
 	}()
 	go func() {
-		time.Sleep(1 * time.Second)
+		clk.Sleep(1 * time.Second)
 		c2 <- "two"
 	}()
 
@@ -145,38 +275,13 @@ func main() {
 	for i := 0; i < 2; i++ {
 		select {
 		case msg1 := <-c1:
-			fmt.Println("received", msg1)
+			fmt.Fprintln(w, "received", msg1)
 		case msg2 := <-c2:
-			fmt.Println("received", msg2)
+			fmt.Fprintln(w, "received", msg2)
 		}
 	}
 
-	// execute a call that returns on channel c1 after 2s, c1 is buffered
-	go func() {
-		time.Sleep(2 * time.Second)
-		c1 <- "result 1"
-	}()
-
-	// perform select to catch c1 or a timeout
-	select {
-	case res := <-c1:
-		fmt.Println(res)
-	// time.After is a channel as well
-	case <-time.After(1 * time.Second): // timeout happens
-		fmt.Println("timeout 1")
-	}
-
-	go func() {
-		time.Sleep(2 * time.Second)
-		c2 <- "result 2"
-	}()
-	// for c2, the time to sleep is 2 seconds, the receive will succceed
-	select {
-	case res := <-c2:
-		fmt.Println(res)
-	case <-time.After(3 * time.Second):
-		fmt.Println("timeout 2")
-	}
+	selectTimeouts(w, clk)
 
 	// using default clause in select to implement non-blocking sends,receives and multi-way selects
 	signals := make(chan bool) // create an unbuffered channel signals
@@ -186,9 +291,9 @@ func main() {
 	// <-messages case with that value. if not it will immediately take the default case
 	select {
 	case msg2 := <-messages:
-		fmt.Println("received message", msg2)
+		fmt.Fprintln(w, "received message", msg2)
 	default:
-		fmt.Println("no message received")
+		fmt.Fprintln(w, "no message received")
 	}
 
 	// here is a non-blocking send. here msg2 cannot be sent to the messages channel, because the channel
@@ -196,20 +301,20 @@ func main() {
 	msg2 := "hi"
 	select {
 	case messages <- msg2:
-		fmt.Println("sent message", msg2)
+		fmt.Fprintln(w, "sent message", msg2)
 	default:
-		fmt.Println("no message sent")
+		fmt.Fprintln(w, "no message sent")
 	}
 
 	// we can use multiple cases above the default clause to implement a multi-way non-blocking select.
 	// here thwe attempt non-blocking receives on both messages and signals
 	select {
 	case msg2 := <-messages:
-		fmt.Println("received message", msg2)
+		fmt.Fprintln(w, "received message", msg2)
 	case sig := <-signals:
-		fmt.Println("received signal", sig)
+		fmt.Fprintln(w, "received signal", sig)
 	default:
-		fmt.Println("no activity")
+		fmt.Fprintln(w, "no activity")
 	}
 
 	// closing channels
@@ -222,9 +327,9 @@ func main() {
 			// the more value will be false if jobs has been closed and all values in the channel have already been received.
 			j, more := <-jobs
 			if more {
-				fmt.Println("received job", j)
+				fmt.Fprintln(w, "received job", j)
 			} else {
-				fmt.Println("received all jobs")
+				fmt.Fprintln(w, "received all jobs")
 				done2 <- true
 				return
 			}
@@ -234,80 +339,41 @@ func main() {
 	// this sends 3 jobs to the worker over the jobs channel, then closes it
 	for j := 1; j <= 3; j++ {
 		jobs <- j
-		fmt.Println("sent job", j)
+		fmt.Fprintln(w, "sent job", j)
 	}
 	// close a channel
 	close(jobs)
-	fmt.Println("sent all jobs")
+	fmt.Fprintln(w, "sent all jobs")
 	<-done2 // we await the wroker using the synchronization approach
 	//reading from a closed channel succeeds immediately, returning the zero value of the underlying type.
 	// the optional second value is true if the value received was delivered by a successful send operation or
 	// false if it was a zero value generated because the channel is closed and empty.
 	_, ok := <-jobs
-	fmt.Println("received more jobs", ok)
+	fmt.Fprintln(w, "received more jobs", ok)
 
 	// perfroming range on channels
 	queue := make(chan string, 2)
 	queue <- "one"
 	queue <- "two"
-	fmt.Println("retrieve an element from queue:", <-queue)
+	fmt.Fprintln(w, "retrieve an element from queue:", <-queue)
 	queue <- "three"
 	close(queue)
 	// the range iterates over each element as it's received from queue. because we closed the channel above,
 	// the iteration terminates after receiving the 2 elements
 	for elem := range queue { // not that it is possible to close a non-empty channel but still have the remaining values be received
-		fmt.Println("final elements in queue:", elem)
-	}
-
-	// timer represents a single event in the future. you tell the timer how long you want to wait,
-	// and it provides a channel that will be notified at that time. this timer will wait 2 seconds
-	timer1 := time.NewTimer(2 * time.Second)
-	<-timer1.C // blocks on the timer's channel C(must be C, C stands for channel) until it sends a value indicating that the timer fired
-	fmt.Println("Timer 1 fired")
-	// if you just wanted to wait, you could have used time.Sleep,
-	// one reason a timer may be useful is that you can cancel the timer before it fires.
-	timer2 := time.NewTimer(time.Second)
-	go func() {
-		<-timer2.C
-		fmt.Println("Timer 2 fired")
-	}()
-	stop2 := timer2.Stop()
-	if stop2 {
-		fmt.Println("Timer 2 stopped")
+		fmt.Fprintln(w, "final elements in queue:", elem)
 	}
-	// Give the timer2 enough time to fire,
-	// if it ever was going to, to show it is in fact stopped.
 
-	// the first timer will fire 2s after we start the program, but the second should be stopped before it has a chance to fire
-	time.Sleep(2 * time.Second)
-
-	// tickers use a similar mechanism to timers:a channel that is sent values.
-	ticker := time.NewTicker(500 * time.Millisecond)
-	// tickers can be stopped like timers, once a tiker is stopped it wont received any more values on its channel
-	go func() {
-		for {
-			select {
-			case <-done2:
-				return
-			case t := <-ticker.C:
-				fmt.Println("Tick at", t)
-			}
-		}
-	}()
-	// stop the ticker after 1600ms
-	time.Sleep(1600 * time.Millisecond)
-	ticker.Stop()
-	// synchronously wait till done
-	done2 <- true
-	fmt.Println("Ticker stopped")
+	timers(w, clk)
+	tickers(w, clk)
 
 	//create buffering channels
 	const numJobs = 5
 	jobs3 := make(chan int, numJobs)
 	results3 := make(chan int, numJobs)
 	// start up 3 workers, initially blocked because there aren't any jobs yet
-	for w := 1; w <= 3; w++ {
-		go worker2(w, jobs3, results3)
+	for id := 1; id <= 3; id++ {
+		go worker2(w, clk, id, jobs3, results3)
 	}
 
 	// send 5 jobs, whoever gets the job first start doing it
@@ -321,6 +387,40 @@ func main() {
 		<-results3
 	}
 
+	// the workerpool package wraps the same pattern for reuse: the job is a parameter, it can fail,
+	// and the first failure cancels the jobs that are still running
+	pool := workerpool.New(ctx, 3, workerpool.Ordered, func(ctx context.Context, j int) (int, error) {
+		if j == 42 {
+			return 0, fmt.Errorf("can't work with %d", j)
+		}
+		select {
+		case <-clk.After(100 * time.Millisecond): // a shorter expensive task
+			return j * 2, nil
+		case <-ctx.Done(): // give up once another job has failed
+			return 0, ctx.Err()
+		}
+	})
+	go func() {
+		for j := 1; j <= numJobs; j++ {
+			pool.Submit(j)
+		}
+		pool.Close() // no more jobs, like close(jobs3)
+	}()
+	for r := range pool.Results() { // Ordered delivers the results in the order the jobs were submitted
+		fmt.Fprintln(w, "pool result for job", r.Seq+1, "is", r.Value)
+	}
+	if err := pool.Wait(); err != nil {
+		fmt.Fprintln(w, "pool failed:", err)
+	}
+	// Map is a shortcut for the common submit-everything-then-collect case
+	_, err := workerpool.Map(ctx, 3, []int{1, 42, 3}, func(ctx context.Context, j int) (int, error) {
+		if j == 42 {
+			return 0, fmt.Errorf("can't work with %d", j)
+		}
+		return j * 2, nil
+	})
+	fmt.Fprintln(w, "workerpool.Map error:", err)
+
 	var wg sync.WaitGroup // this waitgroup is used to wait for all the goroutines launched here to finsh
 	// if a waitgroup is explicitly passed into functions, it should be done by pointer
 
@@ -330,56 +430,13 @@ func main() {
 		// This way the worker itself does not have to be aware of the concurrency primitives involved in its execution.
 		go func() {
 			defer wg.Done()
-			worker3(i)
+			worker3(w, clk, i)
 		}()
 	}
 	//Block until the WaitGroup counter goes back to 0; all the workers notified they’re done.
 	wg.Wait()
 
-	// rate limiting
-	requests := make(chan int, 5) // create a buffering channel that takes in at most 5 ints
-	// buffer 1 to 5 into requests
-	for i := 1; i <= 5; i++ {
-		requests <- i
-	}
-	close(requests) // close the channel, 1-5 is stored in che channel buffer
-
-	// the limiter channel will receive a value every 200ms, this is the regulator in our rate limiting scheme
-	limiter := time.Tick(200 * time.Millisecond)
-
-	// by blocking on a receive from the limiter channel before serving each request,we limit ourselves to 1 request every 200 ms
-	for req := range requests {
-		<-limiter // limiter is triggered every 200ms, and so is this function
-		fmt.Println("request", req, time.Now())
-	}
-
-	// creating a new buffering channel, this channel will allow bursts of up to 3 events
-	burstyLimiter := make(chan time.Time, 3) // the channel type is time.Time
-
-	// fill up the channel to represent allowed bursting
-	for i := 0; i < 3; i++ {
-		burstyLimiter <- time.Now()
-	}
-
-	// every 200ms we'll try to add a new value to burstylimiter , up to it's limit of 3
-	go func() {
-		for t := range time.Tick(200 * time.Millisecond) {
-			burstyLimiter <- t
-		}
-	}()
-
-	// now simulate 5 more incoming requests. the first 3 of these will benefit from the burst capability of burstyLimiter
-	burstyRequests := make(chan int, 5)
-	for i := 1; i <= 5; i++ {
-		burstyRequests <- i
-	}
-	close(burstyRequests) // close channel
-
-	// ranging over bursty requests, which is five ints
-	for req := range burstyRequests {
-		<-burstyLimiter // first three executes immediately, because limiter already has values buffered, the next two waits at 200ms intervals
-		fmt.Println("request", req, time.Now())
-	}
+	rateLimits(ctx, w, clk)
 
 	// atomic counters
 	var ops atomic.Uint64 // atomic integer type to represent our counter(always positive
@@ -393,8 +450,8 @@ func main() {
 			wg.Done() // finish the task
 		}()
 	}
-	wg.Wait()                       // wait for task to finish
-	fmt.Println("ops:", ops.Load()) // read the value of the counter
+	wg.Wait()                           // wait for task to finish
+	fmt.Fprintln(w, "ops:", ops.Load()) // read the value of the counter
 
 	// create a container object
 	con := Container{
@@ -414,68 +471,60 @@ func main() {
 	go doIncrement("a", 10000)
 	go doIncrement("b", 10000)
 	wg.Wait() //Wait for the goroutines to finish
-	fmt.Println("container counters: ", con.counters)
-
-	var readOps uint64
-	var writeOps uint64
-	// create unbuffered channel
-	reads := make(chan readOp)
-	writes := make(chan writeOp)
+	fmt.Fprintln(w, "container counters: ", con.counters)
 
-	// here is the goroutine that owns the state, which is a map as in the previous example but now private to the stateful goroutine
-	// this goroutine repeatedly selects on the reads and writes channels,responding to requests as they arrive.
-	// a response is executed by first performing the reuqested opertaion and then sending the value on a response channel resp to indicate success
-	go func() {
-		var state = make(map[int]int)
-		for {
-			select {
-			case read := <-reads:
-				read.resp <- state[read.key]
-			case write := <-writes:
-				state[write.key] = write.val
-				write.resp <- true
+	// every inc above contends on the same mutex. counter.Sharded spreads the keys over several maps, each with its own lock
+	tally := counter.New(0)
+	wg.Add(3)
+	for _, name := range []string{"a", "a", "b"} {
+		go func() {
+			for i := 0; i < 10000; i++ {
+				tally.Inc(name)
 			}
-		}
-	}()
-
-	// this starts 100 goroutines to issue reads to the state owning goroutine via the reads channel.
-	//each read requires constructing a readOp,sending it over the reads channel, and then receiving the result over the provided resp channel
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	fmt.Fprintln(w, "sharded counters: ", tally.Snapshot(), "top counter:", tally.TopN(1))
+
+	// here the state is a map as in the previous example but now private to a stateful goroutine.
+	// actor.StateOwner runs that goroutine: it repeatedly selects on its reads and writes channels, responding to requests as they arrive.
+	// the owner stops when the context is done, so this one only lives for the second the readers and writers below run
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	state := actor.New[int, int](ctx)
+
+	// this starts 100 goroutines to issue reads to the state owning goroutine.
+	// each Get sends a read request to the owner and then receives the result on a response channel.
+	// the readers and writers pause on real time, like the context deadline that stops them, rather than on clk
 	for r := 0; r < 100; r++ {
 		go func() {
-			for {
-				read := readOp{
-					key:  rand.Intn(5),
-					resp: make(chan int),
-				}
-				reads <- read
-				<-read.resp
-				atomic.AddUint64(&readOps, 1)
+			for ctx.Err() == nil {
+				state.Get(rand.Intn(5))
 				time.Sleep(time.Millisecond)
 			}
 		}()
 	}
 
-	// we start 10 writes as well ,with a similar approach
-	for w := 0; w < 10; w++ {
+	// we start 10 writers as well ,with a similar approach
+	for range 10 {
 		go func() {
-			for {
-				write := writeOp{
-					key:  rand.Intn(5),
-					val:  rand.Intn(100),
-					resp: make(chan bool)}
-				writes <- write
-				<-write.resp
-				atomic.AddUint64(&writeOps, 1)
+			for ctx.Err() == nil {
+				state.Set(rand.Intn(5), rand.Intn(100))
 				time.Sleep(time.Millisecond)
 			}
 		}()
 	}
-	// let the goroutines work for a second
-	time.Sleep(time.Second)
-
-	// finally, capture and return the op counts
-	readOpsFinal := atomic.LoadUint64(&readOps)
-	fmt.Println("readOps:", readOpsFinal)
-	writeOpsFinal := atomic.LoadUint64(&writeOps)
-	fmt.Println("writeOps:", writeOpsFinal)
+	// Update is a read-modify-write that happens atomically on the owner goroutine
+	state.Update(5, func(v int) int { return v + 1 })
+
+	// let the goroutines work until the context times out and the owner shuts down
+	<-state.Done()
+
+	// finally, capture and return the op counts, the owner counts them itself
+	fmt.Fprintln(w, "readOps:", state.ReadOps())
+	fmt.Fprintln(w, "writeOps:", state.WriteOps())
+	// after shutdown the final state can still be read
+	fmt.Fprintln(w, "final state has", len(state.Snapshot()), "keys")
+	return nil
 }
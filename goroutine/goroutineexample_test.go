@@ -0,0 +1,192 @@
+package goroutineexample
+
+import (
+	"RobotTask/clock"
+	"RobotTask/counter"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// incrementers is the workload of the container example: three goroutines doing 10000 increments each,
+// two of them on the same counter
+var incrementers = []string{"a", "a", "b"}
+
+// runIncrements runs the workload with the given increment function and waits for it to finish
+func runIncrements(inc func(name string)) {
+	var wg sync.WaitGroup
+	for _, name := range incrementers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10000; i++ {
+				inc(name)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// go test -bench=. ./goroutine compares the single mutex Container with the sharded counter
+func BenchmarkContainerInc(b *testing.B) {
+	con := Container{counters: map[string]int{"a": 0, "b": 0}}
+	for i := 0; i < b.N; i++ {
+		runIncrements(con.inc)
+	}
+}
+
+func BenchmarkShardedInc(b *testing.B) {
+	c := counter.New(0)
+	for i := 0; i < b.N; i++ {
+		runIncrements(c.Inc)
+	}
+}
+
+// lockedBuffer collects the output of the examples, which print from several goroutines
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// epoch is where the fake clocks start
+var epoch = time.Date(2009, 11, 17, 20, 34, 58, 0, time.UTC)
+
+// start runs f on a goroutine, the returned channel is closed once f returns
+func start(f func()) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	return done
+}
+
+// waitFor waits until out contains s n times. it polls on real time, but never for long: the examples print right after waking up
+func waitFor(t *testing.T, out *lockedBuffer, s string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for strings.Count(out.String(), s) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d times %q, got:\n%s", n, s, out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// wait waits for the example started with start to return
+func wait(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the example did not return")
+	}
+}
+
+func TestSelectTimeouts(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	var out lockedBuffer
+	done := start(func() { selectTimeouts(&out, clk) })
+
+	clk.BlockUntil(2) // the call sleeping for 2s and the 1s timeout
+	clk.Advance(time.Second)
+	waitFor(t, &out, "timeout 1", 1)
+
+	clk.BlockUntil(3) // the first call is still sleeping, the second call sleeps for 2s and has a 3s timeout
+	clk.Advance(2 * time.Second)
+	wait(t, done)
+
+	if got, want := out.String(), "timeout 1\nresult 2\n"; got != want {
+		t.Errorf("output = %q; want %q", got, want)
+	}
+}
+
+func TestTimers(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	var out lockedBuffer
+	done := start(func() { timers(&out, clk) })
+
+	clk.BlockUntil(1)
+	clk.Advance(2 * time.Second)
+	// timer2 is created and stopped before the example sleeps, wait for that so the sleep is the only waiter
+	waitFor(t, &out, "Timer 2 stopped", 1)
+	clk.BlockUntil(1)
+	clk.Advance(2 * time.Second)
+	wait(t, done)
+
+	if got, want := out.String(), "Timer 1 fired\nTimer 2 stopped\n"; got != want {
+		t.Errorf("output = %q; want %q", got, want)
+	}
+	if clk.Waiters() != 0 {
+		t.Errorf("%d timers are still pending", clk.Waiters())
+	}
+}
+
+func TestTickers(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	var out lockedBuffer
+	done := start(func() { tickers(&out, clk) })
+
+	clk.BlockUntil(2) // the ticker and the 1600ms sleep
+	for i := 1; i <= 3; i++ {
+		clk.Advance(500 * time.Millisecond)
+		waitFor(t, &out, "Tick at", i) // one tick at a time, a tick that isn't received before the next one is dropped
+	}
+	clk.Advance(100 * time.Millisecond)
+	wait(t, done)
+
+	want := fmt.Sprintf("Tick at %v\nTick at %v\nTick at %v\nTicker stopped\n",
+		epoch.Add(500*time.Millisecond), epoch.Add(1000*time.Millisecond), epoch.Add(1500*time.Millisecond))
+	if got := out.String(); got != want {
+		t.Errorf("output = %q; want %q", got, want)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	var out lockedBuffer
+	done := start(func() { rateLimits(context.Background(), &out, clk) })
+
+	// the limiters wait on clk.After, move the clock to the end of every wait until the example returns
+	deadline := time.Now().Add(2 * time.Second)
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			if at, ok := clk.NextDeadline(); ok {
+				clk.Set(at)
+			} else if time.Now().After(deadline) {
+				t.Fatal("the example did not return")
+			} else {
+				time.Sleep(100 * time.Microsecond)
+			}
+		}
+	}
+
+	// one request every 200ms, then a burst of 3 followed by one request every 200ms
+	var want strings.Builder
+	for i, ms := range []int{0, 200, 400, 600, 800, 800, 800, 800, 1000, 1200} {
+		fmt.Fprintln(&want, "request", i%5+1, epoch.Add(time.Duration(ms)*time.Millisecond))
+	}
+	want.WriteString("burst used up, allowed right away: false\n")
+	want.WriteString("client a allowed: true client a allowed again: false client b allowed: true\n")
+	if got := out.String(); got != want.String() {
+		t.Errorf("output =\n%s\nwant\n%s", got, want.String())
+	}
+}
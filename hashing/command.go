@@ -0,0 +1,173 @@
+package hashing
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RunArgs runs the hash command, which works like sha256sum:
+//
+//	oneforall hash [-a=sha256|sha1|blake2b] [-j=workers] [-tree] [files or directories...]
+//	oneforall hash -c [-a=...] [manifests...]
+//
+// it prints a manifest of the files, the directories are hashed whole. without arguments it hashes stdin.
+// with -tree, a directory is printed as a single line with its tree digest instead of a line per file.
+// with -c, it checks the files listed in the manifests and fails if one doesn't match. the relative paths are taken from the
+// current directory like sha256sum does, or from the directory of each manifest with -manifest-dir
+func RunArgs(ctx context.Context, w io.Writer, args []string) error {
+	return run(ctx, w, os.Stdin, args)
+}
+
+// run is RunArgs with stdin as a parameter, for the tests
+func run(ctx context.Context, w io.Writer, stdin io.Reader, args []string) error {
+	fs := flag.NewFlagSet("hash", flag.ContinueOnError)
+	alg := SHA256
+	fs.Func("a", "hash algorithm: sha256, sha1 or blake2b (default sha256)", func(s string) (err error) {
+		alg, err = ParseAlgorithm(s)
+		return err
+	})
+	workers := fs.Int("j", DefaultWorkers, "files hashed in parallel")
+	check := fs.Bool("c", false, "check the files listed in the manifests")
+	manifestDir := fs.Bool("manifest-dir", false, "with -c, the relative paths are from the directory of the manifest instead of the current one")
+	tree := fs.Bool("tree", false, "print a single digest per directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *check {
+		return checkManifests(ctx, w, stdin, fs.Args(), alg, *workers, *manifestDir)
+	}
+	if fs.NArg() == 0 {
+		digest, _, err := Reader(stdin, alg)
+		if err != nil {
+			return err
+		}
+		return WriteManifest(w, []Sum{{Path: "-", Digest: digest}})
+	}
+
+	for _, path := range fs.Args() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			s, err := File(path, alg)
+			if err != nil {
+				return err
+			}
+			if err := WriteManifest(w, []Sum{s}); err != nil {
+				return err
+			}
+			continue
+		}
+		sums, err := Dir(ctx, path, alg, *workers)
+		if err != nil {
+			return err
+		}
+		if *tree {
+			digest, err := Tree(path, sums, alg)
+			if err != nil {
+				return err
+			}
+			sums = []Sum{{Path: path, Digest: digest}}
+		}
+		if err := WriteManifest(w, sums); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkManifests verifies the manifests named, stdin for "-" or if there are none, and prints a line per file
+func checkManifests(ctx context.Context, w io.Writer, stdin io.Reader, names []string, alg Algorithm, workers int, manifestDir bool) error {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	failed, unreadable := 0, 0
+	for _, name := range names {
+		checks, err := checkManifest(ctx, stdin, name, alg, workers, manifestDir)
+		if err != nil {
+			return err
+		}
+		for _, c := range checks {
+			fmt.Fprintln(w, c)
+			switch {
+			case c.Err != nil:
+				unreadable++
+			case !c.OK:
+				failed++
+			}
+		}
+	}
+	var errs []error
+	if unreadable > 0 {
+		errs = append(errs, fmt.Errorf("%d listed files could not be read", unreadable))
+	}
+	if failed > 0 {
+		errs = append(errs, fmt.Errorf("%d computed checksums did NOT match", failed))
+	}
+	return errors.Join(errs...)
+}
+
+// checkManifest verifies the manifest name, stdin for "-". the relative paths are from the current directory,
+// or from the directory of the manifest if manifestDir is set
+func checkManifest(ctx context.Context, stdin io.Reader, name string, alg Algorithm, workers int, manifestDir bool) ([]Check, error) {
+	r, base := stdin, "."
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+		if manifestDir {
+			base = filepath.Dir(name)
+		}
+	}
+	manifest, err := ParseManifest(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return Verify(ctx, manifest, base, alg, workers)
+}
+
+// RunDupesArgs runs the dupes command on the directories named in args, the current directory if there is none:
+//
+//	oneforall dupes [-a=sha256|sha1|blake2b] [-j=workers] [dirs...]
+//
+// it prints the groups of identical files, the ones wasting the most space first
+func RunDupesArgs(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("dupes", flag.ContinueOnError)
+	alg := SHA256
+	fs.Func("a", "hash algorithm: sha256, sha1 or blake2b (default sha256)", func(s string) (err error) {
+		alg, err = ParseAlgorithm(s)
+		return err
+	})
+	workers := fs.Int("j", DefaultWorkers, "files hashed in parallel")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	roots := fs.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	groups, err := FindDupes(ctx, roots, alg, *workers)
+	if err != nil {
+		return err
+	}
+	var wasted int64
+	for _, g := range groups {
+		fmt.Fprintf(w, "%d files of %d bytes, %s %x:\n", len(g.Paths), g.Size, alg, g.Digest)
+		for _, p := range g.Paths {
+			fmt.Fprintln(w, "  "+p)
+		}
+		fmt.Fprintln(w)
+		wasted += g.Wasted()
+	}
+	fmt.Fprintf(w, "%d groups of duplicates, %d bytes wasted\n", len(groups), wasted)
+	return nil
+}
@@ -0,0 +1,95 @@
+package hashing
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"io/fs"
+	"path/filepath"
+	"slices"
+)
+
+// Group is a set of identical files
+type Group struct {
+	Size   int64
+	Digest []byte
+	Paths  []string // sorted
+}
+
+// Wasted returns the space the copies take, all the files of the group but one
+func (g Group) Wasted() int64 {
+	return g.Size * int64(len(g.Paths)-1)
+}
+
+// FindDupes finds the identical regular files under roots.
+// hashing is what takes time, so the files are grouped by size first and only the files sharing their size with another one are hashed,
+// with workers goroutines. empty files are left out, they are all identical. a path under two of the roots is counted once.
+// the groups are sorted by wasted space, largest first
+func FindDupes(ctx context.Context, roots []string, alg Algorithm, workers int) ([]Group, error) {
+	bySize := map[int64][]string{}
+	seen := map[string]bool{}
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() || seen[filepath.Clean(path)] {
+				return nil
+			}
+			seen[filepath.Clean(path)] = true
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.Size() > 0 {
+				bySize[info.Size()] = append(bySize[info.Size()], path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var candidates []string
+	for _, paths := range bySize {
+		if len(paths) > 1 {
+			candidates = append(candidates, paths...)
+		}
+	}
+	sums, err := Files(ctx, candidates, alg, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	// the size is part of the key, a file changed between the walk and the hashing is still grouped right
+	type key struct {
+		size   int64
+		digest string
+	}
+	groups := map[key]*Group{}
+	for _, s := range sums {
+		k := key{s.Size, string(s.Digest)}
+		if groups[k] == nil {
+			groups[k] = &Group{Size: s.Size, Digest: s.Digest}
+		}
+		groups[k].Paths = append(groups[k].Paths, s.Path)
+	}
+	var out []Group
+	for _, g := range groups {
+		if len(g.Paths) > 1 {
+			slices.Sort(g.Paths)
+			out = append(out, *g)
+		}
+	}
+	slices.SortFunc(out, func(a, b Group) int {
+		if c := cmp.Compare(b.Wasted(), a.Wasted()); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.Digest, b.Digest)
+	})
+	return out, nil
+}
@@ -0,0 +1,157 @@
+// Package hashing hashes real files, where the sha256 example of the helper package hashes a string in memory.
+// files are streamed through the hash instead of being read whole, directories are walked and their files hashed
+// in parallel by a worker pool. the sums are written and verified in the format of sha256sum,
+// and FindDupes finds the identical files of a tree by grouping them by size, then by hash
+package hashing
+
+import (
+	"RobotTask/workerpool"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/blake2b"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Algorithm is a hash function, by the name used on the command line
+type Algorithm string
+
+const (
+	SHA256  Algorithm = "sha256"
+	SHA1    Algorithm = "sha1"    // broken for security, still fine to find duplicates or check downloads against a sha1sum manifest
+	BLAKE2b Algorithm = "blake2b" // the 256 bits variant, faster than sha256 without hardware support for it
+)
+
+// Algorithms are the supported algorithms
+var Algorithms = []Algorithm{SHA256, SHA1, BLAKE2b}
+
+// ParseAlgorithm returns the algorithm named s
+func ParseAlgorithm(s string) (Algorithm, error) {
+	if a := Algorithm(s); slices.Contains(Algorithms, a) {
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown hash algorithm %q, want one of %v", s, Algorithms)
+}
+
+// New returns a new hash.Hash computing the algorithm
+func (a Algorithm) New() (hash.Hash, error) {
+	switch a {
+	case SHA256:
+		return sha256.New(), nil
+	case SHA1:
+		return sha1.New(), nil
+	case BLAKE2b:
+		return blake2b.New256(nil)
+	}
+	return nil, fmt.Errorf("unknown hash algorithm %q", string(a))
+}
+
+// DefaultWorkers is the number of files hashed at the same time when the workers aren't given
+var DefaultWorkers = runtime.NumCPU()
+
+// Sum is the digest of a file
+type Sum struct {
+	Path   string
+	Size   int64
+	Digest []byte
+}
+
+// Hex returns the digest in hexadecimal, like sha256sum prints it
+func (s Sum) Hex() string {
+	return hex.EncodeToString(s.Digest)
+}
+
+// Reader hashes what is read from r, without keeping it in memory, and returns the digest and the number of bytes read
+func Reader(r io.Reader, alg Algorithm) ([]byte, int64, error) {
+	h, err := alg.New()
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return nil, n, err
+	}
+	return h.Sum(nil), n, nil
+}
+
+// File hashes the file at path
+func File(path string, alg Algorithm) (Sum, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Sum{}, err
+	}
+	defer f.Close()
+	digest, n, err := Reader(f, alg)
+	if err != nil {
+		return Sum{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return Sum{Path: path, Size: n, Digest: digest}, nil
+}
+
+// Files hashes the files at paths with workers goroutines, the sums are in the order of paths.
+// it stops at the first error, workers less than 1 means DefaultWorkers
+func Files(ctx context.Context, paths []string, alg Algorithm, workers int) ([]Sum, error) {
+	if _, err := alg.New(); err != nil {
+		return nil, err // fail once, not for every file
+	}
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	return workerpool.Map(ctx, workers, paths, func(ctx context.Context, path string) (Sum, error) {
+		return File(path, alg)
+	})
+}
+
+// Dir hashes the regular files under root, in the lexical order of their paths. symbolic links aren't followed
+func Dir(ctx context.Context, root string, alg Algorithm, workers int) ([]Sum, error) {
+	paths, err := regularFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	return Files(ctx, paths, alg, workers)
+}
+
+// Tree returns a digest of the whole of sums, which changes if a file is added, removed, renamed or changed:
+// the hash of the sums written as a manifest, with the paths relative to root and sorted.
+// two copies of a directory have the same tree digest wherever they are
+func Tree(root string, sums []Sum, alg Algorithm) ([]byte, error) {
+	h, err := alg.New()
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(sums))
+	for _, s := range sums {
+		rel, err := filepath.Rel(root, s.Path)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, manifestLine(s.Hex(), filepath.ToSlash(rel)))
+	}
+	slices.Sort(lines)
+	io.WriteString(h, strings.Join(lines, ""))
+	return h.Sum(nil), nil
+}
+
+// regularFiles returns the paths of the regular files under root, root itself if it is a file
+func regularFiles(root string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
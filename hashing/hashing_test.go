@@ -0,0 +1,251 @@
+package hashing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// mkFiles writes files, by path relative to a new temporary directory, and returns the directory
+func mkFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestAlgorithms(t *testing.T) {
+	var tests = []struct {
+		alg  Algorithm
+		want string
+	}{
+		{SHA256, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{SHA1, "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{BLAKE2b, "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+	}
+	for _, tt := range tests {
+		digest, n, err := Reader(strings.NewReader("abc"), tt.alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(digest) != tt.want || n != 3 {
+			t.Errorf("%s(abc) = %x, %d bytes; want %s", tt.alg, digest, n, tt.want)
+		}
+	}
+	if _, err := ParseAlgorithm("md5"); err == nil {
+		t.Error("md5 accepted")
+	}
+	if _, err := Files(context.Background(), nil, "md5", 1); err == nil {
+		t.Error("Files accepted md5")
+	}
+}
+
+func TestDirAndTree(t *testing.T) {
+	files := map[string]string{"b.txt": "bee", "a/1.txt": "one", "a/2.txt": "two", "c/d/e.txt": "deep"}
+	dir := mkFiles(t, files)
+	sums, err := Dir(context.Background(), dir, SHA256, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range sums {
+		rel, _ := filepath.Rel(dir, s.Path)
+		got = append(got, filepath.ToSlash(rel))
+		want, _, _ := Reader(strings.NewReader(files[filepath.ToSlash(rel)]), SHA256)
+		if !bytes.Equal(s.Digest, want) || s.Size != int64(len(files[filepath.ToSlash(rel)])) {
+			t.Errorf("%s: %x, %d bytes", rel, s.Digest, s.Size)
+		}
+	}
+	if want := []string{"a/1.txt", "a/2.txt", "b.txt", "c/d/e.txt"}; !slices.Equal(got, want) {
+		t.Errorf("paths = %v; want %v", got, want)
+	}
+
+	// a copy elsewhere has the same tree digest, a renamed file changes it
+	tree := func(files map[string]string) string {
+		dir := mkFiles(t, files)
+		sums, err := Dir(context.Background(), dir, BLAKE2b, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := Tree(dir, sums, BLAKE2b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(d)
+	}
+	first := tree(files)
+	if second := tree(files); second != first {
+		t.Errorf("copies differ: %s and %s", first, second)
+	}
+	files["a/3.txt"] = files["a/2.txt"]
+	delete(files, "a/2.txt")
+	if renamed := tree(files); renamed == first {
+		t.Error("renaming a file didn't change the tree digest")
+	}
+}
+
+func TestManifest(t *testing.T) {
+	sums := []Sum{
+		{Path: "plain.txt", Digest: []byte{0xab, 0xcd}},
+		{Path: "with space.txt", Digest: []byte{0x01}},
+		{Path: `back\slash` + "\nnewline", Digest: []byte{0x02}},
+	}
+	var buf bytes.Buffer
+	if err := WriteManifest(&buf, sums); err != nil {
+		t.Fatal(err)
+	}
+	want := "abcd  plain.txt\n01  with space.txt\n\\02  back\\\\slash\\nnewline\n"
+	if buf.String() != want {
+		t.Errorf("manifest = %q; want %q", buf.String(), want)
+	}
+	// binary mode lines, comments and blank lines are read too
+	buf.WriteString("\n# comment\n03 *binary.bin\n")
+	entries, err := ParseManifest(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("entries = %v", entries)
+	}
+	for i, s := range sums {
+		if entries[i].Path != s.Path || !bytes.Equal(entries[i].Digest, s.Digest) {
+			t.Errorf("entry %d = %+v; want %+v", i, entries[i], s)
+		}
+	}
+	if entries[3].Path != "binary.bin" {
+		t.Errorf("binary entry = %+v", entries[3])
+	}
+
+	for _, bad := range []string{"abcd plain.txt", "zz  file", "abcd  ", "justonefield"} {
+		if _, err := ParseManifest(strings.NewReader("01  ok\n" + bad + "\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("%q: err = %v; want an error on line 2", bad, err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := mkFiles(t, map[string]string{"same.txt": "same", "changed.txt": "before"})
+	sums, err := Dir(context.Background(), dir, SHA1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest []ManifestEntry
+	for _, s := range sums {
+		manifest = append(manifest, ManifestEntry{Digest: s.Digest, Path: filepath.Base(s.Path)})
+	}
+	manifest = append(manifest, ManifestEntry{Digest: []byte{1}, Path: "missing.txt"})
+	os.WriteFile(filepath.Join(dir, "changed.txt"), []byte("after"), 0644)
+
+	checks, err := Verify(context.Background(), manifest, dir, SHA1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range checks {
+		got = append(got, c.String())
+	}
+	want := []string{"changed.txt: FAILED", "same.txt: OK", "missing.txt: FAILED open or read"}
+	if !slices.Equal(got, want) {
+		t.Errorf("checks = %q; want %q", got, want)
+	}
+}
+
+func TestFindDupes(t *testing.T) {
+	dir := mkFiles(t, map[string]string{
+		"a/big1": "0123456789", "b/big2": "0123456789", "c/big3": "0123456789",
+		"a/small1": "xy", "b/small2": "xy",
+		"a/samesize": "zz", // same size as the small ones, different content
+		"a/unique":   "unique",
+		"a/empty1":   "", "b/empty2": "",
+	})
+	// a is walked twice, its files must not be counted twice
+	groups, err := FindDupes(context.Background(), []string{dir, filepath.Join(dir, "a")}, SHA256, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("groups = %+v; want 2", groups)
+	}
+	rel := func(g Group) []string {
+		var out []string
+		for _, p := range g.Paths {
+			r, _ := filepath.Rel(dir, p)
+			out = append(out, filepath.ToSlash(r))
+		}
+		return out
+	}
+	if got := rel(groups[0]); !slices.Equal(got, []string{"a/big1", "b/big2", "c/big3"}) || groups[0].Wasted() != 20 {
+		t.Errorf("first group = %v, %d wasted", got, groups[0].Wasted())
+	}
+	if got := rel(groups[1]); !slices.Equal(got, []string{"a/small1", "b/small2"}) {
+		t.Errorf("second group = %v", got)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := mkFiles(t, map[string]string{"x": "hello\n", "sub/y": "hello\n"})
+	var out bytes.Buffer
+	if err := run(context.Background(), &out, nil, []string{filepath.Join(dir, "x"), filepath.Join(dir, "sub")}); err != nil {
+		t.Fatal(err)
+	}
+	const hello = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	if want := hello + "  " + filepath.Join(dir, "x") + "\n" + hello + "  " + filepath.Join(dir, "sub", "y") + "\n"; out.String() != want {
+		t.Errorf("hash = %q; want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := run(context.Background(), &out, strings.NewReader("hello\n"), nil); err != nil || out.String() != hello+"  -\n" {
+		t.Errorf("stdin = %q, %v", out.String(), err)
+	}
+
+	// check a manifest with relative paths: from the current directory like sha256sum, or from the one of the manifest
+	os.WriteFile(filepath.Join(dir, "sums"), []byte(hello+"  x\n"+hello+"  sub/y\n"), 0644)
+	out.Reset()
+	if err := run(context.Background(), &out, nil, []string{"-c", "-manifest-dir", filepath.Join(dir, "sums")}); err != nil {
+		t.Fatalf("check: %v\n%s", err, out.String())
+	}
+	out.Reset()
+	err := run(context.Background(), &out, nil, []string{"-c", filepath.Join(dir, "sums")})
+	if err == nil || !strings.Contains(err.Error(), "2 listed files could not be read") {
+		t.Errorf("check from another directory = %q, %v", out.String(), err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	out.Reset()
+	if err := run(context.Background(), &out, nil, []string{"-c", "sums"}); err != nil {
+		t.Fatalf("check from the directory of the files: %v\n%s", err, out.String())
+	}
+	os.WriteFile(filepath.Join(dir, "x"), []byte("changed"), 0644)
+	out.Reset()
+	err = run(context.Background(), &out, nil, []string{"-c", filepath.Join(dir, "sums")})
+	if err == nil || !strings.Contains(err.Error(), "1 computed checksums did NOT match") || !strings.Contains(out.String(), "x: FAILED\n") {
+		t.Errorf("check after a change = %q, %v", out.String(), err)
+	}
+
+	out.Reset()
+	if err := RunDupesArgs(context.Background(), &out, []string{"-a=blake2b", dir}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "0 groups of duplicates, 0 bytes wasted\n") {
+		t.Errorf("dupes = %q", out.String())
+	}
+}
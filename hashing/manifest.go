@@ -0,0 +1,134 @@
+package hashing
+
+import (
+	"RobotTask/workerpool"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// a manifest is what sha256sum prints and checks with -c, a line per file:
+//
+//	<hex digest>  <path>
+//
+// two spaces for a file read as text, a space and a star for binary, which is the same on unix.
+// a path with a backslash or a newline has them escaped as \\ and \n, and the line starts with a backslash
+
+// ManifestEntry is a line of a manifest
+type ManifestEntry struct {
+	Digest []byte
+	Path   string
+}
+
+// WriteManifest writes sums in the format of sha256sum, with the paths as they are in the sums
+func WriteManifest(w io.Writer, sums []Sum) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range sums {
+		bw.WriteString(manifestLine(s.Hex(), s.Path))
+	}
+	return bw.Flush()
+}
+
+// manifestLine formats a line of a manifest, escaping path like sha256sum
+func manifestLine(digest, path string) string {
+	if strings.ContainsAny(path, "\\\n") {
+		path = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(path)
+		return `\` + digest + "  " + path + "\n"
+	}
+	return digest + "  " + path + "\n"
+}
+
+// ParseManifest reads a manifest, empty lines and lines starting with # are skipped.
+// a malformed line is an error, naming the line
+func ParseManifest(r io.Reader) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		escaped := strings.HasPrefix(line, `\`)
+		if escaped {
+			line = line[1:]
+		}
+		digest, path, ok := strings.Cut(line, " ")
+		if ok && (strings.HasPrefix(path, " ") || strings.HasPrefix(path, "*")) {
+			path = path[1:]
+		} else {
+			ok = false
+		}
+		sum, err := hex.DecodeString(digest)
+		if !ok || err != nil || len(sum) == 0 || path == "" {
+			return nil, fmt.Errorf("manifest line %d: want <hex digest>  <path>, got %q", n, sc.Text())
+		}
+		if escaped {
+			path = unescape(path)
+		}
+		entries = append(entries, ManifestEntry{Digest: sum, Path: path})
+	}
+	return entries, sc.Err()
+}
+
+// unescape undoes the escaping of manifestLine
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// Check is the result of verifying a file against its manifest entry
+type Check struct {
+	Path string
+	OK   bool
+	Err  error // the file couldn't be read
+}
+
+// String formats the check like sha256sum -c
+func (c Check) String() string {
+	switch {
+	case c.Err != nil:
+		return c.Path + ": FAILED open or read"
+	case !c.OK:
+		return c.Path + ": FAILED"
+	}
+	return c.Path + ": OK"
+}
+
+// Verify hashes the files of the manifest with workers goroutines and compares them with their digests.
+// relative paths are relative to base, "." like sha256sum -c does.
+// a missing or changed file is a failed check, not an error, the checks are in the order of the manifest.
+// the error is only ctx's error
+func Verify(ctx context.Context, manifest []ManifestEntry, base string, alg Algorithm, workers int) ([]Check, error) {
+	if _, err := alg.New(); err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	return workerpool.Map(ctx, workers, manifest, func(ctx context.Context, e ManifestEntry) (Check, error) {
+		path := e.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(base, path)
+		}
+		s, err := File(path, alg)
+		if err != nil {
+			return Check{Path: e.Path, Err: err}, nil
+		}
+		return Check{Path: e.Path, OK: bytes.Equal(s.Digest, e.Digest)}, nil
+	})
+}
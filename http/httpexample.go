@@ -1,15 +1,24 @@
-package main
+// Package httpexample runs an http server with a few handlers, the plant api and middlewares,
+// and a client that calls it
+package httpexample
 
 import (
+	"RobotTask/helper"
+	"RobotTask/httpserver"
+	"RobotTask/logging"
+	"RobotTask/middleware"
+	"RobotTask/plantapi"
 	"bufio"
+	stdcontext "context" // the context handler below already uses the name context
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
 )
 
-// create a buffering channel to make sure server closes after getting the end handler
-var done = make(chan bool, 1)
-
 /************************* server functions ********************************/
 
 // a handler is an object implementing the http.Handler interface.
@@ -29,54 +38,68 @@ func headers(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
-// this handler performs a
-func context(w http.ResponseWriter, req *http.Request) {
-	// a context.Context is created for each request by the net/http machinery,
-	// and is available with the Context() method
-	ctx := req.Context() // get the context from the request
-	fmt.Println("server: hello handler started")
-
-	defer fmt.Println("server: hello handler ended") // output that the handler has ended
-
-	select {
-	// wait for a few seconds before sending a reply to the client. this could simulate some work the server is doing.
-	case <-time.After(10 * time.Second):
-		// if everything is fine and the connection is still stable after 10 seconds, the server will send hello to the client
-		fmt.Fprintf(w, "hello\n")
-	// while working, keep an eye on the context's Done() channel for a signal that we should cancel the work and return asap
-	case <-ctx.Done():
-		// the context's Err()method returns an error that explains why the Done() channel was closed
-		err := ctx.Err()
-		fmt.Println("server error: ", err) // stop the connection to simulate this error
-		// write the error to w, note that the client might not catch this error,
-		// since it could be the client that prematurely closed the connection
-		internalError := http.StatusInternalServerError
-		http.Error(w, err.Error(), internalError)
+// this handler performs a long task that can be cancelled, the server side messages are logged with logger.
+// the request context carries the request id, so the records of one request can be told apart
+func context(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// a context.Context is created for each request by the net/http machinery,
+		// and is available with the Context() method
+		ctx := req.Context() // get the context from the request
+		logger.InfoContext(ctx, "server: hello handler started")
+
+		defer logger.InfoContext(ctx, "server: hello handler ended") // log that the handler has ended
+
+		select {
+		// wait for a few seconds before sending a reply to the client. this could simulate some work the server is doing.
+		case <-time.After(10 * time.Second):
+			// if everything is fine and the connection is still stable after 10 seconds, the server will send hello to the client
+			fmt.Fprintf(w, "hello\n")
+		// while working, keep an eye on the context's Done() channel for a signal that we should cancel the work and return asap
+		case <-ctx.Done():
+			// the context's Err()method returns an error that explains why the Done() channel was closed
+			err := ctx.Err()
+			logger.ErrorContext(ctx, "server error", "err", err) // stop the connection to simulate this error
+			// write the error to w, note that the client might not catch this error,
+			// since it could be the client that prematurely closed the connection
+			internalError := http.StatusInternalServerError
+			http.Error(w, err.Error(), internalError)
+		}
 	}
 }
 
-// this handler will terminate the program
-func end(w http.ResponseWriter, req *http.Request) {
-	fmt.Fprintf(w, "goodbye\n") // a simple hello as response, writes to w
-	done <- true                // signal done channel to end the program
+// this handler panics, the recover middleware turns the panic into a 500 response
+func panics(w http.ResponseWriter, req *http.Request) {
+	panic("a problem")
+}
+
+// this handler will terminate the program, done should be a buffering channel
+// to make sure the server closes after getting the end handler
+func end(done chan<- bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "goodbye\n") // a simple hello as response, writes to w
+		select {
+		case done <- true: // signal done channel to end the program
+		default: // the server is already stopping
+		}
+	}
 }
 
 /************************* client functions ********************************/
 
-func ShowHttpClientExample() {
+func ShowHttpClientExample(w io.Writer, baseURL string) {
 	// issue an http get request to our own server
-	resp, err := http.Get("http://localhost:8090/headers")
+	resp, err := http.Get(baseURL + "/headers")
 	if err != nil {
 		panic(err) // yse panic to fail on errors that shouldn't occur during normal operation
 	}
 	// use defer to make sure the response is closed at the end of the program
 	defer resp.Body.Close()
 	// print response status
-	fmt.Println("Response status:", resp.Status)
+	fmt.Fprintln(w, "Response status:", resp.Status)
 	// use scanner to read the first 5 lines of the response body
 	scanner := bufio.NewScanner(resp.Body)
 	for i := 0; scanner.Scan() && i < 5; i++ {
-		fmt.Println(scanner.Text())
+		fmt.Fprintln(w, scanner.Text())
 	}
 	// if there is an error with scanner, then panic
 	if err := scanner.Err(); err != nil {
@@ -85,21 +108,84 @@ func ShowHttpClientExample() {
 
 }
 
-// will block the thread
-func main() {
+// Run runs the server with the default settings until ctx is done,
+// the process receives SIGINT or SIGTERM, or the /end handler is called
+func Run(ctx stdcontext.Context, w io.Writer) error {
+	return RunArgs(ctx, w, nil)
+}
+
+// RunArgs is Run with the settings parsed from command line arguments,
+// run with "oneforall http -addr=:8091 -shutdown-timeout=5s" to change the defaults
+func RunArgs(ctx stdcontext.Context, w io.Writer, args []string) error {
+	// the address and timeouts of the server can be set from the command line
+	cfg := httpserver.DefaultConfig()
+	fs := flag.NewFlagSet("http", flag.ContinueOnError)
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "address to listen on")
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "maximum duration for reading a request")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "maximum duration for writing a response")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "how long keep-alive connections stay open")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "how long in-flight requests get to finish on shutdown")
+	contextTimeout := fs.Duration("context-timeout", 12*time.Second, "timeout of the /context handler, lower than 10s cuts it off")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// every handler is wrapped by the same middlewares: the first one in the chain runs first.
+	// the request id is set before the access log runs, and panics are recovered inside it so the 500 gets logged.
+	// the logger is the one the launcher configured, oneforall -log-format=json http logs json
+	logger := logging.FromContext(ctx)
+	common := middleware.Chain(
+		middleware.RequestID(),
+		middleware.AccessLog(logger),
+		middleware.Recover(logger),
+	)
+	// a handler specific middleware, such as a timeout, is chained after the common ones
+	withTimeout := func(d time.Duration) middleware.Middleware {
+		return middleware.Chain(common, middleware.Timeout(d))
+	}
+
+	// create a buffering channel to make sure server closes after getting the end handler
+	done := make(chan bool, 1)
 
-	// start a goroutine for the server, so that it does not block the client
+	mux := http.NewServeMux()
+	mux.Handle("/hello", withTimeout(time.Second)(http.HandlerFunc(hello)))
+	mux.Handle("/headers", withTimeout(time.Second)(http.HandlerFunc(headers)))
+	mux.Handle("/context", withTimeout(*contextTimeout)(context(logger)))
+	mux.Handle("/panic", common(http.HandlerFunc(panics)))
+	mux.Handle("/end", common(end(done)))
+
+	// the plant resource has its own mux with method and wildcard patterns, it is mounted on both /plants and /plants/
+	plants := common(plantapi.NewHandler(plantapi.NewStore(helper.Plant{Id: 27, Name: "Coffee", Origin: []string{"Ethiopia", "Brazil"}})))
+	mux.Handle("/plants", plants)
+	mux.Handle("/plants/", plants)
+
+	// binding the address first means a port that is already in use is reported here instead of being ignored
+	srv, err := httpserver.Listen(cfg, mux)
+	if err != nil {
+		// the launcher exits with a non-zero code, which tells the caller that the server never came up
+		return fmt.Errorf("server failed to start: %w", err)
+	}
+	logger.Info("server started", "addr", srv.Addr().String())
+
+	// ctrl+c (SIGINT) or SIGTERM cancels ctx, so does the end handler through the done channel
+	ctx, stop := httpserver.NotifyShutdown(ctx)
+	defer stop()
 	go func() {
-		http.HandleFunc("/hello", hello)
-		http.HandleFunc("/headers", headers)
-		http.HandleFunc("/context", context)
-		http.HandleFunc("/end", end)
-		http.ListenAndServe(":8090", nil)
+		select {
+		case <-done:
+			stop()
+		case <-ctx.Done():
+		}
 	}()
-	fmt.Println("server started on port 8090")
-	// since the server is running on a goroutine, the client will run and send the get to the server
-	ShowHttpClientExample()
-	// this blocks the main process and wait, will terminate when receives the end handler
-	<-done
 
+	// the client runs on a goroutine, so that it does not block the server
+	_, port, _ := net.SplitHostPort(srv.Addr().String())
+	go ShowHttpClientExample(w, "http://localhost:"+port)
+
+	// this blocks until ctx is cancelled, then waits for in-flight requests such as /context to finish
+	if err := srv.Serve(ctx); err != nil {
+		return err
+	}
+	logger.Info("server stopped")
+	return nil
 }
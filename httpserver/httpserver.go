@@ -0,0 +1,142 @@
+// Package httpserver runs an http.Server the way a long running service should:
+// the address and timeouts are configurable, startup errors are reported instead of being ignored,
+// and SIGINT/SIGTERM trigger a graceful shutdown that gives in-flight requests a deadline to finish.
+package httpserver
+
+import (
+	"RobotTask/logging"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Config holds the settings of the server
+type Config struct {
+	Addr              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration // must be longer than the slowest handler, or its response is cut off
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration // how long in-flight requests get to finish once shutdown starts
+}
+
+// DefaultConfig returns the settings used by the http example.
+// the write timeout leaves room for the 10 second /context handler
+func DefaultConfig() Config {
+	return Config{
+		Addr:              ":8090",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		ShutdownTimeout:   15 * time.Second,
+	}
+}
+
+// Server is an http.Server with its listener already bound
+type Server struct {
+	HTTP *http.Server
+	cfg  Config
+	ln   net.Listener
+
+	// handlerCtx is the parent of every request context, it is cancelled when the drain deadline passes
+	// so that handlers still running notice it on ctx.Done()
+	handlerCtx    context.Context
+	cancelHandler context.CancelFunc
+}
+
+// Listen binds the address from cfg and prepares a server for handler.
+// binding up front means errors such as an address already in use are returned here, before any request is served
+func Listen(cfg Config, handler http.Handler) (*Server, error) {
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("httpserver: listen on %s: %w", cfg.Addr, err)
+	}
+	handlerCtx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		cfg:           cfg,
+		ln:            ln,
+		handlerCtx:    handlerCtx,
+		cancelHandler: cancel,
+	}
+	s.HTTP = &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		BaseContext:       func(net.Listener) context.Context { return handlerCtx },
+	}
+	return s, nil
+}
+
+// Addr returns the address the server is listening on, useful when cfg.Addr used port 0
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Serve handles requests until ctx is done, then shuts down gracefully:
+// no new connections are accepted and in-flight requests get cfg.ShutdownTimeout to finish.
+// requests still running after that have their context cancelled and their connections closed.
+// it returns nil after a clean shutdown
+func (s *Server) Serve(ctx context.Context) error {
+	defer s.cancelHandler()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.HTTP.Serve(s.ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		// Serve only returns early if something went wrong with the listener
+		return fmt.Errorf("httpserver: serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	err := s.HTTP.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// the drain deadline passed, tell the remaining handlers to give up and drop their connections
+		s.cancelHandler()
+		s.HTTP.Close()
+		return fmt.Errorf("httpserver: in-flight requests did not finish within %v", s.cfg.ShutdownTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("httpserver: shutdown: %w", err)
+	}
+	// after Shutdown, Serve returns http.ErrServerClosed, which is the expected outcome
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("httpserver: serve: %w", err)
+	}
+	return nil
+}
+
+// NotifyShutdown returns a copy of ctx that is cancelled when the process receives SIGINT or SIGTERM.
+// like the signal example it registers a buffered channel with signal.Notify and waits on it in a goroutine.
+// stop unregisters the signals and releases the goroutine, it should be deferred by the caller
+func NotifyShutdown(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigs:
+			logging.FromContext(ctx).Info("shutting down", "signal", sig.String())
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	stop := func() {
+		signal.Stop(sigs)
+		cancel()
+	}
+	return ctx, stop
+}
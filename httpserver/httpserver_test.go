@@ -0,0 +1,124 @@
+package httpserver
+
+import (
+	"RobotTask/logging"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testConfig listens on a random local port with a short drain deadline
+func testConfig(shutdown time.Duration) Config {
+	cfg := DefaultConfig()
+	cfg.Addr = "127.0.0.1:0"
+	cfg.ShutdownTimeout = shutdown
+	return cfg
+}
+
+// slowHandler is a shorter version of the /context handler: it answers after delay unless its context is cancelled
+func slowHandler(delay time.Duration, started chan<- struct{}, cancelled chan<- struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-time.After(delay):
+			io.WriteString(w, "hello\n")
+		case <-req.Context().Done():
+			cancelled <- struct{}{}
+		}
+	})
+}
+
+func TestListenReportsAddressInUse(t *testing.T) {
+	first, err := Listen(testConfig(time.Second), http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer first.ln.Close()
+
+	cfg := testConfig(time.Second)
+	cfg.Addr = first.Addr().String()
+	if _, err := Listen(cfg, http.NotFoundHandler()); err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Errorf("second Listen on %s = %v; want an address in use error", cfg.Addr, err)
+	}
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{}, 1)
+	cancelled := make(chan struct{}, 1)
+	srv, err := Listen(testConfig(5*time.Second), slowHandler(200*time.Millisecond, started, cancelled))
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ctx) }()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + srv.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+
+	<-started
+	cancel() // shut down while the request is in flight
+	if err := <-served; err != nil {
+		t.Errorf("Serve = %v; want a clean shutdown", err)
+	}
+	if got := <-body; got != "hello\n" {
+		t.Errorf("in-flight request got %q; want it to finish", got)
+	}
+}
+
+func TestShutdownDeadlineCancelsHandlers(t *testing.T) {
+	started := make(chan struct{}, 1)
+	cancelled := make(chan struct{}, 1)
+	srv, err := Listen(testConfig(100*time.Millisecond), slowHandler(time.Minute, started, cancelled))
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ctx) }()
+	go http.Get("http://" + srv.Addr().String())
+
+	<-started
+	cancel()
+	if err := <-served; err == nil {
+		t.Error("Serve = nil; want an error once the drain deadline passed")
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("the handler context was not cancelled after the drain deadline")
+	}
+}
+
+func TestNotifyShutdownLogs(t *testing.T) {
+	rec := logging.NewRecorder(slog.LevelDebug)
+	ctx, stop := NotifyShutdown(logging.NewContext(context.Background(), slog.New(rec)))
+	defer stop()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGTERM didn't cancel the context")
+	}
+	r, ok := rec.Find("shutting down")
+	if !ok || logging.AttrsOf(r)["signal"].String() != syscall.SIGTERM.String() {
+		t.Errorf("logged %q", rec.Messages())
+	}
+}
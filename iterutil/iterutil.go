@@ -0,0 +1,179 @@
+// Package iterutil contains combinators for composing iter.Seq iterators,
+// such as the ones returned by List.All or genFib in the main package.
+// every combinator is lazy: nothing is pulled from the source until the result is ranged over,
+// and once the consumer stops (yield returns false) the source is stopped as well.
+package iterutil
+
+import "iter"
+
+// Map returns an iterator that yields f(v) for every v in seq
+func Map[T, U any](seq iter.Seq[T], f func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			if !yield(f(v)) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns an iterator that only yields the values of seq for which keep returns true
+func Filter[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if keep(v) && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Take returns an iterator over the first n values of seq.
+// seq is never asked for more than n values, so Take is safe to use on infinite iterators
+func Take[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			i++
+			if i == n {
+				return
+			}
+		}
+	}
+}
+
+// TakeWhile returns an iterator over the values of seq up to, not including, the first one
+// for which keep returns false
+func TakeWhile[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if !keep(v) || !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Skip returns an iterator over the values of seq after the first n
+func Skip[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		i := 0
+		for v := range seq {
+			if i < n {
+				i++
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Zip returns an iterator over pairs taken from a and b in lockstep.
+// it stops as soon as either of them is exhausted
+func Zip[A, B any](a iter.Seq[A], b iter.Seq[B]) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		// a is ranged over directly, b is turned into a pull iterator so we can ask it for one value at a time
+		next, stop := iter.Pull(b)
+		defer stop()
+		for va := range a {
+			vb, ok := next()
+			if !ok || !yield(va, vb) {
+				return
+			}
+		}
+	}
+}
+
+// Chain returns an iterator that yields all values of each seq one after the other
+func Chain[T any](seqs ...iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, seq := range seqs {
+			for v := range seq {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Enumerate returns an iterator over the values of seq paired with their index, starting at 0
+func Enumerate[T any](seq iter.Seq[T]) iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		i := 0
+		for v := range seq {
+			if !yield(i, v) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Reduce folds seq into a single value, starting from init and applying f to every value in order.
+// seq must be finite, otherwise Reduce never returns
+func Reduce[T, U any](seq iter.Seq[T], init U, f func(U, T) U) U {
+	acc := init
+	for v := range seq {
+		acc = f(acc, v)
+	}
+	return acc
+}
+
+// Chunk returns an iterator over consecutive slices of up to n values of seq.
+// all chunks have n values except possibly the last one. Chunk panics if n is less than 1
+func Chunk[T any](seq iter.Seq[T], n int) iter.Seq[[]T] {
+	if n < 1 {
+		panic("iterutil: Chunk size must be at least 1")
+	}
+	return func(yield func([]T) bool) {
+		chunk := make([]T, 0, n)
+		for v := range seq {
+			chunk = append(chunk, v)
+			if len(chunk) == n {
+				if !yield(chunk) {
+					return
+				}
+				// allocate a new chunk so the caller can keep the one it was given
+				chunk = make([]T, 0, n)
+			}
+		}
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// Window returns an iterator over every run of n consecutive values of seq, sliding by one value.
+// nothing is yielded if seq has fewer than n values. Window panics if n is less than 1
+func Window[T any](seq iter.Seq[T], n int) iter.Seq[[]T] {
+	if n < 1 {
+		panic("iterutil: Window size must be at least 1")
+	}
+	return func(yield func([]T) bool) {
+		buf := make([]T, 0, n)
+		for v := range seq {
+			if len(buf) == n {
+				buf = buf[1:]
+			}
+			buf = append(buf, v)
+			if len(buf) < n {
+				continue
+			}
+			// every window is a copy, otherwise the next append would overwrite what the caller holds
+			window := make([]T, n)
+			copy(window, buf)
+			if !yield(window) {
+				return
+			}
+		}
+	}
+}
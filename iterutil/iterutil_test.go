@@ -0,0 +1,201 @@
+package iterutil
+
+import (
+	"iter"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// naturals is an infinite iterator over 0, 1, 2, ...
+// pulled counts how many values were handed out so tests can check that nothing is over-read
+func naturals(pulled *int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			*pulled++
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func isEven(n int) bool { return n%2 == 0 }
+
+func TestCombinators(t *testing.T) {
+	src := slices.Values([]int{1, 2, 3, 4, 5})
+	var tests = []struct {
+		name string
+		seq  iter.Seq[int]
+		want []int
+	}{
+		{"Map", Map(src, func(n int) int { return n * n }), []int{1, 4, 9, 16, 25}},
+		{"Filter", Filter(src, isEven), []int{2, 4}},
+		{"Take", Take(src, 2), []int{1, 2}},
+		{"TakeZero", Take(src, 0), nil},
+		{"TakeMore", Take(src, 10), []int{1, 2, 3, 4, 5}},
+		{"TakeWhile", TakeWhile(src, func(n int) bool { return n < 4 }), []int{1, 2, 3}},
+		{"Skip", Skip(src, 3), []int{4, 5}},
+		{"SkipAll", Skip(src, 10), nil},
+		{"Chain", Chain(src, slices.Values([]int{6}), src), []int{1, 2, 3, 4, 5, 6, 1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slices.Collect(tt.seq); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTakeInfinite(t *testing.T) {
+	pulled := 0
+	got := slices.Collect(Take(naturals(&pulled), 3))
+	if !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("Take(naturals, 3) = %v", got)
+	}
+	// Take must stop the source right after the last value instead of reading one more
+	if pulled != 3 {
+		t.Errorf("source was pulled %d times; want 3", pulled)
+	}
+}
+
+// every combinator that yields values must stop its source when the consumer breaks out of the loop
+func TestEarlyTermination(t *testing.T) {
+	var tests = []struct {
+		name string
+		seq  func(src iter.Seq[int]) iter.Seq[int]
+	}{
+		{"Map", func(src iter.Seq[int]) iter.Seq[int] { return Map(src, func(n int) int { return n }) }},
+		{"Filter", func(src iter.Seq[int]) iter.Seq[int] { return Filter(src, isEven) }},
+		{"Take", func(src iter.Seq[int]) iter.Seq[int] { return Take(src, 100) }},
+		{"TakeWhile", func(src iter.Seq[int]) iter.Seq[int] { return TakeWhile(src, func(int) bool { return true }) }},
+		{"Skip", func(src iter.Seq[int]) iter.Seq[int] { return Skip(src, 2) }},
+		{"Chain", func(src iter.Seq[int]) iter.Seq[int] { return Chain(src, src) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pulled := 0
+			n := 0
+			for range tt.seq(naturals(&pulled)) {
+				n++
+				if n == 2 {
+					break // the runtime panics if the iterator keeps calling yield after this
+				}
+			}
+			if pulled > 5 {
+				t.Errorf("source was pulled %d times after the consumer stopped", pulled)
+			}
+		})
+	}
+}
+
+func TestZip(t *testing.T) {
+	pulled := 0
+	names := slices.Values([]string{"a", "b", "c"})
+	got := maps.Collect(Zip(names, naturals(&pulled)))
+	want := map[string]int{"a": 0, "b": 1, "c": 2}
+	if !maps.Equal(got, want) {
+		t.Errorf("Zip = %v; want %v", got, want)
+	}
+
+	// the shorter side decides the length, whichever side it is
+	n := 0
+	for range Zip(naturals(&pulled), names) {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("Zip(naturals, names) yielded %d pairs; want 3", n)
+	}
+
+	for a, b := range Zip(names, names) {
+		if a != "a" || b != "a" {
+			t.Errorf("first pair = %q, %q", a, b)
+		}
+		break
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	var idx []int
+	var vals []string
+	for i, v := range Enumerate(slices.Values([]string{"x", "y", "z"})) {
+		idx = append(idx, i)
+		vals = append(vals, v)
+		if i == 1 {
+			break
+		}
+	}
+	if !slices.Equal(idx, []int{0, 1}) || !slices.Equal(vals, []string{"x", "y"}) {
+		t.Errorf("Enumerate = %v %v", idx, vals)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum := Reduce(slices.Values([]int{1, 2, 3, 4}), 0, func(acc, n int) int { return acc + n })
+	if sum != 10 {
+		t.Errorf("Reduce sum = %d; want 10", sum)
+	}
+	// the accumulator may have a different type than the values
+	joined := Reduce(slices.Values([]int{1, 2, 3}), "", func(acc string, n int) string {
+		return acc + string(rune('0'+n))
+	})
+	if joined != "123" {
+		t.Errorf("Reduce join = %q; want 123", joined)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	var tests = []struct {
+		in   []int
+		size int
+		want [][]int
+	}{
+		{nil, 2, nil},
+		{[]int{1, 2, 3, 4}, 2, [][]int{{1, 2}, {3, 4}}},
+		{[]int{1, 2, 3, 4, 5}, 2, [][]int{{1, 2}, {3, 4}, {5}}},
+		{[]int{1, 2}, 5, [][]int{{1, 2}}},
+	}
+	for _, tt := range tests {
+		got := slices.Collect(Chunk(slices.Values(tt.in), tt.size))
+		if !slices.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("Chunk(%v, %d) = %v; want %v", tt.in, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	var tests = []struct {
+		in   []int
+		size int
+		want [][]int
+	}{
+		{[]int{1, 2}, 3, nil},
+		{[]int{1, 2, 3}, 3, [][]int{{1, 2, 3}}},
+		{[]int{1, 2, 3, 4, 5}, 3, [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}}},
+		{[]int{1, 2, 3}, 1, [][]int{{1}, {2}, {3}}},
+	}
+	for _, tt := range tests {
+		// collecting keeps every window around, which also checks that they don't share memory
+		got := slices.Collect(Window(slices.Values(tt.in), tt.size))
+		if !slices.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("Window(%v, %d) = %v; want %v", tt.in, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidSizePanics(t *testing.T) {
+	for name, f := range map[string]func(){
+		"Chunk":  func() { Chunk(slices.Values([]int{1}), 0) },
+		"Window": func() { Window(slices.Values([]int{1}), 0) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic for size 0")
+				}
+			}()
+			f()
+		})
+	}
+}
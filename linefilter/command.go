@@ -0,0 +1,126 @@
+package linefilter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RunArgs runs the filter command on stdin, or on the files named in args:
+//
+//	oneforall filter [-0] [-max-record=n] [transforms...] [files...]
+//
+// the transforms run in the order they are given, for example
+// "oneforall filter -grep=go -upper -number notes.txt" numbers the lines containing go, uppercased
+func RunArgs(ctx context.Context, w io.Writer, args []string) error {
+	return run(ctx, w, os.Stdin, args)
+}
+
+// run is RunArgs with stdin as a parameter, for the tests
+func run(ctx context.Context, w io.Writer, stdin io.Reader, args []string) error {
+	opts, t, files, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		if err := filterFile(ctx, w, stdin, name, opts, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterFile runs the filter on the file name, "-" is stdin
+func filterFile(ctx context.Context, w io.Writer, stdin io.Reader, name string, opts Options, t Transform) error {
+	r := stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if err := Filter(ctx, w, r, opts, t); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// parseArgs reads the options and the transforms from the flags, the remaining arguments are the files.
+// flag.Func and flag.BoolFunc call back in command line order, which keeps the transforms in the order they are given
+func parseArgs(args []string) (Options, Transform, []string, error) {
+	var (
+		opts Options
+		ts   []Transform
+	)
+	fs := flag.NewFlagSet("filter", flag.ContinueOnError)
+	fs.BoolVar(&opts.NUL, "0", false, "records end with a NUL byte instead of a newline")
+	fs.IntVar(&opts.MaxRecord, "max-record", DefaultMaxRecord, "longest record accepted, in bytes")
+	add := func(t Transform) error { ts = append(ts, t); return nil }
+	// addIf adds a new transform for -name or -name=true, -name=false adds nothing
+	addIf := func(newT func() Transform) func(string) error {
+		return func(s string) error {
+			on, err := strconv.ParseBool(s)
+			if err != nil || !on {
+				return err
+			}
+			return add(newT())
+		}
+	}
+	fs.BoolFunc("upper", "uppercase", addIf(Upper))
+	fs.BoolFunc("lower", "lowercase", addIf(Lower))
+	fs.BoolFunc("trim", "remove leading and trailing white space", addIf(Trim))
+	fs.BoolFunc("number", "prefix the records with their number", addIf(Number))
+	fs.BoolFunc("sha256", "replace the records with their sha256", addIf(SHA256))
+	fs.Func("grep", "keep the records matching the regular expression", func(s string) error {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return err
+		}
+		return add(Grep(re, false))
+	})
+	fs.Func("grep-v", "drop the records matching the regular expression", func(s string) error {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return err
+		}
+		return add(Grep(re, true))
+	})
+	fs.Func("replace", "replace matches, written /pattern/replacement/ with any delimiter, the replacement can use $1", func(s string) error {
+		re, repl, err := parseReplace(s)
+		if err != nil {
+			return err
+		}
+		return add(Replace(re, repl))
+	})
+	if err := fs.Parse(args); err != nil {
+		return Options{}, nil, nil, err
+	}
+	return opts, Chain(ts...), fs.Args(), nil
+}
+
+// parseReplace splits a sed like /pattern/replacement/, the first character is the delimiter
+func parseReplace(s string) (*regexp.Regexp, string, error) {
+	if len(s) < 3 {
+		return nil, "", errors.New("want /pattern/replacement/")
+	}
+	parts := strings.Split(s[1:], s[:1])
+	if len(parts) != 3 || parts[2] != "" {
+		return nil, "", fmt.Errorf("want %[1]spattern%[1]sreplacement%[1]s", s[:1])
+	}
+	re, err := regexp.Compile(parts[0])
+	if err != nil {
+		return nil, "", err
+	}
+	return re, parts[1], nil
+}
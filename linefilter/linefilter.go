@@ -0,0 +1,151 @@
+// Package linefilter is the line filter of the file example grown into a tool: it streams records,
+// lines or NUL-delimited, from stdin or files through a chain of transforms and writes the result.
+// the transforms are the ToUpper of the file example, plus lower, trim, grep and replace with the regular expressions
+// of the regexp example, line numbers and a sha256 per line.
+// unlike a bufio.Scanner with its defaults, records may be longer than 64KB, up to a configurable limit
+package linefilter
+
+import (
+	"RobotTask/helper"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// DefaultMaxRecord is the default limit on the length of a record, 1MB
+const DefaultMaxRecord = 1 << 20
+
+// Transform changes a record, or drops it by returning false.
+// the returned slice may be the one passed in, a transform must not keep it after returning
+type Transform func(rec []byte) ([]byte, bool)
+
+// Upper uppercases the record, like strings.ToUpper in the file example
+func Upper() Transform {
+	return func(rec []byte) ([]byte, bool) { return bytes.ToUpper(rec), true }
+}
+
+// Lower lowercases the record
+func Lower() Transform {
+	return func(rec []byte) ([]byte, bool) { return bytes.ToLower(rec), true }
+}
+
+// Trim removes the leading and trailing white space
+func Trim() Transform {
+	return func(rec []byte) ([]byte, bool) { return bytes.TrimSpace(rec), true }
+}
+
+// Grep keeps the records matching re, or the ones that don't if invert is true
+func Grep(re *regexp.Regexp, invert bool) Transform {
+	return func(rec []byte) ([]byte, bool) { return rec, re.Match(rec) != invert }
+}
+
+// Replace replaces every match of re, repl can refer to submatches like $1, as in regexp.ReplaceAll
+func Replace(re *regexp.Regexp, repl string) Transform {
+	r := []byte(repl)
+	return func(rec []byte) ([]byte, bool) { return re.ReplaceAll(rec, r), true }
+}
+
+// Number prefixes the records with their number, starting at 1, like cat -n.
+// it counts the records that reach it, so a grep before it numbers the matches only
+func Number() Transform {
+	n := 0
+	return func(rec []byte) ([]byte, bool) {
+		n++
+		out := fmt.Appendf(nil, "%6d\t", n)
+		return append(out, rec...), true
+	}
+}
+
+// SHA256 replaces the record with the hex encoded sha256 of its content
+func SHA256() Transform {
+	return func(rec []byte) ([]byte, bool) {
+		return []byte(hex.EncodeToString(helper.Sha256Sum(rec))), true
+	}
+}
+
+// Chain runs the transforms in order, a record dropped by one of them doesn't reach the next ones
+func Chain(ts ...Transform) Transform {
+	return func(rec []byte) ([]byte, bool) {
+		for _, t := range ts {
+			var keep bool
+			if rec, keep = t(rec); !keep {
+				return nil, false
+			}
+		}
+		return rec, true
+	}
+}
+
+// Options controls how records are read and written
+type Options struct {
+	MaxRecord int  // longest record accepted, 0 means DefaultMaxRecord
+	NUL       bool // records end with a NUL byte instead of a newline, like the output of find -print0
+}
+
+// ErrTooLong is returned when a record is longer than Options.MaxRecord
+var ErrTooLong = errors.New("linefilter: record too long")
+
+// Filter reads records from r, runs them through t and writes the kept ones to w, each followed by the delimiter.
+// it stops early with ctx's error once ctx is done
+func Filter(ctx context.Context, w io.Writer, r io.Reader, opts Options, t Transform) error {
+	limit := opts.MaxRecord
+	if limit <= 0 {
+		limit = DefaultMaxRecord
+	}
+	sc := bufio.NewScanner(r)
+	// the buffer starts small and grows up to the limit, the default limit of a scanner is 64KB.
+	// the scanner needs room for the delimiter too, a record of exactly limit bytes is accepted
+	sc.Buffer(make([]byte, 0, min(limit+1, 64*1024)), limit+1)
+	delim := byte('\n')
+	if opts.NUL {
+		delim = 0
+		sc.Split(scanDelimited(0))
+	}
+
+	bw := bufio.NewWriter(w)
+	for sc.Scan() {
+		if err := ctx.Err(); err != nil {
+			bw.Flush() // what was filtered so far still goes out
+			return err
+		}
+		rec, keep := t(sc.Bytes())
+		if !keep {
+			continue
+		}
+		if _, err := bw.Write(rec); err != nil {
+			return err
+		}
+		if err := bw.WriteByte(delim); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		bw.Flush()
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("%w, the limit is %d bytes", ErrTooLong, limit)
+		}
+		return err
+	}
+	return bw.Flush()
+}
+
+// scanDelimited is a bufio.SplitFunc splitting on delim, the last record doesn't need to end with it
+func scanDelimited(delim byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.IndexByte(data, delim); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil // read more
+	}
+}
@@ -0,0 +1,182 @@
+package linefilter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTransforms(t *testing.T) {
+	var tests = []struct {
+		name string
+		t    Transform
+		in   string
+		want string
+	}{
+		{"upper", Upper(), "abc\nDef\n", "ABC\nDEF\n"},
+		{"lower", Lower(), "ABC\nDef\n", "abc\ndef\n"},
+		{"trim", Trim(), "  a \n\tb\n", "a\nb\n"},
+		{"grep", Grep(regexp.MustCompile("p([a-z]+)ch"), false), "peach\npunch\npear\n", "peach\npunch\n"},
+		{"grep -v", Grep(regexp.MustCompile("p([a-z]+)ch"), true), "peach\npunch\npear\n", "pear\n"},
+		{"replace", Replace(regexp.MustCompile("p([a-z]+)ch"), "<$1>"), "a peach\n", "a <ea>\n"},
+		{"number", Number(), "a\nb\n", "     1\ta\n     2\tb\n"},
+		{"sha256", SHA256(), "sha256 this string\n", "1af1dfa857bf1d8814fe1af8983c18080019922e557f15a8a0d3db739d77aacb\n"},
+		{"chain", Chain(Grep(regexp.MustCompile("o"), false), Upper(), Number()), "go\nrust\nzig\nocaml\n", "     1\tGO\n     2\tOCAML\n"},
+		{"no transform", Chain(), "a\r\nb", "a\nb\n"}, // windows line endings and a missing final newline are handled like the scanner does
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Filter(context.Background(), &out, strings.NewReader(tt.in), Options{}, tt.t); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q; want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLongLines(t *testing.T) {
+	// 100KB is past the 64KB a bufio.Scanner accepts by default
+	long := strings.Repeat("a", 100*1024)
+	var out bytes.Buffer
+	if err := Filter(context.Background(), &out, strings.NewReader(long+"\nb\n"), Options{}, Upper()); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != strings.ToUpper(long)+"\nB\n" {
+		t.Errorf("long line mangled, got %d bytes", out.Len())
+	}
+
+	// past the configured limit the error says so, the lines before it are still written
+	out.Reset()
+	err := Filter(context.Background(), &out, strings.NewReader("short\n"+long+"\n"), Options{MaxRecord: 1024}, Upper())
+	if !errors.Is(err, ErrTooLong) {
+		t.Errorf("err = %v; want ErrTooLong", err)
+	}
+	if out.String() != "SHORT\n" {
+		t.Errorf("output before the long line = %q", out.String())
+	}
+}
+
+func TestMaxRecordBoundary(t *testing.T) {
+	for _, tt := range []struct {
+		in      string
+		nul     bool
+		tooLong bool
+	}{
+		{"12345\n", false, false},
+		{"12345", false, false},
+		{"123456\n", false, true},
+		{"123456", false, true},
+		{"12345\x00", true, false},
+		{"12345", true, false},
+		{"123456\x00", true, true},
+		{"123456", true, true},
+	} {
+		var out bytes.Buffer
+		err := Filter(context.Background(), &out, strings.NewReader(tt.in), Options{MaxRecord: 5, NUL: tt.nul}, Chain())
+		if tt.tooLong {
+			if !errors.Is(err, ErrTooLong) {
+				t.Errorf("%q: err = %v; want ErrTooLong", tt.in, err)
+			}
+			continue
+		}
+		delim := "\n"
+		if tt.nul {
+			delim = "\x00"
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+		} else if out.String() != "12345"+delim {
+			t.Errorf("%q: got %q", tt.in, out.String())
+		}
+	}
+}
+
+func TestNUL(t *testing.T) {
+	var out bytes.Buffer
+	in := "a file\nwith a newline\x00b\x00c" // newlines are part of the records, the last one has no terminator
+	if err := Filter(context.Background(), &out, strings.NewReader(in), Options{NUL: true}, Upper()); err != nil {
+		t.Fatal(err)
+	}
+	if want := "A FILE\nWITH A NEWLINE\x00B\x00C\x00"; out.String() != want {
+		t.Errorf("got %q; want %q", out.String(), want)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	if err := Filter(ctx, &out, strings.NewReader("a\nb\n"), Options{}, Upper()); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v; want context.Canceled", err)
+	}
+}
+
+// failWriter fails every write
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriteError(t *testing.T) {
+	// a record larger than the buffer of the writer goes straight to w
+	big := strings.Repeat("x", 10000) + "\n"
+	err := Filter(context.Background(), failWriter{}, strings.NewReader(big+big), Options{}, Chain())
+	if err == nil || err.Error() != "disk full" {
+		t.Errorf("err = %v; want disk full", err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	one := filepath.Join(dir, "one.txt")
+	two := filepath.Join(dir, "two.txt")
+	os.WriteFile(one, []byte("go\nrust\n"), 0644)
+	os.WriteFile(two, []byte("ocaml\nzig\n"), 0644)
+
+	var tests = []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    string
+		wantErr string
+	}{
+		{"stdin", []string{"-upper"}, "abc\n", "ABC\n", ""},
+		{"order matters", []string{"-number", "-grep=o"}, "go\nrust\nocaml\n", "     1\tgo\n     3\tocaml\n", ""},
+		{"files", []string{"-grep-v=u", one, two}, "", "go\nocaml\nzig\n", ""},
+		{"stdin among files", []string{"-trim", one, "-"}, "  x  \n", "go\nrust\nx\n", ""},
+		{"replace", []string{"-replace=|o|0|", "-replace=/([a-z]+)/<$1>/"}, "go\n", "<g>0\n", ""},
+		{"nul", []string{"-0", "-lower"}, "A\x00B\x00", "a\x00b\x00", ""},
+		{"max record", []string{"-max-record=4"}, "12345\n", "", "record too long"},
+		{"exactly max record", []string{"-max-record=5"}, "12345\n", "12345\n", ""},
+		{"bad regexp", []string{"-grep=("}, "", "", "missing closing )"},
+		{"bad replace", []string{"-replace=/a/b"}, "", "", "want /pattern/replacement/"},
+		{"missing file", []string{filepath.Join(dir, "nope")}, "", "", "no such file"},
+		{"false flags", []string{"-upper=false", "-number=false", "-sha256=false", "-trim=0", "-lower=true"}, " Ab\n", " ab\n", ""},
+		{"bad bool", []string{"-trim=maybe"}, "", "", `invalid boolean value "maybe" for -trim`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(context.Background(), &out, strings.NewReader(tt.stdin), tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v; want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q; want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// RequestIDKey is the attribute key of the request id
+const RequestIDKey = "request_id"
+
+// context keys, unexported types avoid collisions with other packages
+type (
+	loggerKey struct{}
+	attrsKey  struct{}
+)
+
+// NewContext returns a copy of ctx carrying logger, the examples get their logger this way
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored by NewContext, or slog.Default() if there is none
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// With returns a copy of ctx carrying attrs on top of the attributes ctx already carries.
+// records logged with that context through a ContextHandler get them added
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	// a new slice, so that contexts derived from the same parent don't share their attributes
+	all := slices.Concat(Attrs(ctx), attrs)
+	return context.WithValue(ctx, attrsKey{}, all)
+}
+
+// WithRequestID returns a copy of ctx carrying the request id under RequestIDKey
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return With(ctx, slog.String(RequestIDKey, id))
+}
+
+// Attrs returns the attributes carried by ctx
+func Attrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// ContextHandler adds the attributes carried by the context of a record to it.
+// an attribute the record already has with the same key is kept as is, so an explicit request_id wins
+type ContextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps h in a ContextHandler
+func NewContextHandler(h slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: h}
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := Attrs(ctx)
+	if len(attrs) == 0 {
+		return h.Handler.Handle(ctx, r)
+	}
+	has := make(map[string]bool, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		has[a.Key] = true
+		return true
+	})
+	r = r.Clone() // the record may be shared with other handlers
+	for _, a := range attrs {
+		if !has[a.Key] {
+			r.AddAttrs(a)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,115 @@
+// Package logging sets up the structured loggers of the examples on top of log/slog.
+// the level, format, destination and source locations come from a Config, which can be filled from flags
+// and environment variables. records pick up the attributes carried by their context, such as a request id,
+// log files can be rotated once they reach a size, and Recorder captures records for tests.
+package logging
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// Config holds the settings of a logger
+type Config struct {
+	Level      slog.Level
+	Format     string // "text" or "json"
+	File       string // path of the log file, empty means the writer given to Open, usually stderr
+	AddSource  bool   // add the file and line of the log call to every record
+	MaxSize    int64  // rotate the file once it would grow past this many bytes, 0 never rotates
+	MaxBackups int    // number of rotated files to keep next to the log file
+}
+
+// DefaultConfig returns the settings used when nothing is configured: info and above, as text
+func DefaultConfig() Config {
+	return Config{
+		Level:      slog.LevelInfo,
+		Format:     "text",
+		MaxSize:    10 << 20,
+		MaxBackups: 3,
+	}
+}
+
+// RegisterFlags declares -log-level, -log-format, -log-file, -log-source, -log-max-size and -log-max-backups on fs.
+// the current values of c are the defaults, so loading the environment first lets flags override it
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.TextVar(&c.Level, "log-level", c.Level, "minimum level logged: debug, info, warn or error")
+	fs.StringVar(&c.Format, "log-format", c.Format, "log format: text or json")
+	fs.StringVar(&c.File, "log-file", c.File, "write logs to this file instead of stderr")
+	fs.BoolVar(&c.AddSource, "log-source", c.AddSource, "add the source file and line to every record")
+	fs.Int64Var(&c.MaxSize, "log-max-size", c.MaxSize, "rotate the log file once it reaches this many bytes, 0 never rotates")
+	fs.IntVar(&c.MaxBackups, "log-max-backups", c.MaxBackups, "number of rotated log files to keep")
+}
+
+// LoadEnv reads the settings from the environment variables LOG_LEVEL, LOG_FORMAT, LOG_FILE, LOG_SOURCE,
+// LOG_MAX_SIZE and LOG_MAX_BACKUPS, each starting with prefix. variables that are not set leave the setting alone.
+// lookup is usually os.LookupEnv
+func (c *Config) LoadEnv(prefix string, lookup func(string) (string, bool)) error {
+	var errs []error
+	get := func(name string, set func(string) error) {
+		if v, ok := lookup(prefix + name); ok {
+			if err := set(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s%s: %w", prefix, name, err))
+			}
+		}
+	}
+	get("LOG_LEVEL", func(v string) error { return c.Level.UnmarshalText([]byte(v)) })
+	get("LOG_FORMAT", func(v string) error { c.Format = v; return nil })
+	get("LOG_FILE", func(v string) error { c.File = v; return nil })
+	get("LOG_SOURCE", func(v string) (err error) { c.AddSource, err = strconv.ParseBool(v); return err })
+	get("LOG_MAX_SIZE", func(v string) (err error) { c.MaxSize, err = strconv.ParseInt(v, 10, 64); return err })
+	get("LOG_MAX_BACKUPS", func(v string) (err error) { c.MaxBackups, err = strconv.Atoi(v); return err })
+	return errors.Join(errs...)
+}
+
+// NewHandler returns a text or json handler writing to w, wrapped in a ContextHandler
+func NewHandler(w io.Writer, cfg Config) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: cfg.Level, AddSource: cfg.AddSource}
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("logging: unknown format %q, want text or json", cfg.Format)
+	}
+	return NewContextHandler(h), nil
+}
+
+// New returns a logger writing to w, see NewHandler
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	h, err := NewHandler(w, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return slog.New(h), nil
+}
+
+// nopCloser is returned by Open when there is no file to close
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// Open returns the logger described by cfg. it writes to the file cfg.File, rotated by size, or to w if there is none.
+// the closer closes the file and must be called once the logger is no longer used
+func Open(cfg Config, w io.Writer) (*slog.Logger, io.Closer, error) {
+	var closer io.Closer = nopCloser{}
+	if cfg.File != "" {
+		f, err := OpenRotating(cfg.File, cfg.MaxSize, cfg.MaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, closer = f, f
+	}
+	logger, err := New(w, cfg)
+	if err != nil {
+		closer.Close()
+		return nil, nil, err
+	}
+	return logger, closer, nil
+}
@@ -0,0 +1,240 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// env returns a lookup function over a fixed environment
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestConfigEnvThenFlags(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.LoadEnv("APP_", env(map[string]string{
+		"APP_LOG_LEVEL":    "debug",
+		"APP_LOG_FORMAT":   "json",
+		"APP_LOG_SOURCE":   "true",
+		"APP_LOG_MAX_SIZE": "1024",
+		"LOG_FILE":         "ignored.log", // no prefix
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// flags are declared with the environment values as defaults, and override them
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-log-level=warn", "-log-file=app.log"}); err != nil {
+		t.Fatal(err)
+	}
+	want := Config{Level: slog.LevelWarn, Format: "json", File: "app.log", AddSource: true, MaxSize: 1024, MaxBackups: 3}
+	if cfg != want {
+		t.Errorf("config = %+v; want %+v", cfg, want)
+	}
+}
+
+func TestConfigEnvErrors(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.LoadEnv("", env(map[string]string{"LOG_LEVEL": "loud", "LOG_MAX_SIZE": "big"}))
+	if err == nil || !strings.Contains(err.Error(), "LOG_LEVEL") || !strings.Contains(err.Error(), "LOG_MAX_SIZE") {
+		t.Errorf("err = %v; want both bad variables reported", err)
+	}
+}
+
+func TestNewFormats(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := DefaultConfig()
+	cfg.Format = "json"
+	cfg.Level = slog.LevelWarn
+	logger, err := New(&buf, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("dropped")
+	logger.Warn("kept", "n", 1)
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("not a single json record: %q", buf.String())
+	}
+	if got["msg"] != "kept" || got["n"] != float64(1) {
+		t.Errorf("record = %v", got)
+	}
+
+	cfg.Format = "xml"
+	if _, err := New(&buf, cfg); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestContextAttrs(t *testing.T) {
+	rec := NewRecorder(slog.LevelDebug)
+	logger := slog.New(NewContextHandler(rec))
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	child := With(ctx, slog.String("user", "joe"))
+	logger.InfoContext(child, "child")
+	logger.InfoContext(ctx, "parent")                            // the child's attributes don't leak into the parent
+	logger.InfoContext(child, "explicit", RequestIDKey, "req-2") // an explicit attribute wins over the context
+
+	var tests = []struct {
+		msg  string
+		want map[string]string
+	}{
+		{"child", map[string]string{RequestIDKey: "req-1", "user": "joe"}},
+		{"parent", map[string]string{RequestIDKey: "req-1"}},
+		{"explicit", map[string]string{RequestIDKey: "req-2", "user": "joe"}},
+	}
+	for _, tt := range tests {
+		r, ok := rec.Find(tt.msg)
+		if !ok {
+			t.Fatalf("%q not logged", tt.msg)
+		}
+		attrs := AttrsOf(r)
+		if len(attrs) != len(tt.want) {
+			t.Errorf("%s: attrs = %v; want %v", tt.msg, attrs, tt.want)
+		}
+		for k, v := range tt.want {
+			if attrs[k].String() != v {
+				t.Errorf("%s: %s = %v; want %s", tt.msg, k, attrs[k], v)
+			}
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("FromContext without a logger isn't slog.Default()")
+	}
+	l := slog.New(NewRecorder(slog.LevelInfo))
+	if FromContext(NewContext(context.Background(), l)) != l {
+		t.Error("FromContext didn't return the stored logger")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder(slog.LevelInfo)
+	logger := slog.New(rec).With("app", "test").WithGroup("req")
+	logger.Debug("too low")
+	logger.Info("hello", "id", 7)
+
+	if got := rec.Messages(); len(got) != 1 || got[0] != "hello" {
+		t.Fatalf("messages = %v; want [hello]", got)
+	}
+	r, _ := rec.Find("hello")
+	attrs := AttrsOf(r)
+	if attrs["app"].String() != "test" || attrs["req.id"].Int64() != 7 {
+		t.Errorf("attrs = %v", attrs)
+	}
+	rec.Reset()
+	if len(rec.Records()) != 0 {
+		t.Error("Reset kept the records")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := OpenRotating(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// every line pushes the previous one out, only two backups are kept
+	for name, want := range map[string]string{
+		path:        "dddddd\n",
+		path + ".1": "cccccc\n",
+		path + ".2": "bbbbbb\n",
+	} {
+		got, err := os.ReadFile(name)
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(name), got, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("a third backup was kept: %v", err)
+	}
+
+	// reopening appends and keeps counting from the existing size
+	f.Close()
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Error("write after Close succeeded")
+	}
+	f, err = OpenRotating(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("eeeeee\n"))
+	if got, _ := os.ReadFile(path + ".1"); string(got) != "dddddd\n" {
+		t.Errorf("reopened file didn't rotate at its existing size, app.log.1 = %q", got)
+	}
+}
+
+func TestRotateFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := OpenRotating(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// a directory in the way of the backup makes the rename fail
+	if err := os.MkdirAll(filepath.Join(path+".1", "busy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("0123456789"))
+	for _, line := range []string{"abc", "def"} {
+		if n, err := f.Write([]byte(line)); err == nil || n != len(line) {
+			t.Errorf("write %q = %d, %v; want it written with the rotation error", line, n, err)
+		}
+	}
+	if err := f.Rotate(); err == nil {
+		t.Error("Rotate succeeded with a directory in the way")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "0123456789abcdef" {
+		t.Errorf("app.log = %q; want all the writes", got)
+	}
+
+	// once the way is clear the file rotates again
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("ghi")); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{path: "ghi", path + ".1": "0123456789abcdef"} {
+		if got, err := os.ReadFile(name); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(name), got, err, want)
+		}
+	}
+}
+
+func TestOpenWithFile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.File = filepath.Join(t.TempDir(), "app.log")
+	logger, closer, err := Open(cfg, os.Stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("to the file")
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(cfg.File)
+	if !strings.Contains(string(got), "msg=\"to the file\"") {
+		t.Errorf("log file = %q", got)
+	}
+}
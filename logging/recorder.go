@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// Recorder is a slog.Handler that keeps the records it handles, so that tests can check what was logged:
+//
+//	rec := logging.NewRecorder(slog.LevelDebug)
+//	ctx := logging.NewContext(context.Background(), slog.New(rec))
+//	... run the code under test with ctx ...
+//	if _, ok := rec.Find("server started"); !ok { t.Error(...) }
+//
+// attributes added with WithAttrs are stored on the records, keys in a group are prefixed with the group name and a dot.
+// it is safe for concurrent use
+type Recorder struct {
+	level  slog.Leveler
+	store  *recorderStore // shared with the handlers derived by WithAttrs and WithGroup
+	attrs  []slog.Attr
+	prefix string // the groups opened with WithGroup, joined with dots
+}
+
+type recorderStore struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+// NewRecorder returns a Recorder keeping the records at level and above
+func NewRecorder(level slog.Leveler) *Recorder {
+	return &Recorder{level: level, store: &recorderStore{}}
+}
+
+func (r *Recorder) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= r.level.Level()
+}
+
+func (r *Recorder) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, rec.Message, rec.PC)
+	out.AddAttrs(r.attrs...)
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(r.qualify(a))
+		return true
+	})
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.records = append(r.store.records, out)
+	return nil
+}
+
+func (r *Recorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *r
+	c.attrs = slices.Clip(c.attrs)
+	for _, a := range attrs {
+		c.attrs = append(c.attrs, r.qualify(a))
+	}
+	return &c
+}
+
+func (r *Recorder) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return r
+	}
+	c := *r
+	c.prefix = r.prefix + name + "."
+	return &c
+}
+
+// qualify prefixes the key of a with the open groups
+func (r *Recorder) qualify(a slog.Attr) slog.Attr {
+	a.Key = r.prefix + a.Key
+	return a
+}
+
+// Records returns a copy of the records handled so far
+func (r *Recorder) Records() []slog.Record {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	out := make([]slog.Record, len(r.store.records))
+	for i, rec := range r.store.records {
+		out[i] = rec.Clone()
+	}
+	return out
+}
+
+// Messages returns the messages of the records handled so far, in order
+func (r *Recorder) Messages() []string {
+	var msgs []string
+	for _, rec := range r.Records() {
+		msgs = append(msgs, rec.Message)
+	}
+	return msgs
+}
+
+// Find returns the first record with the message msg
+func (r *Recorder) Find(msg string) (slog.Record, bool) {
+	for _, rec := range r.Records() {
+		if rec.Message == msg {
+			return rec, true
+		}
+	}
+	return slog.Record{}, false
+}
+
+// Reset drops the records handled so far
+func (r *Recorder) Reset() {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.records = nil
+}
+
+// AttrsOf returns the attributes of rec by key, group members are keyed like "group.key"
+func AttrsOf(rec slog.Record) map[string]slog.Value {
+	m := make(map[string]slog.Value, rec.NumAttrs())
+	rec.Attrs(func(a slog.Attr) bool {
+		m[a.Key] = a.Value.Resolve()
+		return true
+	})
+	return m
+}
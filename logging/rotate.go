@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is a log file that is rotated once it would grow past a size: app.log is renamed to app.log.1,
+// app.log.1 to app.log.2 and so on up to the number of backups kept, then a new app.log is started.
+// it is safe for concurrent use
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+// OpenRotating opens path for appending, creating it if needed. maxSize 0 or less never rotates
+func OpenRotating(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxSize: maxSize, maxBackups: max(maxBackups, 0)}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the file at path and picks up its current size, it replaces r.f only once the new file is ready.
+// mu must be held, or r not shared yet
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("logging: %w", err)
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if p doesn't fit. a single write larger than the limit still goes into one file.
+// when the rotation fails p still goes into the current file and the error is returned with n, the next write tries again
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		rotateErr = r.rotate()
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+// backup returns the name of the i-th rotated file
+func (r *RotatingFile) backup(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
+
+// rotate shifts the backups by one, dropping the oldest, and starts a new file. mu must be held.
+// the current file stays open until the new one is: if a step fails, the writes keep going to it, under its old name or
+// the backup one, and nothing is lost
+func (r *RotatingFile) rotate() error {
+	old := r.f
+	if r.maxBackups == 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("logging: %w", err)
+		}
+	} else {
+		os.Remove(r.backup(r.maxBackups)) // the oldest one, it may not exist yet
+		for i := r.maxBackups - 1; i >= 1; i-- {
+			if err := os.Rename(r.backup(i), r.backup(i+1)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("logging: %w", err)
+			}
+		}
+		if err := os.Rename(r.path, r.backup(1)); err != nil {
+			return fmt.Errorf("logging: %w", err)
+		}
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if err := old.Close(); err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+	return nil
+}
+
+// Rotate rotates the file now, whatever its size
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return os.ErrClosed
+	}
+	return r.rotate()
+}
+
+// Close closes the file, writes after Close fail with os.ErrClosed
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
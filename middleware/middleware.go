@@ -0,0 +1,181 @@
+// Package middleware contains composable wrappers for http handlers: request ids, access logging,
+// panic recovery, timeouts and response status/size capture.
+// a middleware takes a handler and returns a new handler that does some work before and/or after calling it
+package middleware
+
+import (
+	"RobotTask/logging"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// Middleware wraps a handler with extra behavior
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes middlewares into one. the first middleware is the outermost,
+// so Chain(a, b)(h) runs a, then b, then h
+func Chain(mws ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](h)
+		}
+		return h
+	}
+}
+
+/************************* request ids ********************************/
+
+// RequestIDHeader is the header a request id is read from and written to
+const RequestIDHeader = "X-Request-Id"
+
+// requestIDKey is the context key for the request id, an unexported type avoids collisions with other packages
+type requestIDKey struct{}
+
+// RequestID makes sure every request has an id: it keeps the one sent by the client in RequestIDHeader,
+// or generates a new one. the id is echoed in the response header and stored in the request context,
+// where it is also added to the logging attributes so every record logged with the request context carries it
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(RequestIDHeader)
+			if id == "" {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			ctx := context.WithValue(req.Context(), requestIDKey{}, id)
+			ctx = logging.WithRequestID(ctx, id)
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIDFrom returns the request id stored by RequestID, or "" if there is none
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID returns 16 random hex characters
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+/************************* status and size capture ********************************/
+
+// StatusWriter is a ResponseWriter that remembers the status code and the number of body bytes written
+type StatusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+// Capture wraps w in a StatusWriter, or returns w itself if it already is one
+func Capture(w http.ResponseWriter) *StatusWriter {
+	if sw, ok := w.(*StatusWriter); ok {
+		return sw
+	}
+	return &StatusWriter{ResponseWriter: w}
+}
+
+func (w *StatusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *StatusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK // a write without WriteHeader implies 200
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, for example to flush it
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the status code sent, 200 if the handler wrote nothing
+func (w *StatusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Size returns the number of body bytes written
+func (w *StatusWriter) Size() int {
+	return w.size
+}
+
+/************************* logging and recovery ********************************/
+
+// AccessLog logs one record per request once it is done, with its status, size and duration
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			sw := Capture(w)
+			next.ServeHTTP(sw, req)
+			logger.LogAttrs(req.Context(), slog.LevelInfo, "request",
+				slog.String("request_id", RequestIDFrom(req.Context())),
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.Int("status", sw.Status()),
+				slog.Int("size", sw.Size()),
+				slog.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
+
+// Recover turns a panic in the handler into a 500 response instead of a dropped connection.
+// like in the recover example, recover is called within a deferred function
+func Recover(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			sw := Capture(w)
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				// http.ErrAbortHandler is the way to abort a response on purpose, let the server handle it
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+				// with the context, a logging.ContextHandler adds the attributes of the request
+				logger.ErrorContext(req.Context(), "recovered from panic",
+					"request_id", RequestIDFrom(req.Context()),
+					"panic", fmt.Sprint(r),
+					"stack", string(debug.Stack()))
+				// if the handler already started the response, the status can't be changed anymore
+				if sw.status == 0 {
+					http.Error(sw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(sw, req)
+		})
+	}
+}
+
+/************************* timeouts ********************************/
+
+// Timeout limits how long the handler may run. past d the client gets a 503 and the handler's context is cancelled,
+// so handlers that watch ctx.Done(), like the context handler of the http example, stop early
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, d, "request timed out\n")
+	}
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"RobotTask/logging"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// record returns a middleware that appends name to order, to check the order Chain runs things in
+func record(order *[]string, name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			*order = append(*order, name)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	h := Chain(record(&order, "a"), record(&order, "b"), record(&order, "c"))(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { order = append(order, "handler") }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got := strings.Join(order, ","); got != "a,b,c,handler" {
+		t.Errorf("order = %s; want a,b,c,handler", got)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		seen = RequestIDFrom(req.Context())
+	}))
+
+	// a new id is generated when the client doesn't send one
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if len(seen) != 16 || rec.Header().Get(RequestIDHeader) != seen {
+		t.Errorf("generated id %q, response header %q", seen, rec.Header().Get(RequestIDHeader))
+	}
+
+	// an id sent by the client is kept
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if seen != "abc" || rec.Header().Get(RequestIDHeader) != "abc" {
+		t.Errorf("id = %q, header = %q; want abc", seen, rec.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestRequestIDIsLogged(t *testing.T) {
+	// records logged with the request context carry the id, without the handler passing it along
+	recorder := logging.NewRecorder(slog.LevelInfo)
+	logger := slog.New(logging.NewContextHandler(recorder))
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		logger.InfoContext(req.Context(), "handling")
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "req-7")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	rec, ok := recorder.Find("handling")
+	if !ok {
+		t.Fatalf("nothing logged, got %v", recorder.Messages())
+	}
+	if id := logging.AttrsOf(rec)[logging.RequestIDKey].String(); id != "req-7" {
+		t.Errorf("request_id = %q; want req-7", id)
+	}
+}
+
+func TestStatusWriter(t *testing.T) {
+	var tests = []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantSize   int
+	}{
+		{"nothing written", func(w http.ResponseWriter, req *http.Request) {}, 200, 0},
+		{"implicit 200", func(w http.ResponseWriter, req *http.Request) { io.WriteString(w, "hello\n") }, 200, 6},
+		{"explicit status", func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+			io.WriteString(w, "tea")
+			w.WriteHeader(http.StatusOK) // a superfluous second WriteHeader doesn't change the status sent
+		}, http.StatusTeapot, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sw := Capture(httptest.NewRecorder())
+			tt.handler(sw, httptest.NewRequest("GET", "/", nil))
+			if sw.Status() != tt.wantStatus || sw.Size() != tt.wantSize {
+				t.Errorf("status, size = %d, %d; want %d, %d", sw.Status(), sw.Size(), tt.wantStatus, tt.wantSize)
+			}
+			if Capture(sw) != sw {
+				t.Error("Capture wrapped a StatusWriter twice")
+			}
+		})
+	}
+}
+
+func TestAccessLogAndRecover(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	h := Chain(RequestID(), AccessLog(logger), Recover(logger))(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { panic("a problem") }))
+
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d; want 500", rec.Code)
+	}
+
+	// one record for the panic, then the access log record
+	var records []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r map[string]any
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decode log: %v", err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d log records; want 2", len(records))
+	}
+	if records[0]["panic"] != "a problem" {
+		t.Errorf("panic record = %v", records[0])
+	}
+	access := records[1]
+	if access["status"] != float64(500) || access["path"] != "/panic" || access["request_id"] != "req-1" {
+		t.Errorf("access record = %v", access)
+	}
+}
+
+func TestRecoverLogsTheContext(t *testing.T) {
+	rec := logging.NewRecorder(slog.LevelInfo)
+	logger := slog.New(logging.NewContextHandler(rec))
+	h := Chain(RequestID(), Recover(logger))(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { panic("a problem") }))
+
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req = req.WithContext(logging.With(req.Context(), slog.String("user", "joe")))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	r, ok := rec.Find("recovered from panic")
+	if !ok {
+		t.Fatalf("no panic record in %q", rec.Messages())
+	}
+	if attrs := logging.AttrsOf(r); attrs["user"].String() != "joe" || attrs["request_id"].String() == "" {
+		t.Errorf("panic record attrs = %v; want the ones of the request context", attrs)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done() // like the context handler, give up once the context is cancelled
+		close(cancelled)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d; want 503", rec.Code)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("handler context was not cancelled")
+	}
+}
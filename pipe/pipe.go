@@ -0,0 +1,188 @@
+// Package pipe connects commands like a shell pipeline, where the spawn example wires a single grep by hand:
+//
+//	res, err := pipe.Pipeline(ctx, strings.NewReader(text),
+//		exec.Command("grep", "hello"),
+//		exec.Command("sort"),
+//		exec.Command("uniq", "-c"),
+//	)
+//
+// the stdout of each command is the stdin of the next one through an os.Pipe, the first one reads the io.Reader given.
+// the output of the last command and the stderr of them all are collected together, like 2>&1 on each stage.
+// a stage failing doesn't stop the others, they all run to the end and every failure is reported, like set -o pipefail
+package pipe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWaitDelay is the WaitDelay of the commands that leave it at 0
+const DefaultWaitDelay = time.Second
+
+// Result is what a pipeline produced
+type Result struct {
+	// Output is the stdout of the last command and the stderr of every command, in the order they were written
+	Output []byte
+	// ExitCodes are the exit codes of the commands, in the pipeline order.
+	// -1 for a command that didn't start or was killed by a signal
+	ExitCodes []int
+}
+
+// StageError is the failure of one command of a pipeline
+type StageError struct {
+	Stage int    // the position of the command, 1 for the first
+	Args  string // the command line
+	Err   error  // an *exec.ExitError for a command that ran, or the error that kept it from starting
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %d (%s): %v", e.Stage, e.Args, e.Err)
+}
+
+func (e *StageError) Unwrap() error { return e.Err }
+
+// Pipeline runs cmds connected stdout to stdin, with stdin as the input of the first one, nil for none.
+// the commands must not be started. their Stdin, and Stdout except for the last one, must be nil, the pipeline sets them.
+// the Stderr of a command and the Stdout of the last one are collected in Result.Output unless they are already set.
+// a command without WaitDelay gets DefaultWaitDelay, so that a process it started can't keep the pipeline waiting.
+//
+// the error joins a *StageError for each command that failed, in the pipeline order.
+// when ctx is done every command is killed and the error includes ctx.Err()
+func Pipeline(ctx context.Context, stdin io.Reader, cmds ...*exec.Cmd) (Result, error) {
+	res := Result{ExitCodes: make([]int, len(cmds))}
+	if len(cmds) == 0 {
+		return res, errors.New("pipe: no commands")
+	}
+	for i, cmd := range cmds {
+		res.ExitCodes[i] = -1
+		switch {
+		case cmd.Process != nil:
+			return res, stageError(i, cmd, errors.New("already started"))
+		case cmd.Stdin != nil:
+			return res, stageError(i, cmd, errors.New("Stdin is already set"))
+		case cmd.Stdout != nil && i < len(cmds)-1:
+			return res, stageError(i, cmd, errors.New("Stdout is already set, but it feeds the next command"))
+		}
+	}
+
+	// the pipe ends the children use, the parent closes its copies once the children have them.
+	// they are all made before any command is changed, a failure leaves the commands as they were
+	var ends []*os.File
+	closeEnds := func() {
+		for _, f := range ends {
+			f.Close()
+		}
+		ends = nil
+	}
+	defer closeEnds()
+	for range len(cmds) - 1 {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return res, fmt.Errorf("pipe: %w", err)
+		}
+		ends = append(ends, r, w)
+	}
+
+	var out syncBuffer
+	cmds[0].Stdin = stdin
+	last := cmds[len(cmds)-1]
+	if last.Stdout == nil {
+		last.Stdout = &out
+	}
+	for i := range len(cmds) - 1 {
+		cmds[i].Stdout = ends[2*i+1]
+		cmds[i+1].Stdin = ends[2*i]
+	}
+	for _, cmd := range cmds {
+		if cmd.Stderr == nil {
+			cmd.Stderr = &out
+		}
+		// a grandchild keeping an output open doesn't block Wait for longer than this, once the command is gone
+		if cmd.WaitDelay == 0 {
+			cmd.WaitDelay = DefaultWaitDelay
+		}
+	}
+
+	started := 0
+	var startErr error
+	for i, cmd := range cmds {
+		if err := cmd.Start(); err != nil {
+			startErr = stageError(i, cmd, err)
+			break
+		}
+		started++
+	}
+	// without the parent's copies, a reader sees the end of its input when the writer before it exits
+	closeEnds()
+	if startErr != nil {
+		// the commands already running would wait forever for a stage that isn't there
+		for _, cmd := range cmds[:started] {
+			cmd.Process.Kill()
+		}
+	}
+
+	// a done ctx kills the whole pipeline, not only the command reading from it
+	waited := make(chan struct{})
+	var killer sync.WaitGroup
+	killer.Add(1)
+	go func() {
+		defer killer.Done()
+		select {
+		case <-ctx.Done():
+			for _, cmd := range cmds[:started] {
+				cmd.Process.Kill()
+			}
+		case <-waited:
+		}
+	}()
+
+	var errs []error
+	for i, cmd := range cmds[:started] {
+		err := cmd.Wait()
+		res.ExitCodes[i] = cmd.ProcessState.ExitCode()
+		if err != nil && startErr == nil {
+			errs = append(errs, stageError(i, cmd, err))
+		}
+	}
+	close(waited)
+	killer.Wait()
+	res.Output = out.Bytes()
+
+	if startErr != nil {
+		return res, startErr
+	}
+	if ctx.Err() != nil {
+		errs = append([]error{ctx.Err()}, errs...)
+	}
+	return res, errors.Join(errs...)
+}
+
+func stageError(i int, cmd *exec.Cmd, err error) error {
+	return &StageError{Stage: i + 1, Args: strings.Join(cmd.Args, " "), Err: err}
+}
+
+// syncBuffer is a bytes.Buffer the copying goroutines of exec.Cmd can write to at the same time
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
@@ -0,0 +1,151 @@
+package pipe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sh returns a command running script with sh, skipping the test without sh
+func sh(t *testing.T, script string) *exec.Cmd {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	return exec.Command("sh", "-c", script)
+}
+
+func TestPipeline(t *testing.T) {
+	res, err := Pipeline(context.Background(), strings.NewReader("b hello\na bye\nc hello\na hello\n"),
+		sh(t, "grep hello"),
+		sh(t, "sort"),
+		sh(t, "tr a-z A-Z"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "A HELLO\nB HELLO\nC HELLO\n"; string(res.Output) != want {
+		t.Errorf("output = %q; want %q", res.Output, want)
+	}
+	if !slices.Equal(res.ExitCodes, []int{0, 0, 0}) {
+		t.Errorf("exit codes = %v", res.ExitCodes)
+	}
+}
+
+func TestSingleCommandAndNoInput(t *testing.T) {
+	res, err := Pipeline(context.Background(), nil, sh(t, "cat; echo done"))
+	if err != nil || string(res.Output) != "done\n" {
+		t.Errorf("output = %q, %v", res.Output, err)
+	}
+}
+
+func TestCombinedOutput(t *testing.T) {
+	res, err := Pipeline(context.Background(), nil,
+		sh(t, "echo first >&2; echo data"),
+		sh(t, "sleep 0.1; cat; echo second >&2"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "first\ndata\nsecond\n"; string(res.Output) != want {
+		t.Errorf("output = %q; want %q", res.Output, want)
+	}
+
+	// an Stdout or Stderr set by the caller is left alone
+	var stdout, stderr bytes.Buffer
+	last := sh(t, "cat; echo oops >&2")
+	last.Stdout, last.Stderr = &stdout, &stderr
+	res, err = Pipeline(context.Background(), strings.NewReader("in\n"), sh(t, "cat"), last)
+	if err != nil || len(res.Output) != 0 || stdout.String() != "in\n" || stderr.String() != "oops\n" {
+		t.Errorf("output = %q, stdout = %q, stderr = %q, %v", res.Output, stdout.String(), stderr.String(), err)
+	}
+}
+
+func TestStageErrors(t *testing.T) {
+	res, err := Pipeline(context.Background(), strings.NewReader("x\n"),
+		sh(t, "cat; exit 3"),
+		sh(t, "cat"),
+		sh(t, "cat >/dev/null; echo broken >&2; exit 5"),
+	)
+	if !slices.Equal(res.ExitCodes, []int{3, 0, 5}) {
+		t.Errorf("exit codes = %v", res.ExitCodes)
+	}
+	var se *StageError
+	if !errors.As(err, &se) || se.Stage != 1 || se.Args != "sh -c cat; exit 3" {
+		t.Fatalf("err = %v; want a StageError for stage 1", err)
+	}
+	want := "stage 1 (sh -c cat; exit 3): exit status 3\nstage 3 (sh -c cat >/dev/null; echo broken >&2; exit 5): exit status 5"
+	if err.Error() != want {
+		t.Errorf("err = %q; want %q", err, want)
+	}
+	if string(res.Output) != "broken\n" {
+		t.Errorf("output = %q", res.Output)
+	}
+}
+
+func TestStartError(t *testing.T) {
+	// the first stage is started, then killed when the second can't start
+	res, err := Pipeline(context.Background(), nil, sh(t, "sleep 30"), exec.Command("/nonexistent/program"))
+	var se *StageError
+	if !errors.As(err, &se) || se.Stage != 2 {
+		t.Fatalf("err = %v; want a StageError for stage 2", err)
+	}
+	if !slices.Equal(res.ExitCodes, []int{-1, -1}) {
+		t.Errorf("exit codes = %v", res.ExitCodes)
+	}
+
+	var tests = []struct {
+		name string
+		cmds []*exec.Cmd
+		want string
+	}{
+		{"no commands", nil, "no commands"},
+		{"stdin set", []*exec.Cmd{{Path: "cat", Args: []string{"cat"}, Stdin: strings.NewReader("")}}, "stage 1 (cat): Stdin is already set"},
+		{"stdout set in the middle", []*exec.Cmd{{Path: "cat", Args: []string{"cat"}, Stdout: &bytes.Buffer{}}, {Path: "cat", Args: []string{"cat"}}}, "feeds the next command"},
+	}
+	for _, tt := range tests {
+		if _, err := Pipeline(context.Background(), nil, tt.cmds...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v; want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	// every stage would run for a minute
+	res, err := Pipeline(ctx, nil, sh(t, "exec sleep 60"), sh(t, "exec sleep 60"), sh(t, "exec cat"))
+	if d := time.Since(begin); d > 10*time.Second {
+		t.Errorf("the pipeline ran for %v after the deadline", d)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v; want context.DeadlineExceeded", err)
+	}
+	if !slices.Equal(res.ExitCodes, []int{-1, -1, -1}) {
+		t.Errorf("exit codes = %v; want all killed", res.ExitCodes)
+	}
+	var se *StageError
+	if !errors.As(err, &se) || !strings.Contains(se.Error(), "signal: killed") {
+		t.Errorf("err = %v; want the stages killed", err)
+	}
+}
+
+func TestGrandchildKeepsOutputOpen(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	// the shell is killed, the sleep it started in the background keeps its stderr and the pipe to cat open
+	res, err := Pipeline(ctx, nil, sh(t, "sleep 20 & wait"), sh(t, "exec cat"))
+	if d := time.Since(begin); d > 10*time.Second {
+		t.Errorf("Wait waited %v for the grandchild", d)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !slices.Equal(res.ExitCodes, []int{-1, -1}) {
+		t.Errorf("exit codes %v, err = %v; want the stages killed", res.ExitCodes, err)
+	}
+}
@@ -0,0 +1,280 @@
+// Package plantapi serves the helper.Plant type as a REST resource:
+//
+//	GET    /plants       list the plants, a page at a time
+//	POST   /plants       create a plant
+//	GET    /plants/{id}  get a single plant
+//	PUT    /plants/{id}  replace a plant
+//	DELETE /plants/{id}  delete a plant
+//
+// responses are encoded as json or xml depending on the Accept header, using the struct tags of helper.Plant.
+// request bodies are decoded according to their Content-Type
+package plantapi
+
+import (
+	"RobotTask/helper"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PageSize is the number of plants returned by a single list request
+const PageSize = 20
+
+// maxBodySize caps request bodies, a plant is a few hundred bytes at most
+const maxBodySize = 1 << 20
+
+// PlantPage is one page of the plant list. like response2 in the helper package,
+// the page number is encoded under the "page" key
+type PlantPage struct {
+	XMLName xml.Name       `xml:"plants" json:"-"`
+	Page    int            `xml:"page,attr" json:"page"`
+	Total   int            `xml:"total,attr" json:"total"`
+	Plants  []helper.Plant `xml:"plant" json:"plants"`
+}
+
+// apiError is the body of an error response
+type apiError struct {
+	XMLName xml.Name `xml:"error" json:"-"`
+	Message string   `xml:",chardata" json:"error"`
+}
+
+// format is a supported encoding of the responses
+type format int
+
+const (
+	formatJSON format = iota
+	formatXML
+)
+
+var contentTypes = map[format]string{
+	formatJSON: "application/json",
+	formatXML:  "application/xml",
+}
+
+// NewHandler returns the handler for the plant resource.
+// it uses the method and wildcard patterns of http.ServeMux introduced in go 1.22
+func NewHandler(store *Store) http.Handler {
+	h := &handler{store: store}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /plants", h.list)
+	mux.HandleFunc("POST /plants", h.create)
+	mux.HandleFunc("GET /plants/{id}", h.get)
+	mux.HandleFunc("PUT /plants/{id}", h.replace)
+	mux.HandleFunc("DELETE /plants/{id}", h.delete)
+	return mux
+}
+
+type handler struct {
+	store *Store
+}
+
+func (h *handler) list(w http.ResponseWriter, req *http.Request) {
+	page := 1
+	if s := req.URL.Query().Get("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			writeError(w, req, http.StatusBadRequest, "page must be a positive integer")
+			return
+		}
+		page = n
+	}
+	plants := h.store.List()
+	start := min((page-1)*PageSize, len(plants))
+	end := min(start+PageSize, len(plants))
+	write(w, req, http.StatusOK, PlantPage{Page: page, Total: len(plants), Plants: plants[start:end]})
+}
+
+func (h *handler) create(w http.ResponseWriter, req *http.Request) {
+	p, ok := readPlant(w, req)
+	if !ok {
+		return
+	}
+	p = h.store.Create(p)
+	w.Header().Set("Location", fmt.Sprintf("/plants/%d", p.Id))
+	write(w, req, http.StatusCreated, p)
+}
+
+func (h *handler) get(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req)
+	if !ok {
+		return
+	}
+	p, err := h.store.Get(id)
+	if err != nil {
+		writeStoreError(w, req, err)
+		return
+	}
+	write(w, req, http.StatusOK, p)
+}
+
+func (h *handler) replace(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req)
+	if !ok {
+		return
+	}
+	p, ok := readPlant(w, req)
+	if !ok {
+		return
+	}
+	// the id in the path wins over the one in the body
+	p, err := h.store.Replace(id, p)
+	if err != nil {
+		writeStoreError(w, req, err)
+		return
+	}
+	write(w, req, http.StatusOK, p)
+}
+
+func (h *handler) delete(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req)
+	if !ok {
+		return
+	}
+	if err := h.store.Delete(id); err != nil {
+		writeStoreError(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pathID parses the {id} wildcard, writing a 400 response if it isn't a number
+func pathID(w http.ResponseWriter, req *http.Request) (int, bool) {
+	id, err := strconv.Atoi(req.PathValue("id"))
+	if err != nil {
+		writeError(w, req, http.StatusBadRequest, "id must be an integer")
+		return 0, false
+	}
+	return id, true
+}
+
+/************************* encoding ********************************/
+
+// mediaTypes are the types each format answers to, the first one is the content type sent
+var mediaTypes = map[format][]string{
+	formatJSON: {"application/json"},
+	formatXML:  {"application/xml", "text/xml"},
+}
+
+// negotiate picks the response format from the Accept header.
+// each media type takes the q of the most specific media range matching it: application/json over application/* over */*,
+// so "application/json;q=0, */*" refuses json even though */* would take it. a format takes the best q of its media types,
+// the highest q wins and json is used when the client has no preference. the bool is false if the client accepts neither json nor xml
+func negotiate(accept string) (format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, true
+	}
+	type match struct {
+		q           float64
+		specificity int // 3 for type/subtype, 2 for type/*, 1 for */*, 0 for no match
+		pos         int // index of the media range, the first one wins a tie
+	}
+	matches := map[string]match{}
+	for pos, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		for _, types := range mediaTypes {
+			for _, t := range types {
+				// the more specific range decides, the first one among equally specific ones
+				if spec := specificity(mediaType, t); spec > matches[t].specificity {
+					matches[t] = match{q, spec, pos}
+				}
+			}
+		}
+	}
+	best, bestMatch := formatJSON, match{}
+	for _, f := range []format{formatJSON, formatXML} {
+		for _, t := range mediaTypes[f] {
+			m := matches[t]
+			if m.q > bestMatch.q || m.q > 0 && m.q == bestMatch.q && m.pos < bestMatch.pos {
+				best, bestMatch = f, m
+			}
+		}
+	}
+	return best, bestMatch.q > 0
+}
+
+// specificity tells how closely the media range r matches the media type t, 0 if it doesn't
+func specificity(r, t string) int {
+	switch {
+	case r == t:
+		return 3
+	case r == "*/*":
+		return 1
+	case strings.HasSuffix(r, "/*") && strings.HasPrefix(t, strings.TrimSuffix(r, "*")):
+		return 2
+	}
+	return 0
+}
+
+// write encodes v in the format asked for by the client
+func write(w http.ResponseWriter, req *http.Request, status int, v any) {
+	f, ok := negotiate(req.Header.Get("Accept"))
+	if !ok {
+		// there is no format the client accepts, so the error itself is sent as plain text
+		http.Error(w, "only application/json and application/xml are supported", http.StatusNotAcceptable)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypes[f]+"; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	if f == formatXML {
+		io.WriteString(w, xml.Header)
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		enc.Encode(v)
+		io.WriteString(w, "\n")
+		return
+	}
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, req *http.Request, status int, msg string) {
+	write(w, req, status, apiError{Message: msg})
+}
+
+func writeStoreError(w http.ResponseWriter, req *http.Request, err error) {
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, req, http.StatusNotFound, err.Error())
+		return
+	}
+	writeError(w, req, http.StatusInternalServerError, err.Error())
+}
+
+// readPlant decodes the request body as json or xml according to its Content-Type, json being the default.
+// it writes the error response itself and returns false if the body can't be used
+func readPlant(w http.ResponseWriter, req *http.Request) (helper.Plant, bool) {
+	var p helper.Plant
+	body := http.MaxBytesReader(w, req.Body, maxBodySize)
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	var err error
+	switch mediaType {
+	case "application/xml", "text/xml":
+		err = xml.NewDecoder(body).Decode(&p)
+	case "", "application/json":
+		dec := json.NewDecoder(body)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&p)
+	default:
+		writeError(w, req, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported content type %q", mediaType))
+		return p, false
+	}
+	if err != nil {
+		writeError(w, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return p, false
+	}
+	return p, true
+}
@@ -0,0 +1,172 @@
+package plantapi
+
+import (
+	"RobotTask/helper"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newTestHandler() http.Handler {
+	return NewHandler(NewStore(helper.Plant{Id: 27, Name: "Coffee", Origin: []string{"Ethiopia", "Brazil"}}))
+}
+
+// do sends a request to h and returns the recorded response
+func do(h http.Handler, method, target, accept, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNegotiate(t *testing.T) {
+	var tests = []struct {
+		accept string
+		want   format
+		ok     bool
+	}{
+		{"", formatJSON, true},
+		{"application/json", formatJSON, true},
+		{"application/xml", formatXML, true},
+		{"text/xml", formatXML, true},
+		{"*/*", formatJSON, true},
+		{"application/xml, application/json", formatXML, true},
+		{"application/json;q=0.5, application/xml", formatXML, true},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", formatXML, true},
+		{"text/html", formatJSON, false},
+		{"application/json;q=0", formatJSON, false},
+		{"application/json;q=0, */*", formatXML, true},              // the specific range refuses json, the wildcard doesn't override it
+		{"application/*;q=0.5, application/json", formatJSON, true}, // the specific range wins over the wildcard
+		{"application/xml;q=0, text/xml", formatXML, true},
+		{"application/*;q=0, text/plain", formatJSON, false},
+		{"text/*", formatXML, true},
+		{"*/*;q=0.5, application/xml", formatXML, true},
+	}
+	for _, tt := range tests {
+		got, ok := negotiate(tt.accept)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("negotiate(%q) = %v, %t; want %v, %t", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGetJSONAndXML(t *testing.T) {
+	h := newTestHandler()
+
+	rec := do(h, "GET", "/plants/27", "", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET json status = %d", rec.Code)
+	}
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"id":27,"name":"Coffee","origin":["Ethiopia","Brazil"]}`; got != want {
+		t.Errorf("json body = %s; want %s", got, want)
+	}
+
+	rec = do(h, "GET", "/plants/27", "application/xml", "", "")
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Errorf("Content-Type = %q; want application/xml", ct)
+	}
+	// the xml tags of Plant are used: id is an attribute, origin a repeated element
+	var p helper.Plant
+	if err := xml.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode xml: %v\n%s", err, rec.Body)
+	}
+	if p.Id != 27 || p.Name != "Coffee" || !slices.Equal(p.Origin, []string{"Ethiopia", "Brazil"}) {
+		t.Errorf("xml plant = %v", p)
+	}
+	if !strings.Contains(rec.Body.String(), `<plant id="27">`) {
+		t.Errorf("xml body doesn't use the id attribute:\n%s", rec.Body)
+	}
+}
+
+func TestCRUD(t *testing.T) {
+	h := newTestHandler()
+
+	// create from an xml body, answer in json
+	rec := do(h, "POST", "/plants", "application/json", "application/xml",
+		`<plant><name>Tomato</name><origin>Mexico</origin><origin>California</origin></plant>`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/plants/28" {
+		t.Fatalf("POST = %d, Location %q\n%s", rec.Code, rec.Header().Get("Location"), rec.Body)
+	}
+
+	rec = do(h, "PUT", "/plants/28", "", "application/json", `{"id":1,"name":"Tomato","origin":["Peru"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT = %d\n%s", rec.Code, rec.Body)
+	}
+	var p helper.Plant
+	json.Unmarshal(rec.Body.Bytes(), &p)
+	if p.Id != 28 || !slices.Equal(p.Origin, []string{"Peru"}) {
+		t.Errorf("PUT result = %v; the path id must win", p)
+	}
+
+	rec = do(h, "GET", "/plants", "", "", "")
+	var page PlantPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if page.Page != 1 || page.Total != 2 || len(page.Plants) != 2 || page.Plants[0].Id != 27 {
+		t.Errorf("list = %+v", page)
+	}
+
+	if rec = do(h, "DELETE", "/plants/27", "", "", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE = %d", rec.Code)
+	}
+	if rec = do(h, "GET", "/plants/27", "", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET after DELETE = %d; want 404", rec.Code)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	h := newTestHandler()
+	var tests = []struct {
+		name                                string
+		method, target, accept, ctype, body string
+		want                                int
+	}{
+		{"missing plant", "GET", "/plants/1", "", "", "", http.StatusNotFound},
+		{"bad id", "GET", "/plants/abc", "", "", "", http.StatusBadRequest},
+		{"bad page", "GET", "/plants?page=0", "", "", "", http.StatusBadRequest},
+		{"replace missing", "PUT", "/plants/1", "", "", `{"name":"x"}`, http.StatusNotFound},
+		{"delete missing", "DELETE", "/plants/1", "", "", "", http.StatusNotFound},
+		{"bad json", "POST", "/plants", "", "", `{"name":`, http.StatusBadRequest},
+		{"unknown field", "POST", "/plants", "", "", `{"colour":"green"}`, http.StatusBadRequest},
+		{"unsupported body", "POST", "/plants", "", "text/plain", "Coffee", http.StatusUnsupportedMediaType},
+		{"not acceptable", "GET", "/plants/27", "text/html", "", "", http.StatusNotAcceptable},
+		{"method not allowed", "PATCH", "/plants/27", "", "", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(h, tt.method, tt.target, tt.accept, tt.ctype, tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d; want %d\n%s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	// errors are negotiated like any other response
+	rec := do(h, "GET", "/plants/1", "application/xml", "", "")
+	if !strings.Contains(rec.Body.String(), "<error>plantapi: plant not found</error>") {
+		t.Errorf("xml error body = %s", rec.Body)
+	}
+}
+
+func TestPaging(t *testing.T) {
+	store := NewStore()
+	for range PageSize + 5 {
+		store.Create(helper.Plant{Name: "Fern"})
+	}
+	rec := do(NewHandler(store), "GET", "/plants?page=2", "", "", "")
+	var page PlantPage
+	json.Unmarshal(rec.Body.Bytes(), &page)
+	if page.Page != 2 || page.Total != PageSize+5 || len(page.Plants) != 5 || page.Plants[0].Id != PageSize+1 {
+		t.Errorf("page 2 = page %d, total %d, %d plants", page.Page, page.Total, len(page.Plants))
+	}
+}
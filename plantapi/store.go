@@ -0,0 +1,93 @@
+package plantapi
+
+import (
+	"RobotTask/helper"
+	"cmp"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// ErrNotFound is returned when no plant has the requested id
+var ErrNotFound = errors.New("plantapi: plant not found")
+
+// Store keeps plants in memory. like the Container in the goroutine example,
+// a mutex guards the map so the store can be used from many handlers at once.
+// note that a Store must not be copied, pass it around by pointer
+type Store struct {
+	mu     sync.RWMutex
+	nextID int
+	plants map[int]helper.Plant
+}
+
+// NewStore returns a store holding the given plants, keeping their ids
+func NewStore(plants ...helper.Plant) *Store {
+	s := &Store{nextID: 1, plants: make(map[int]helper.Plant)}
+	for _, p := range plants {
+		s.plants[p.Id] = clonePlant(p)
+		s.nextID = max(s.nextID, p.Id+1)
+	}
+	return s
+}
+
+// clonePlant copies the origin slice, so callers can't change a stored plant through it
+func clonePlant(p helper.Plant) helper.Plant {
+	p.Origin = slices.Clone(p.Origin)
+	return p
+}
+
+// List returns all the plants sorted by id
+func (s *Store) List() []helper.Plant {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	plants := make([]helper.Plant, 0, len(s.plants))
+	for _, p := range s.plants {
+		plants = append(plants, clonePlant(p))
+	}
+	slices.SortFunc(plants, func(a, b helper.Plant) int { return cmp.Compare(a.Id, b.Id) })
+	return plants
+}
+
+// Get returns the plant with the given id
+func (s *Store) Get(id int) (helper.Plant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.plants[id]
+	if !ok {
+		return helper.Plant{}, ErrNotFound
+	}
+	return clonePlant(p), nil
+}
+
+// Create stores p under a new id, ignoring p.Id, and returns the stored plant
+func (s *Store) Create(p helper.Plant) helper.Plant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.Id = s.nextID
+	s.nextID++
+	s.plants[p.Id] = clonePlant(p)
+	return clonePlant(p)
+}
+
+// Replace overwrites the plant with the given id, which must already exist
+func (s *Store) Replace(id int, p helper.Plant) (helper.Plant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.plants[id]; !ok {
+		return helper.Plant{}, ErrNotFound
+	}
+	p.Id = id
+	s.plants[id] = clonePlant(p)
+	return clonePlant(p), nil
+}
+
+// Delete removes the plant with the given id
+func (s *Store) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.plants[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.plants, id)
+	return nil
+}
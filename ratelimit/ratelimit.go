@@ -0,0 +1,266 @@
+// Package ratelimit implements a token bucket rate limiter, the reusable form of the
+// limiter and burstyLimiter channels from the goroutine example.
+// a bucket holds up to burst tokens and gains one token every interval, each request takes one token.
+// unlike a time.Tick based limiter there is no background goroutine or ticker to leak,
+// tokens are computed from the elapsed time whenever the limiter is used.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by the limiters.
+// tests can provide a fake one so they don't have to sleep, clock.Clock and clock.Fake satisfy it
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the default Clock, backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RateLimiter is a token bucket. it is safe for concurrent use
+type RateLimiter struct {
+	mu     sync.Mutex
+	clock  Clock
+	every  time.Duration // time it takes to gain one token, 0 or less means no limit
+	burst  int           // maximum number of tokens in the bucket
+	tokens float64       // can be negative when requests are waiting for future tokens
+	last   time.Time     // last time tokens were updated
+}
+
+// New returns a limiter that allows one request every interval on average,
+// with bursts of up to burst requests. the bucket starts full
+func New(every time.Duration, burst int) *RateLimiter {
+	return NewWithClock(every, burst, realClock{})
+}
+
+// NewWithClock is like New but reads time from clock
+func NewWithClock(every time.Duration, burst int, clock Clock) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		clock:  clock,
+		every:  every,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// advance adds the tokens gained since the last update, up to burst. mu must be held
+func (l *RateLimiter) advance(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 && l.every > 0 {
+		l.tokens += float64(elapsed) / float64(l.every)
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+	}
+	l.last = now
+}
+
+// SetRate changes the interval between tokens, tokens gained so far are kept
+func (l *RateLimiter) SetRate(every time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.clock.Now())
+	l.every = every
+}
+
+// SetBurst changes the size of the bucket. if the bucket holds more tokens than the new size they are dropped
+func (l *RateLimiter) SetBurst(burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.clock.Now())
+	l.burst = burst
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+}
+
+// Allow reports whether a request may happen now, taking a token if it does
+func (l *RateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.every <= 0 {
+		return true
+	}
+	l.advance(l.clock.Now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Reservation is a token taken ahead of time, the request may happen once Delay has passed
+type Reservation struct {
+	lim *RateLimiter
+	at  time.Time // time at which the token becomes available
+}
+
+// Reserve takes a token right away, even if it is only available in the future.
+// the caller must wait for Delay before acting, or Cancel the reservation
+func (l *RateLimiter) Reserve() *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if l.every <= 0 {
+		return &Reservation{lim: l, at: now}
+	}
+	l.advance(now)
+	l.tokens--
+	r := &Reservation{lim: l, at: now}
+	if l.tokens < 0 {
+		// the missing part of a token is gained in -tokens intervals
+		r.at = now.Add(time.Duration(-l.tokens * float64(l.every)))
+	}
+	return r
+}
+
+// Delay returns how long to wait before acting on the reservation
+func (r *Reservation) Delay() time.Duration {
+	return max(r.at.Sub(r.lim.clock.Now()), 0)
+}
+
+// Cancel gives the token back if the reservation hasn't become due yet,
+// so that the requests behind it don't have to wait for it
+func (r *Reservation) Cancel() {
+	l := r.lim
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if l.every <= 0 || !r.at.After(now) {
+		return
+	}
+	l.advance(now)
+	l.tokens++
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	r.at = now // cancelling twice is a no-op
+}
+
+// Wait blocks until a request may happen or ctx is done.
+// if ctx is done first, the reserved token is given back and ctx's error is returned
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := l.Reserve()
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	select {
+	case <-l.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// KeyedLimiter keeps a separate RateLimiter per key, for example one per client address.
+// limiters that haven't been used for the idle duration are evicted,
+// the sweep happens while the limiter is used so no background goroutine is needed
+type KeyedLimiter[K comparable] struct {
+	mu        sync.Mutex
+	clock     Clock
+	every     time.Duration
+	burst     int
+	idle      time.Duration
+	lastSweep time.Time
+	limiters  map[K]*keyedEntry
+}
+
+type keyedEntry struct {
+	lim      *RateLimiter
+	lastUsed time.Time
+}
+
+// NewKeyed returns a KeyedLimiter whose limiters are created with New(every, burst)
+// and evicted after being idle for the given duration
+func NewKeyed[K comparable](every time.Duration, burst int, idle time.Duration) *KeyedLimiter[K] {
+	return NewKeyedWithClock[K](every, burst, idle, realClock{})
+}
+
+// NewKeyedWithClock is like NewKeyed but reads time from clock
+func NewKeyedWithClock[K comparable](every time.Duration, burst int, idle time.Duration, clock Clock) *KeyedLimiter[K] {
+	return &KeyedLimiter[K]{
+		clock:     clock,
+		every:     every,
+		burst:     burst,
+		idle:      idle,
+		lastSweep: clock.Now(),
+		limiters:  make(map[K]*keyedEntry),
+	}
+}
+
+// Get returns the limiter for key, creating it if needed
+func (k *KeyedLimiter[K]) Get(key K) *RateLimiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := k.clock.Now()
+	// sweeping at most once per idle period keeps Get cheap
+	if k.idle > 0 && now.Sub(k.lastSweep) >= k.idle {
+		k.evict(now)
+	}
+	e, ok := k.limiters[key]
+	if !ok {
+		e = &keyedEntry{lim: NewWithClock(k.every, k.burst, k.clock)}
+		k.limiters[key] = e
+	}
+	e.lastUsed = now
+	return e.lim
+}
+
+// Allow reports whether a request for key may happen now
+func (k *KeyedLimiter[K]) Allow(key K) bool {
+	return k.Get(key).Allow()
+}
+
+// Wait blocks until a request for key may happen or ctx is done
+func (k *KeyedLimiter[K]) Wait(ctx context.Context, key K) error {
+	return k.Get(key).Wait(ctx)
+}
+
+// EvictIdle removes the limiters that have been idle for longer than the idle duration
+// and returns how many were removed
+func (k *KeyedLimiter[K]) EvictIdle() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.evict(k.clock.Now())
+}
+
+// evict removes idle limiters. mu must be held
+func (k *KeyedLimiter[K]) evict(now time.Time) int {
+	k.lastSweep = now
+	if k.idle <= 0 {
+		return 0
+	}
+	n := 0
+	for key, e := range k.limiters {
+		if now.Sub(e.lastUsed) >= k.idle {
+			delete(k.limiters, key)
+			n++
+		}
+	}
+	return n
+}
+
+// Len returns the number of keys currently tracked
+func (k *KeyedLimiter[K]) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
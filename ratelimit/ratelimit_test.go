@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"RobotTask/clock"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2009, 11, 17, 20, 34, 58, 0, time.UTC)
+
+// this is the burstyLimiter scenario from the goroutine example:
+// the first three requests go through right away, the next ones are 200ms apart
+func TestBurstyBehavior(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	start := clk.Now()
+	lim := NewWithClock(200*time.Millisecond, 3, clk)
+
+	var got []time.Duration
+	for range 5 {
+		r := lim.Reserve()
+		// act on the reservation at the earliest possible time
+		clk.Advance(r.Delay())
+		got = append(got, clk.Now().Sub(start))
+	}
+	want := []time.Duration{0, 0, 0, 200 * time.Millisecond, 400 * time.Millisecond}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d served at %v; want %v", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	lim := NewWithClock(time.Second, 2, clk)
+
+	var tests = []struct {
+		advance time.Duration
+		want    bool
+	}{
+		{0, true},
+		{0, true},
+		{0, false}, // the bucket is empty
+		{500 * time.Millisecond, false},
+		{500 * time.Millisecond, true}, // one token gained after a full second
+		{10 * time.Second, true},       // a long pause only refills up to burst
+		{0, true},
+		{0, false},
+	}
+	for i, tt := range tests {
+		clk.Advance(tt.advance)
+		if got := lim.Allow(); got != tt.want {
+			t.Errorf("step %d: Allow() = %t; want %t", i, got, tt.want)
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	lim := NewWithClock(200*time.Millisecond, 1, clk)
+
+	if err := lim.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- lim.Wait(context.Background()) }()
+	clk.BlockUntil(1)
+	clk.Advance(100 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Wait returned before the token was available")
+	default:
+	}
+	clk.Advance(100 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Errorf("Wait: %v", err)
+	}
+}
+
+func TestWaitCancelGivesTokenBack(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	lim := NewWithClock(time.Second, 1, clk)
+	lim.Allow() // empty the bucket
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- lim.Wait(ctx) }()
+	clk.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v; want %v", err, context.Canceled)
+	}
+
+	// the cancelled reservation must not delay the next request
+	clk.Advance(time.Second)
+	if !lim.Allow() {
+		t.Error("Allow() = false after the cancelled Wait gave its token back")
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	lim := NewWithClock(time.Second, 1, clk)
+	lim.Allow()
+
+	lim.SetRate(100 * time.Millisecond)
+	clk.Advance(100 * time.Millisecond)
+	if !lim.Allow() {
+		t.Error("Allow() = false after SetRate made tokens faster")
+	}
+
+	lim.SetBurst(3)
+	clk.Advance(time.Second)
+	for i := range 3 {
+		if !lim.Allow() {
+			t.Errorf("request %d of the new burst was refused", i+1)
+		}
+	}
+	if lim.Allow() {
+		t.Error("Allow() = true past the new burst")
+	}
+
+	// no rate means no limit
+	lim.SetRate(0)
+	for range 10 {
+		if !lim.Allow() {
+			t.Fatal("Allow() = false without a rate")
+		}
+	}
+}
+
+func TestKeyedLimiter(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	k := NewKeyedWithClock[string](time.Second, 1, time.Minute, clk)
+
+	if !k.Allow("a") || !k.Allow("b") {
+		t.Fatal("first request of each key should be allowed")
+	}
+	// keys have separate buckets
+	if k.Allow("a") {
+		t.Error("second request for a was allowed")
+	}
+	if k.Len() != 2 {
+		t.Errorf("Len() = %d; want 2", k.Len())
+	}
+
+	clk.Advance(30 * time.Second)
+	k.Allow("a") // a stays active, b goes idle
+	clk.Advance(45 * time.Second)
+	if n := k.EvictIdle(); n != 1 {
+		t.Errorf("EvictIdle() = %d; want 1", n)
+	}
+	if k.Len() != 1 {
+		t.Errorf("Len() after eviction = %d; want 1", k.Len())
+	}
+
+	// Get also sweeps idle keys once per idle period
+	clk.Advance(2 * time.Minute)
+	k.Get("c")
+	if k.Len() != 1 {
+		t.Errorf("Len() after sweep = %d; want 1", k.Len())
+	}
+}
@@ -1,22 +1,29 @@
-package main
+// Package recoverexample recovers from a panic in a deferred function
+package recoverexample
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"io"
+)
 
 // this function panics
 func mayPanic() {
 	panic("a problem")
 }
 
-func main() {
+// Run panics and recovers, printing the recovered value to w
+func Run(ctx context.Context, w io.Writer) error {
 	//recover must be called within a deferred function. when the enclosing function panics,
 	// the defer will activate and a recover call within it will catch the panic
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Println("Recovered. Error:\n", r)
+			fmt.Fprintln(w, "Recovered. Error:\n", r)
 		}
 	}()
 
 	mayPanic()
 	// this line will not run, maypanic will panic and defer will run, thus this line is never reached
-	fmt.Println("After mayPanic()")
+	fmt.Fprintln(w, "After mayPanic()")
+	return nil
 }
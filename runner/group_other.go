@@ -0,0 +1,21 @@
+//go:build !unix
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup does nothing, there are no process groups
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killGroup kills p alone
+func killGroup(p *os.Process) error {
+	return p.Kill()
+}
+
+// signalOf returns nil, the processes end without signals
+func signalOf(state *os.ProcessState) os.Signal {
+	return nil
+}
@@ -0,0 +1,111 @@
+//go:build linux
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const limitsSupported = true
+
+// childEnv runs the binary as the helper that sets the limits on itself and execs the command.
+// its value is the descriptor for the errors, the seconds of processor time, the bytes of memory and the path of the command
+const childEnv = "RUNNER_CHILD"
+
+// the helper is the binary the runner is in, started again: it takes over before main runs
+func init() {
+	if spec, ok := os.LookupEnv(childEnv); ok {
+		limitChild(spec)
+	}
+}
+
+// limitCommand makes cmd start under the resource limits: it runs this binary as the helper, which sets them with
+// setrlimit and execs the command. the limits are in place before the command runs its first instruction, and the
+// processes it forks inherit them. check is called once Start returned, it waits for the exec and returns the error
+// of a limit the helper couldn't set or of the exec, which happen in the helper
+func limitCommand(cmd *exec.Cmd, cpu time.Duration, memory int64) (check func() error, err error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	var secs int64
+	if cpu > 0 {
+		secs = int64((cpu + time.Second - 1) / time.Second)
+	}
+	fd := 3 + len(cmd.ExtraFiles)
+	cmd.ExtraFiles = append(cmd.ExtraFiles, w)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d,%d,%d,%s", childEnv, fd, secs, memory, cmd.Path))
+	// the args stay, the helper passes them on
+	cmd.Path = "/proc/self/exe"
+	return func() error {
+		// the copy of the helper is closed by the exec, or when it exits: the read ends there
+		w.Close()
+		defer r.Close()
+		msg, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if len(msg) > 0 {
+			return errors.New(string(msg))
+		}
+		return nil
+	}, nil
+}
+
+// limitChild is the helper: it sets the limits of spec on the process, then replaces it with the command.
+// it never returns, a failure is written to the error descriptor and the helper exits
+func limitChild(spec string) {
+	parts := strings.SplitN(spec, ",", 4)
+	if len(parts) != 4 {
+		fmt.Fprintf(os.Stderr, "runner: bad %s %q\n", childEnv, spec)
+		os.Exit(127)
+	}
+	fd, err := strconv.Atoi(parts[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "runner: bad %s %q\n", childEnv, spec)
+		os.Exit(127)
+	}
+	errs := os.NewFile(uintptr(fd), "runner errors")
+	fail := func(err error) {
+		errs.WriteString(err.Error())
+		os.Exit(127)
+	}
+	secs, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		fail(fmt.Errorf("bad processor time %q", parts[1]))
+	}
+	memory, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		fail(fmt.Errorf("bad memory %q", parts[2]))
+	}
+	path := parts[3]
+	unix.CloseOnExec(fd)
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, childEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	if secs > 0 {
+		// SIGXCPU at the soft limit, which the process could catch, SIGKILL at the hard one
+		if err := unix.Setrlimit(unix.RLIMIT_CPU, &unix.Rlimit{Cur: secs, Max: secs + 1}); err != nil {
+			fail(fmt.Errorf("cpu limit: %w", err))
+		}
+	}
+	if memory > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_AS, &unix.Rlimit{Cur: memory, Max: memory}); err != nil {
+			fail(fmt.Errorf("memory limit: %w", err))
+		}
+	}
+	err = unix.Exec(path, os.Args, env)
+	fail(fmt.Errorf("exec %s: %w", path, err))
+}
@@ -0,0 +1,198 @@
+// Package runner runs a command to completion under limits, where the spawn example calls Output without any:
+//
+//	res, err := runner.Run(ctx, runner.Options{
+//		Timeout:   10 * time.Second,
+//		MaxStdout: 1 << 20,
+//		CPUTime:   5 * time.Second,
+//		Memory:    512 << 20,
+//		EnvAllow:  []string{"PATH", "LANG", "LC_*"},
+//	}, "bash", "-c", "ls -a -l -h")
+//
+// the command runs in a process group of its own, a timeout or a done ctx kills the whole group, the processes it started included.
+// the output beyond the caps is read and dropped, the child is never blocked on a full pipe, and a marker tells how much was cut.
+// the result has the exit code, the duration, the truncation flags and the signal that ended the process
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultMaxOutput caps stdout and stderr when Options leaves them at 0
+const DefaultMaxOutput = 1 << 20
+
+// Options are the limits and the environment of a run, the zero value has no timeout, an empty environment and outputs capped to DefaultMaxOutput
+type Options struct {
+	// Timeout ends the run when it is more than 0, on top of the deadline of ctx
+	Timeout time.Duration
+	// Dir is the working directory of the command, the one of the caller if empty
+	Dir string
+	// EnvAllow names the variables of the caller the command gets, a name ending with * matches a prefix like LC_*.
+	// nothing else is passed on, an empty EnvAllow and Env give an empty environment
+	EnvAllow []string
+	// Env are more variables, as "KEY=value", they win over the allowed ones
+	Env   []string
+	Stdin io.Reader
+	// MaxStdout and MaxStderr are the bytes kept of each output, DefaultMaxOutput if 0, no cap if less than 0
+	MaxStdout int64
+	MaxStderr int64
+	// CPUTime limits the processor time with RLIMIT_CPU, rounded up to seconds: SIGXCPU then SIGKILL a second later. 0 for no limit, linux only.
+	// the limits are set before the command starts, by the binary of the caller started again, which then execs the command:
+	// the processes it forks get them too
+	CPUTime time.Duration
+	// Memory limits the address space with RLIMIT_AS, in bytes: the allocations beyond it fail. 0 for no limit, linux only
+	Memory int64
+}
+
+// Result is the outcome of a run
+type Result struct {
+	// ExitCode is the exit status, -1 when a signal ended the process
+	ExitCode int
+	// Signal is the signal that ended the process, nil if it exited
+	Signal   os.Signal
+	Duration time.Duration
+	// Stdout and Stderr end with TruncatedMarker when they were cut at their cap
+	Stdout          []byte
+	Stderr          []byte
+	StdoutTruncated bool
+	StderrTruncated bool
+	// TimedOut is set when the timeout or the deadline of ctx killed the process
+	TimedOut bool
+}
+
+// TruncatedMarker ends an output cut at its cap, with the number of bytes dropped
+const TruncatedMarker = "\n[... %d bytes truncated]\n"
+
+// Run runs name with args under the options and waits for it to end. an exit status other than 0 is in the Result, not an error.
+// the error is for a command that couldn't start, a limit that couldn't be set, and ctx.Err() when ctx ended the run early,
+// the Result is filled in then too
+func Run(ctx context.Context, opts Options, name string, args ...string) (Result, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	if (opts.CPUTime > 0 || opts.Memory > 0) && !limitsSupported {
+		return Result{}, errors.New("runner: resource limits are only supported on linux")
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = opts.Dir
+	cmd.Env = Environ(os.Environ(), opts.EnvAllow, opts.Env)
+	cmd.Stdin = opts.Stdin
+	stdout := &capWriter{max: outputCap(opts.MaxStdout)}
+	stderr := &capWriter{max: outputCap(opts.MaxStderr)}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	setProcessGroup(cmd)
+	// the whole group goes, not only the command: a shell would leave its children running otherwise
+	cmd.Cancel = func() error { return killGroup(cmd.Process) }
+	// a process that left the group could keep the output open, Wait doesn't wait for it longer than this
+	cmd.WaitDelay = time.Second
+	var checkLimits func() error
+	if (opts.CPUTime > 0 || opts.Memory > 0) && cmd.Err == nil {
+		var err error
+		if checkLimits, err = limitCommand(cmd, opts.CPUTime, opts.Memory); err != nil {
+			return Result{ExitCode: -1}, fmt.Errorf("runner: %w", err)
+		}
+	}
+
+	start := time.Now()
+	startErr := cmd.Start()
+	if checkLimits != nil {
+		if err := checkLimits(); err != nil && startErr == nil {
+			cmd.Wait() // the helper has exited, the command never ran
+			return Result{ExitCode: -1}, fmt.Errorf("runner: %w", err)
+		}
+	}
+	if startErr != nil {
+		return Result{ExitCode: -1}, fmt.Errorf("runner: %w", startErr)
+	}
+	waitErr := cmd.Wait()
+
+	res := Result{
+		ExitCode: cmd.ProcessState.ExitCode(),
+		Signal:   signalOf(cmd.ProcessState),
+		Duration: time.Since(start),
+	}
+	res.Stdout, res.StdoutTruncated = stdout.result()
+	res.Stderr, res.StderrTruncated = stderr.result()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		res.TimedOut = errors.Is(ctxErr, context.DeadlineExceeded)
+		return res, ctxErr
+	}
+	var ee *exec.ExitError
+	if waitErr != nil && !errors.As(waitErr, &ee) {
+		// the process ended but its output couldn't be read in full, like exec.ErrWaitDelay
+		return res, fmt.Errorf("runner: %w", waitErr)
+	}
+	return res, nil
+}
+
+func outputCap(n int64) int64 {
+	if n == 0 {
+		return DefaultMaxOutput
+	}
+	return n
+}
+
+// Environ returns the variables of environ named in allow, a name ending with * matching a prefix, followed by env.
+// a variable of env replaces an allowed one with the same name
+func Environ(environ, allow, env []string) []string {
+	out := []string{} // not nil, exec.Cmd would pass the whole environment
+	set := map[string]bool{}
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		set[name] = true
+	}
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if !set[name] && allowed(name, allow) {
+			out = append(out, kv)
+		}
+	}
+	return append(out, env...)
+}
+
+func allowed(name string, allow []string) bool {
+	for _, a := range allow {
+		if prefix, ok := strings.CutSuffix(a, "*"); ok && strings.HasPrefix(name, prefix) || a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// capWriter keeps the first max bytes written to it and counts the others. exec.Cmd copies an output into it from a single goroutine
+type capWriter struct {
+	max     int64 // less than 0 for no cap
+	buf     bytes.Buffer
+	dropped int64
+}
+
+func (c *capWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if c.max >= 0 {
+		if room := c.max - int64(c.buf.Len()); int64(len(p)) > room {
+			c.dropped += int64(len(p)) - room
+			p = p[:room]
+		}
+	}
+	c.buf.Write(p)
+	return n, nil // the dropped bytes count as written, the child goes on as if nothing happened
+}
+
+// result returns the bytes kept, followed by TruncatedMarker if some were dropped
+func (c *capWriter) result() ([]byte, bool) {
+	if c.dropped == 0 {
+		return c.buf.Bytes(), false
+	}
+	fmt.Fprintf(&c.buf, TruncatedMarker, c.dropped)
+	return c.buf.Bytes(), true
+}
@@ -0,0 +1,231 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// needSh skips the test without sh
+func needSh(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+}
+
+// the commands find sh and the tools in PATH
+var withPath = Options{EnvAllow: []string{"PATH"}}
+
+func TestExitAndOutput(t *testing.T) {
+	needSh(t)
+	res, err := Run(context.Background(), withPath, "sh", "-c", "echo out; echo err >&2; exit 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ExitCode != 3 || res.Signal != nil || string(res.Stdout) != "out\n" || string(res.Stderr) != "err\n" {
+		t.Errorf("result = %+v", res)
+	}
+	if res.StdoutTruncated || res.StderrTruncated || res.TimedOut || res.Duration <= 0 {
+		t.Errorf("result = %+v", res)
+	}
+
+	opts := withPath
+	opts.Stdin = strings.NewReader("from stdin")
+	if res, err := Run(context.Background(), opts, "cat"); err != nil || string(res.Stdout) != "from stdin" {
+		t.Errorf("cat = %q, %v", res.Stdout, err)
+	}
+
+	if _, err := Run(context.Background(), withPath, "/nonexistent/program"); err == nil {
+		t.Error("a missing program started")
+	}
+}
+
+func TestOutputCaps(t *testing.T) {
+	needSh(t)
+	opts := withPath
+	opts.MaxStdout, opts.MaxStderr = 10, -1
+	res, err := Run(context.Background(), opts, "sh", "-c", "printf 0123456789abcdef; head -c 100000 /dev/zero >&2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0123456789" + fmt.Sprintf(TruncatedMarker, 6); string(res.Stdout) != want || !res.StdoutTruncated {
+		t.Errorf("stdout = %q, truncated %v; want %q", res.Stdout, res.StdoutTruncated, want)
+	}
+	if len(res.Stderr) != 100000 || res.StderrTruncated {
+		t.Errorf("stderr has %d bytes, truncated %v; want all 100000", len(res.Stderr), res.StderrTruncated)
+	}
+
+	// the child isn't blocked by the cap, it writes everything and exits normally
+	res, err = Run(context.Background(), withPath, "sh", "-c", "head -c 5000000 /dev/zero; echo done >&2")
+	if err != nil || res.ExitCode != 0 || !res.StdoutTruncated || string(res.Stderr) != "done\n" {
+		t.Errorf("large output: exit %d, truncated %v, stderr %q, %v", res.ExitCode, res.StdoutTruncated, res.Stderr, err)
+	}
+	if want := DefaultMaxOutput + len(fmt.Sprintf(TruncatedMarker, 5000000-DefaultMaxOutput)); len(res.Stdout) != want {
+		t.Errorf("stdout has %d bytes; want %d", len(res.Stdout), want)
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	got := Environ(
+		[]string{"PATH=/bin", "HOME=/root", "LC_ALL=C", "LC_TIME=en", "SECRET=x", "LANG=fr"},
+		[]string{"PATH", "LC_*", "LANG"},
+		[]string{"LANG=de", "EXTRA=1"},
+	)
+	if want := []string{"PATH=/bin", "LC_ALL=C", "LC_TIME=en", "LANG=de", "EXTRA=1"}; !slices.Equal(got, want) {
+		t.Errorf("Environ = %q; want %q", got, want)
+	}
+	if got := Environ([]string{"HOME=/root"}, nil, nil); got == nil || len(got) != 0 {
+		t.Errorf("Environ without allow = %#v; want empty, not nil", got)
+	}
+
+	needSh(t)
+	t.Setenv("RUNNER_TEST_SECRET", "hidden")
+	t.Setenv("RUNNER_TEST_ALLOWED", "shown")
+	opts := Options{EnvAllow: []string{"PATH", "RUNNER_TEST_A*"}, Env: []string{"ADDED=yes"}, Dir: t.TempDir()}
+	res, err := Run(context.Background(), opts, "sh", "-c", `echo "$RUNNER_TEST_SECRET|$RUNNER_TEST_ALLOWED|$ADDED"; pwd`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, _ := filepath.EvalSymlinks(opts.Dir)
+	if want := "|shown|yes\n" + dir + "\n"; string(res.Stdout) != want {
+		t.Errorf("stdout = %q; want %q", res.Stdout, want)
+	}
+}
+
+func TestTimeoutKillsTheGroup(t *testing.T) {
+	needSh(t)
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	opts := withPath
+	opts.Timeout = 200 * time.Millisecond
+	begin := time.Now()
+	// the shell starts a sleep in the background and waits for it, both must die
+	res, err := Run(context.Background(), opts, "sh", "-c", "sleep 60 & echo $! > "+pidFile+"; wait")
+	if !errors.Is(err, context.DeadlineExceeded) || !res.TimedOut {
+		t.Fatalf("err = %v, timed out %v; want a timeout", err, res.TimedOut)
+	}
+	if d := time.Since(begin); d > 10*time.Second {
+		t.Errorf("the run took %v", d)
+	}
+	if res.ExitCode != -1 || res.Signal != syscall.SIGKILL {
+		t.Errorf("exit %d, signal %v; want killed", res.ExitCode, res.Signal)
+	}
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	// the sleep was killed too, once its parent reaps it the pid is gone. it is a zombie until then, so poll
+	deadline := time.Now().Add(5 * time.Second)
+	for syscall.Kill(pid, 0) == nil {
+		if state, _ := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); strings.Contains(string(state), ") Z ") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the background sleep %d survived the timeout", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// a cancelled ctx is not a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	res, err = Run(ctx, withPath, "sleep", "60")
+	if !errors.Is(err, context.Canceled) || res.TimedOut {
+		t.Errorf("err = %v, timed out %v; want cancelled", err, res.TimedOut)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		if _, err := Run(context.Background(), Options{CPUTime: time.Second}, "true"); err == nil {
+			t.Error("limits accepted outside linux")
+		}
+		t.Skip("resource limits are linux only")
+	}
+	needSh(t)
+
+	t.Run("cpu", func(t *testing.T) {
+		opts := withPath
+		opts.CPUTime = time.Second
+		opts.Timeout = 30 * time.Second
+		res, err := Run(context.Background(), opts, "sh", "-c", "while :; do :; done")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Signal != syscall.SIGXCPU && res.Signal != syscall.SIGKILL {
+			t.Errorf("signal = %v; want SIGXCPU or SIGKILL, after %v", res.Signal, res.Duration)
+		}
+	})
+
+	t.Run("before the start", func(t *testing.T) {
+		opts := withPath
+		opts.CPUTime = 3 * time.Second
+		opts.Memory = 64 << 20
+		// the command and a child it forks right away see the limits from their first instruction
+		script := `ulimit -S -t; ulimit -H -t; ulimit -v; (ulimit -S -t; ulimit -v) & wait`
+		res, err := Run(context.Background(), opts, "sh", "-c", script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "3\n4\n65536\n3\n65536\n"; string(res.Stdout) != want || res.ExitCode != 0 {
+			t.Errorf("limits = %q, exit %d, stderr %q; want %q", res.Stdout, res.ExitCode, res.Stderr, want)
+		}
+
+		// the command keeps its arguments and is looked up in PATH before the shell takes over
+		res, err = Run(context.Background(), opts, "printf", "%s|", "a b", "$HOME", "")
+		if err != nil || string(res.Stdout) != "a b|$HOME||" {
+			t.Errorf("printf = %q, %v", res.Stdout, err)
+		}
+		if _, err := Run(context.Background(), opts, "no-such-program"); err == nil {
+			t.Error("a missing program started")
+		}
+	})
+
+	t.Run("helper", func(t *testing.T) {
+		opts := withPath
+		opts.Memory = 64 << 20
+		// the status 126 of the command is its own, the variable of the helper isn't passed on
+		res, err := Run(context.Background(), opts, "sh", "-c", "echo ${RUNNER_CHILD-unset}; exit 126")
+		if err != nil || res.ExitCode != 126 || string(res.Stdout) != "unset\n" {
+			t.Errorf("exit 126: %+v, %v", res, err)
+		}
+
+		// an exec that fails in the helper is an error, like it is for Start without limits
+		script := filepath.Join(t.TempDir(), "script")
+		if err := os.WriteFile(script, []byte("#!/nonexistent/interpreter\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		res, err = Run(context.Background(), opts, script)
+		if err == nil || !strings.Contains(err.Error(), "exec "+script) || res.ExitCode != -1 {
+			t.Errorf("bad interpreter: %+v, %v", res, err)
+		}
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		// a 40MB string in the shell, within the limit or not
+		script := `x=$(head -c 40000000 /dev/zero | tr '\0' a); echo ${#x}`
+		opts := withPath
+		if res, err := Run(context.Background(), opts, "sh", "-c", script); err != nil || string(res.Stdout) != "40000000\n" {
+			t.Fatalf("without limit: %+v, %v", res, err)
+		}
+		opts.Memory = 20 << 20
+		res, err := Run(context.Background(), opts, "sh", "-c", script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.ExitCode == 0 {
+			t.Errorf("the shell fit in 20MB: %q", res.Stdout)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+// Package sighub maps the signals of the process to callbacks, where the signal example waits for one and exits:
+//
+//	hub := &sighub.Hub{Timeout: 10 * time.Second}
+//	hub.OnReload(cfg.Reload)              // SIGHUP
+//	hub.OnShutdown("http", srv.Shutdown)  // SIGINT or SIGTERM, the hooks run in order
+//	hub.OnShutdown("db", db.Close)
+//	err := hub.Run(ctx)
+//
+// SIGUSR1 writes the goroutine stacks and the runtime stats to Dump. a second SIGINT while the hooks run
+// ends the process at once with ExitForced
+package sighub
+
+import (
+	"RobotTask/logging"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultTimeout bounds the shutdown hooks when Hub leaves Timeout at 0
+const DefaultTimeout = 10 * time.Second
+
+// ExitForced is the exit status after a second SIGINT, 128 + 2 like a shell reports a process killed by SIGINT
+const ExitForced = 130
+
+// ErrForced is returned by Run when a second SIGINT cut the shutdown short and Exit returned
+var ErrForced = errors.New("sighub: forced exit")
+
+// Hub runs the callbacks registered for the signals it receives, the zero value is ready to use
+type Hub struct {
+	// Timeout bounds all the shutdown hooks together, DefaultTimeout if 0
+	Timeout time.Duration
+	// Dump gets the goroutine stacks and the runtime stats on SIGUSR1, os.Stderr if nil
+	Dump io.Writer
+	// Exit ends the process on a second SIGINT, os.Exit if nil
+	Exit func(code int)
+
+	mu       sync.Mutex
+	handlers map[os.Signal][]func(context.Context)
+	hooks    []hook
+}
+
+type hook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// shutdownSignals start the shutdown hooks
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// Handle runs fn each time the process receives sig, after the callbacks registered before it.
+// the callbacks run one at a time, a slow one delays the signals behind it
+func (h *Hub) Handle(sig os.Signal, fn func(ctx context.Context)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.handlers == nil {
+		h.handlers = map[os.Signal][]func(context.Context){}
+	}
+	h.handlers[sig] = append(h.handlers[sig], fn)
+}
+
+// OnReload runs reload on SIGHUP, a failed reload is logged and the process keeps its current config
+func (h *Hub) OnReload(reload func(ctx context.Context) error) {
+	for _, sig := range reloadSignals {
+		h.Handle(sig, func(ctx context.Context) {
+			logger := logging.FromContext(ctx)
+			if err := reload(ctx); err != nil {
+				logger.Error("reload failed", "err", err)
+				return
+			}
+			logger.Info("reloaded")
+		})
+	}
+}
+
+// OnShutdown adds a hook run on SIGINT or SIGTERM, the hooks run one after the other in the order they were added.
+// ctx ends when the Timeout of the hub is over
+func (h *Hub) OnShutdown(name string, fn func(ctx context.Context) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, hook{name, fn})
+}
+
+// Run receives the signals until a shutdown is over or ctx is done, see Start
+func (h *Hub) Run(ctx context.Context) error {
+	return <-h.Start(ctx)
+}
+
+// Start registers for the signals before it returns, then receives them in the background. the channel gets the result
+// of the shutdown: nil, the errors of the hooks, or the deadline when they took longer than Timeout. it gets ctx.Err()
+// when ctx is done first, the hooks don't run then
+func (h *Hub) Start(ctx context.Context) <-chan error {
+	h.mu.Lock()
+	sigs := slices.Concat(shutdownSignals, dumpSignals)
+	for sig := range h.handlers {
+		if !slices.Contains(sigs, sig) {
+			sigs = append(sigs, sig)
+		}
+	}
+	h.mu.Unlock()
+
+	// buffered so that a second SIGINT gets in while the loop runs a callback
+	ch := make(chan os.Signal, 4)
+	signal.Notify(ch, sigs...)
+	result := make(chan error, 1)
+	go func() {
+		defer signal.Stop(ch)
+		result <- h.loop(ctx, ch)
+	}()
+	return result
+}
+
+func (h *Hub) loop(ctx context.Context, ch <-chan os.Signal) error {
+	logger := logging.FromContext(ctx)
+	var done chan error // set once the shutdown has started
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-done:
+			return err
+		case sig := <-ch:
+			logger.Info("signal", "signal", sig.String())
+			h.mu.Lock()
+			handlers := slices.Clone(h.handlers[sig])
+			h.mu.Unlock()
+			for _, fn := range handlers {
+				fn(ctx)
+			}
+			switch {
+			case slices.Contains(dumpSignals, sig):
+				h.dump()
+			case done != nil && sig == os.Interrupt:
+				logger.Warn("second interrupt, exiting now")
+				h.exit(ExitForced)
+				return ErrForced
+			case done != nil && slices.Contains(shutdownSignals, sig):
+				logger.Info("already shutting down")
+			case slices.Contains(shutdownSignals, sig):
+				done = make(chan error, 1)
+				go func() { done <- h.shutdown(ctx) }()
+			}
+		}
+	}
+}
+
+// shutdown runs the hooks in order until one overruns the timeout, the hooks after it are skipped
+func (h *Hub) shutdown(ctx context.Context) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	// the hooks get their own deadline, not the one of ctx: ctx ending stops the hub from waiting, not the hooks
+	hctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	h.mu.Lock()
+	hooks := slices.Clone(h.hooks)
+	h.mu.Unlock()
+
+	logger := logging.FromContext(ctx)
+	var errs []error
+	for i, hk := range hooks {
+		start := time.Now()
+		errc := make(chan error, 1)
+		go func() { errc <- hk.fn(hctx) }()
+		select {
+		case err := <-errc:
+			if err != nil {
+				logger.Error("shutdown hook failed", "hook", hk.name, "err", err)
+				errs = append(errs, fmt.Errorf("%s: %w", hk.name, err))
+				continue
+			}
+			logger.Info("shutdown hook done", "hook", hk.name, "took", time.Since(start))
+		case <-hctx.Done():
+			var skipped []string
+			for _, s := range hooks[i+1:] {
+				skipped = append(skipped, s.name)
+			}
+			logger.Error("shutdown timed out", "hook", hk.name, "skipped", skipped)
+			errs = append(errs, fmt.Errorf("%s: %w, skipped %v", hk.name, hctx.Err(), skipped))
+			return errors.Join(errs...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dump writes the runtime stats and the stacks of all the goroutines
+func (h *Hub) dump() {
+	w := h.Dump
+	if w == nil {
+		w = os.Stderr
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	fmt.Fprintf(w, "goroutines %d, heap %d KiB in use of %d KiB, %d gc runs, last pause %v\n",
+		runtime.NumGoroutine(), m.HeapAlloc>>10, m.HeapSys>>10, m.NumGC, time.Duration(m.PauseNs[(m.NumGC+255)%256]))
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			w.Write(buf[:n])
+			return
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+func (h *Hub) exit(code int) {
+	if h.Exit != nil {
+		h.Exit(code)
+		return
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,198 @@
+//go:build unix
+
+package sighub
+
+import (
+	"RobotTask/logging"
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// send delivers sig to the test process itself
+func send(t *testing.T, sig syscall.Signal) {
+	t.Helper()
+	if err := syscall.Kill(os.Getpid(), sig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wait returns the result of the hub, failing the test if it takes too long
+func wait(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hub is still running")
+		return nil
+	}
+}
+
+// lockedBuffer is written by the hub and read by the test
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestReload(t *testing.T) {
+	rec := logging.NewRecorder(slog.LevelDebug)
+	ctx, cancel := context.WithCancel(logging.NewContext(context.Background(), slog.New(rec)))
+	defer cancel()
+	var h Hub
+	reloads := make(chan int, 2)
+	fail := errors.New("bad config")
+	n := 0
+	h.OnReload(func(ctx context.Context) error {
+		n++
+		reloads <- n
+		if n == 2 {
+			return fail
+		}
+		return nil
+	})
+	result := h.Start(ctx)
+
+	for want := 1; want <= 2; want++ {
+		send(t, syscall.SIGHUP)
+		select {
+		case <-reloads:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("reload %d didn't run", want)
+		}
+	}
+	cancel()
+	if err := wait(t, result); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v; want context.Canceled", err)
+	}
+	if _, ok := rec.Find("reloaded"); !ok {
+		t.Errorf("no reloaded log in %q", rec.Messages())
+	}
+	r, ok := rec.Find("reload failed")
+	if !ok || logging.AttrsOf(r)["err"].String() != fail.Error() {
+		t.Errorf("no reload failed log in %q", rec.Messages())
+	}
+}
+
+func TestDump(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out lockedBuffer
+	h := Hub{Dump: &out}
+	result := h.Start(ctx)
+	send(t, syscall.SIGUSR1)
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "TestDump") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no stack of the test in the dump:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s := out.String(); !strings.HasPrefix(s, "goroutines ") || !strings.Contains(s, "gc runs") || !strings.Contains(s, "goroutine 1 [") {
+		t.Errorf("dump:\n%s", s)
+	}
+	cancel()
+	wait(t, result)
+}
+
+func TestShutdownOrder(t *testing.T) {
+	var h Hub
+	var mu sync.Mutex
+	var order []string
+	for _, name := range []string{"http", "queue", "db"} {
+		h.OnShutdown(name, func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			if name == "queue" {
+				return errors.New("not drained")
+			}
+			return nil
+		})
+	}
+	var handled []string
+	h.Handle(syscall.SIGTERM, func(ctx context.Context) { handled = append(handled, "term") })
+	result := h.Start(context.Background())
+	send(t, syscall.SIGTERM)
+	err := wait(t, result)
+	if err == nil || err.Error() != "queue: not drained" {
+		t.Errorf("err = %v; want the queue error", err)
+	}
+	if want := []string{"http", "queue", "db"}; !slices.Equal(order, want) {
+		t.Errorf("hooks ran in order %q; want %q", order, want)
+	}
+	if !slices.Equal(handled, []string{"term"}) {
+		t.Errorf("handlers of SIGTERM ran %q times", handled)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	h := Hub{Timeout: 100 * time.Millisecond}
+	ran := false
+	h.OnShutdown("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Hour) // doesn't look at ctx
+		return nil
+	})
+	h.OnShutdown("after", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	begin := time.Now()
+	result := h.Start(context.Background())
+	send(t, syscall.SIGINT)
+	err := wait(t, result)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "stuck: ") || !strings.Contains(err.Error(), "skipped [after]") {
+		t.Errorf("err = %v; want the deadline of stuck", err)
+	}
+	if d := time.Since(begin); d > 3*time.Second {
+		t.Errorf("the shutdown took %v", d)
+	}
+	if ran {
+		t.Error("a hook ran after the timeout")
+	}
+}
+
+func TestSecondInterrupt(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	codes := make(chan int, 1)
+	h := Hub{Exit: func(code int) { codes <- code }}
+	h.OnShutdown("slow", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	result := h.Start(context.Background())
+	send(t, syscall.SIGINT)
+	<-started
+	// a SIGTERM doesn't force anything, the shutdown goes on
+	send(t, syscall.SIGTERM)
+	send(t, syscall.SIGINT)
+	if err := wait(t, result); !errors.Is(err, ErrForced) {
+		t.Errorf("err = %v; want ErrForced", err)
+	}
+	if code := <-codes; code != ExitForced {
+		t.Errorf("exit code %d; want %d", code, ExitForced)
+	}
+}
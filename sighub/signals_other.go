@@ -0,0 +1,11 @@
+//go:build !unix
+
+package sighub
+
+import "os"
+
+// there is no SIGHUP or SIGUSR1 to send, OnReload and the dump never run
+var (
+	reloadSignals []os.Signal
+	dumpSignals   []os.Signal
+)
@@ -0,0 +1,13 @@
+//go:build unix
+
+package sighub
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	reloadSignals = []os.Signal{syscall.SIGHUP}
+	dumpSignals   = []os.Signal{syscall.SIGUSR1}
+)
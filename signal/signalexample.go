@@ -1,32 +1,51 @@
-package main
+// Package signalexample reloads on SIGHUP, dumps the goroutines on SIGUSR1 and shuts down on SIGINT or SIGTERM
+package signalexample
 
 import (
+	"RobotTask/logging"
+	"RobotTask/sighub"
+	"context"
 	"fmt"
-	"os"
-	"os/signal"
-	"syscall"
+	"io"
+	"time"
 )
 
-func main() {
-	// go signal notification works by sending os.Signal values on a channel. we create a channel to receive
-	// these notifications. note that this channel should be buffered
-	sigs := make(chan os.Signal, 1)
-	// signal.Notify registers the given channel to receive notifications of the specified signals.
-	// Notify can take multiple signals starting from the second parameter. in this case,
-	// we listen for sigint and sigterm
-	// after running the program, use ctrl+c to send the signal
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+// Run blocks until the process receives SIGINT or SIGTERM and its shutdown hooks ran, or ctx is done
+func Run(ctx context.Context, w io.Writer) error {
+	logger := logging.FromContext(ctx)
+	// go signal notification works by sending os.Signal values on a channel, registered with signal.Notify.
+	// the hub owns that channel and calls back what we registered for each signal
+	hub := &sighub.Hub{Timeout: 5 * time.Second, Dump: w}
 
-	done := make(chan bool, 1) // create buffering channel
-	// this goroutine executes a blocking receive for signals. when it gets one it'll print it out and
-	// then notify the programt that it can finish
-	go func() {
-		sig := <-sigs
-		fmt.Println(sig)
-		done <- true
-	}()
-	fmt.Println("awaiting signal")
-	// the program will wait here until it gets the expected signal
-	<-done
-	fmt.Println("exiting")
+	// try kill -HUP <pid> from another terminal
+	reloads := 0
+	hub.OnReload(func(ctx context.Context) error {
+		reloads++
+		fmt.Fprintln(w, "reloading config, reload", reloads)
+		return nil
+	})
+	// after running the program, use ctrl+c to send the signal. the hooks run in this order,
+	// a second ctrl+c while they run exits at once
+	hub.OnShutdown("listener", func(ctx context.Context) error {
+		fmt.Fprintln(w, "closing the listener")
+		return nil
+	})
+	hub.OnShutdown("flush", func(ctx context.Context) error {
+		fmt.Fprintln(w, "flushing, ctrl+c again to skip")
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		fmt.Fprintln(w, "flushed")
+		return nil
+	})
+
+	logger.Info("awaiting signal", "reload", "SIGHUP", "dump", "SIGUSR1", "shutdown", "SIGINT or SIGTERM")
+	// the program will wait here until the shutdown is over
+	if err := hub.Run(ctx); err != nil {
+		return err
+	}
+	logger.Info("exiting")
+	return nil
 }
@@ -1,23 +1,30 @@
-package main
+// Package spawnexample runs other programs with exec.Command and reads their output
+package spawnexample
 
 import (
+	"RobotTask/pipe"
+	"RobotTask/runner"
+	"context"
 	"fmt"
 	"io"
 	"os/exec"
+	"strings"
+	"time"
 )
 
-func main() {
+// Run runs date, grep, a pipeline and ls as child processes and prints their output to w
+func Run(ctx context.Context, w io.Writer) error {
 
 	// the exec.Command helper creates a command object, the command does not run here
 	dateCmd := exec.Command("date")
 	// the output method runs the command, if there were no errors, dateOut will hold bytes of the result
 	dateOut, err := dateCmd.Output()
 	if err != nil {
-		panic(err)
+		return err
 	}
-	fmt.Println("> date")
+	fmt.Fprintln(w, "> date")
 	// print the result of dateOut
-	fmt.Println(string(dateOut))
+	fmt.Fprintln(w, string(dateOut))
 
 	// Output and other methods of Command will return *exec.Error if
 	// there was a problem executing the command and *exec.ExitError
@@ -26,41 +33,96 @@ func main() {
 	if err != nil {
 		switch e := err.(type) {
 		case *exec.Error:
-			fmt.Println("failed executing:", err)
+			fmt.Fprintln(w, "failed executing:", err)
 		case *exec.ExitError:
-			fmt.Println("command exit rc =", e.ExitCode())
+			fmt.Fprintln(w, "command exit rc =", e.ExitCode())
 		default:
-			panic(err)
+			return err
 		}
 	}
 
 	// grep command with pipes
 	grepCmd := exec.Command("grep", "hello")
 	// grab input and output pipes
-	grepIn, _ := grepCmd.StdinPipe()
-	grepOut, _ := grepCmd.StdoutPipe()
+	grepIn, err := grepCmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	grepOut, err := grepCmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
 	// start the process
-	grepCmd.Start()
+	if err := grepCmd.Start(); err != nil {
+		return err
+	}
 	// write input to the inpipe
-	grepIn.Write([]byte("hello grep 123\ngoodbye grep"))
+	if _, err := grepIn.Write([]byte("hello grep 123\ngoodbye grep")); err != nil {
+		return err
+	}
 	// close the inpipe
 	grepIn.Close()
 	// read from the outpipe
-	grepBytes, _ := io.ReadAll(grepOut)
-	// wait for the process to exit
-	grepCmd.Wait()
+	grepBytes, err := io.ReadAll(grepOut)
+	if err != nil {
+		return err
+	}
+	// wait for the process to exit, after reading all of its output
+	if err := grepCmd.Wait(); err != nil {
+		return err
+	}
 
-	fmt.Println("> grep hello")
+	fmt.Fprintln(w, "> grep hello")
 	// output the bytes read from the outpipe
-	fmt.Println(string(grepBytes))
+	fmt.Fprintln(w, string(grepBytes))
+
+	// the pipe package does this wiring for any number of commands: the stdout of each one feeds the next one,
+	// the first one reads from an io.Reader, and the result has the output and the exit code of every stage
+	input := "hello grep 123\ngoodbye grep\nhello pipe 456\n"
+	res, err := pipe.Pipeline(ctx, strings.NewReader(input),
+		exec.Command("grep", "hello"),
+		exec.Command("sort", "-r"),
+		exec.Command("tr", "a-z", "A-Z"),
+	)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "> grep hello | sort -r | tr a-z A-Z")
+	fmt.Fprint(w, string(res.Output))
+	fmt.Fprintln(w, "exit codes:", res.ExitCodes)
+	// a stage that fails doesn't stop the others, the error says which one failed. grep exits with 1 when nothing matches
+	res, err = pipe.Pipeline(ctx, strings.NewReader(input), exec.Command("grep", "nothing"), exec.Command("wc", "-l"))
+	fmt.Fprintln(w, "> grep nothing | wc -l")
+	fmt.Fprint(w, strings.TrimLeft(string(res.Output), " ")) // some wc pad the count
+	fmt.Fprintln(w, "exit codes:", res.ExitCodes)
+	fmt.Fprintln(w, "error:", err)
+	fmt.Fprintln(w)
+
+	// Output waits as long as the command runs and keeps all of its output. the runner package bounds both:
+	// a timeout kills the command and the processes it started, and the output beyond a cap is dropped with a marker
+	result, err := runner.Run(ctx, runner.Options{Timeout: 100 * time.Millisecond, EnvAllow: []string{"PATH"}}, "bash", "-c", "sleep 10 & wait")
+	fmt.Fprintln(w, "> bash -c 'sleep 10 & wait', with a 100ms timeout")
+	fmt.Fprintf(w, "exit code %d, signal %v, timed out %v, error: %v\n", result.ExitCode, result.Signal, result.TimedOut, err)
+	result, err = runner.Run(ctx, runner.Options{MaxStdout: 20, EnvAllow: []string{"PATH"}}, "bash", "-c", "yes | head -n 1000")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "> bash -c 'yes | head -n 1000', 20 bytes kept")
+	fmt.Fprint(w, string(result.Stdout))
+	fmt.Fprintln(w, "exit code", result.ExitCode, "truncated", result.StdoutTruncated)
+	fmt.Fprintln(w)
 
 	// when spawning commands we need to provide a delineated command and argument array.
 	// if you want to spawn a full command with a string, you can use bash's -c option
-	lsCmd := exec.Command("bash", "-c", "ls -a -l -h") // one line command, not delineated
-	lsOut, err := lsCmd.Output()
+	result, err = runner.Run(ctx, runner.Options{Timeout: 10 * time.Second, EnvAllow: []string{"PATH", "LANG", "LC_*"}},
+		"bash", "-c", "ls -a -l -h") // one line command, not delineated
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("ls exited with %d: %s", result.ExitCode, result.Stderr)
 	}
-	fmt.Println("> ls -a -l -h")
-	fmt.Println(string(lsOut))
+	fmt.Fprintln(w, "> ls -a -l -h")
+	fmt.Fprintln(w, string(result.Stdout))
+	return nil
 }
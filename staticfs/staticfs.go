@@ -0,0 +1,211 @@
+// Package staticfs serves a read-only file system such as an embed.FS over http.
+// compared to a plain http.FileServer it adds what a production site usually needs:
+//
+//   - strong ETags computed from the sha256 of each file, so unchanged files are answered with 304
+//   - Cache-Control headers chosen by file extension
+//   - an option to turn off directory listings
+//   - pre-compressed variants, file.js.br or file.js.gz is sent instead of file.js when the client accepts it
+//   - an index.html fallback for single page apps that do their own routing
+package staticfs
+
+import (
+	"RobotTask/helper"
+	"bytes"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options controls how files are served
+type Options struct {
+	// CacheControl maps a file extension, including the dot, to the Cache-Control header sent with it
+	CacheControl map[string]string
+	// DefaultCacheControl is sent for extensions that are not in CacheControl, no header is sent if it is empty
+	DefaultCacheControl string
+	// DisableListing answers 404 for directories that have no index.html, instead of listing their files
+	DisableListing bool
+	// SPAFallback serves the root index.html for paths without an extension that match no file,
+	// so a single page app can handle routes like /plants/27 itself. missing assets such as /app.js are still 404
+	SPAFallback bool
+}
+
+// DefaultOptions returns options suited to a typical site:
+// html is revalidated on every request, since it names the other assets, which can be cached for a day
+func DefaultOptions() Options {
+	return Options{
+		CacheControl: map[string]string{
+			".html": "no-cache",
+			".css":  "public, max-age=86400",
+			".js":   "public, max-age=86400",
+			".png":  "public, max-age=86400",
+			".svg":  "public, max-age=86400",
+		},
+		DefaultCacheControl: "public, max-age=3600",
+	}
+}
+
+// encodings are the pre-compressed variants looked up, in order of preference, with the file suffix of each
+var encodings = []struct{ name, suffix string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Handler serves the files of an fs.FS
+type Handler struct {
+	fsys    fs.FS
+	opts    Options
+	listing http.Handler
+
+	// the file system is read-only, so the etag of a file never changes and is computed only once
+	mu    sync.Mutex
+	etags map[string]string
+}
+
+// New returns a handler serving the files of fsys with the given options.
+// fsys must not change while it is served, which is always true of an embed.FS
+func New(fsys fs.FS, opts Options) *Handler {
+	return &Handler{
+		fsys:    fsys,
+		opts:    opts,
+		listing: http.FileServerFS(fsys),
+		etags:   make(map[string]string),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// path.Clean removes any .. elements, so a request can't escape the file system
+	urlPath := req.URL.Path
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+	name := strings.TrimPrefix(path.Clean(urlPath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		if !h.opts.SPAFallback || path.Ext(name) != "" {
+			http.NotFound(w, req)
+			return
+		}
+		name = "index.html"
+		if info, err = fs.Stat(h.fsys, name); err != nil {
+			http.NotFound(w, req)
+			return
+		}
+	}
+
+	if info.IsDir() {
+		// like http.FileServer, redirect to the trailing slash so relative links in the page work
+		if !strings.HasSuffix(urlPath, "/") {
+			http.Redirect(w, req, path.Base(urlPath)+"/", http.StatusMovedPermanently)
+			return
+		}
+		index := path.Join(name, "index.html")
+		if _, err := fs.Stat(h.fsys, index); err != nil {
+			if h.opts.DisableListing {
+				http.NotFound(w, req)
+				return
+			}
+			h.listing.ServeHTTP(w, req)
+			return
+		}
+		name = index
+	}
+
+	h.serveFile(w, req, name)
+}
+
+// serveFile sends the named file, or its best pre-compressed variant
+func (h *Handler) serveFile(w http.ResponseWriter, req *http.Request, name string) {
+	header := w.Header()
+	ext := path.Ext(name)
+	// the content type comes from the original name, not from the .br or .gz suffix of a variant
+	if ctype := mime.TypeByExtension(ext); ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
+	if cc, ok := h.opts.CacheControl[ext]; ok {
+		header.Set("Cache-Control", cc)
+	} else if h.opts.DefaultCacheControl != "" {
+		header.Set("Cache-Control", h.opts.DefaultCacheControl)
+	}
+	// the response depends on Accept-Encoding whenever a variant might exist
+	header.Add("Vary", "Accept-Encoding")
+
+	accepted := acceptedEncodings(req.Header.Get("Accept-Encoding"))
+	for _, enc := range encodings {
+		ok, listed := accepted[enc.name]
+		if !listed {
+			ok = accepted["*"] // a wildcard covers the codings not named in the header
+		}
+		if !ok {
+			continue
+		}
+		data, err := fs.ReadFile(h.fsys, name+enc.suffix)
+		if err != nil {
+			continue
+		}
+		header.Set("Content-Encoding", enc.name)
+		h.serveContent(w, req, name+enc.suffix, data)
+		return
+	}
+
+	data, err := fs.ReadFile(h.fsys, name)
+	if err != nil {
+		http.Error(w, "could not read file", http.StatusInternalServerError)
+		return
+	}
+	h.serveContent(w, req, name, data)
+}
+
+// serveContent sets the etag and lets http.ServeContent deal with conditional and range requests
+func (h *Handler) serveContent(w http.ResponseWriter, req *http.Request, name string, data []byte) {
+	w.Header().Set("ETag", h.etag(name, data))
+	// embedded files have no modification time, so the etag is the only validator
+	http.ServeContent(w, req, name, time.Time{}, bytes.NewReader(data))
+}
+
+// etag returns the strong etag of the named file, the quoted hex sha256 of its contents
+func (h *Handler) etag(name string, data []byte) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if tag, ok := h.etags[name]; ok {
+		return tag
+	}
+	tag := strconv.Quote(hex.EncodeToString(helper.Sha256Sum(data)))
+	h.etags[name] = tag
+	return tag
+}
+
+// acceptedEncodings parses an Accept-Encoding header.
+// a coding maps to false when the client refuses it with q=0
+func acceptedEncodings(header string) map[string]bool {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		accepted[coding] = true
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				accepted[coding] = false
+			}
+		}
+	}
+	return accepted
+}
@@ -0,0 +1,160 @@
+package staticfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var testFS = fstest.MapFS{
+	"index.html":        {Data: []byte("<h1>home</h1>")},
+	"app.js":            {Data: []byte("console.log('hello')")},
+	"app.js.br":         {Data: []byte("brotli bytes")},
+	"app.js.gz":         {Data: []byte("gzip bytes")},
+	"style.css":         {Data: []byte("body {}")},
+	"docs/index.html":   {Data: []byte("<h1>docs</h1>")},
+	"assets/logo.txt":   {Data: []byte("logo")},
+	"assets/readme.txt": {Data: []byte("readme")},
+}
+
+// get sends a GET request to h with the given headers, given as name, value pairs
+func get(h http.Handler, target string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestETag(t *testing.T) {
+	h := New(testFS, DefaultOptions())
+	rec := get(h, "/style.css")
+	// the sha256 of "body {}"
+	const want = `"62368a1a29259b30bac235c0e75dc700c9b3bacf1513ad5708e4fe4a6c0d6560"`
+	etag := rec.Header().Get("ETag")
+	if etag != want {
+		t.Fatalf("ETag = %s; want %s", etag, want)
+	}
+
+	rec = get(h, "/style.css", "If-None-Match", etag)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("conditional GET = %d with %d bytes; want 304 and no body", rec.Code, rec.Body.Len())
+	}
+	if rec = get(h, "/style.css", "If-None-Match", `"stale"`); rec.Code != http.StatusOK {
+		t.Errorf("GET with a stale etag = %d; want 200", rec.Code)
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	h := New(testFS, DefaultOptions())
+	var tests = []struct {
+		target, want string
+	}{
+		{"/", "no-cache"},
+		{"/index.html", "no-cache"},
+		{"/style.css", "public, max-age=86400"},
+		{"/assets/logo.txt", "public, max-age=3600"},
+	}
+	for _, tt := range tests {
+		if got := get(h, tt.target).Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("Cache-Control of %s = %q; want %q", tt.target, got, tt.want)
+		}
+	}
+
+	if got := get(New(testFS, Options{}), "/style.css").Header().Get("Cache-Control"); got != "" {
+		t.Errorf("Cache-Control without rules = %q; want none", got)
+	}
+}
+
+func TestPrecompressed(t *testing.T) {
+	h := New(testFS, DefaultOptions())
+	var tests = []struct {
+		accept, wantEncoding, wantBody string
+	}{
+		{"", "", "console.log('hello')"},
+		{"gzip", "gzip", "gzip bytes"},
+		{"gzip, deflate, br", "br", "brotli bytes"},
+		{"br;q=0, gzip", "gzip", "gzip bytes"},
+		{"*", "br", "brotli bytes"},
+		{"br;q=0, *", "gzip", "gzip bytes"},
+		{"identity", "", "console.log('hello')"},
+	}
+	for _, tt := range tests {
+		rec := get(h, "/app.js", "Accept-Encoding", tt.accept)
+		if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding || rec.Body.String() != tt.wantBody {
+			t.Errorf("Accept-Encoding %q: encoding %q, body %q; want %q, %q", tt.accept, got, rec.Body, tt.wantEncoding, tt.wantBody)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+			t.Errorf("Accept-Encoding %q: Content-Type = %q", tt.accept, ct)
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: missing Vary header", tt.accept)
+		}
+	}
+
+	// each variant has its own etag, a cache must not mix them up
+	plain := get(h, "/app.js").Header().Get("ETag")
+	gz := get(h, "/app.js", "Accept-Encoding", "gzip").Header().Get("ETag")
+	if plain == gz {
+		t.Errorf("plain and gzip variants share etag %s", plain)
+	}
+}
+
+func TestDirectories(t *testing.T) {
+	var tests = []struct {
+		name       string
+		opts       Options
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"index page", Options{}, "/docs/", http.StatusOK, "<h1>docs</h1>"},
+		{"trailing slash redirect", Options{}, "/docs", http.StatusMovedPermanently, ""},
+		{"listing", Options{}, "/assets/", http.StatusOK, "readme.txt"},
+		{"listing disabled", Options{DisableListing: true}, "/assets/", http.StatusNotFound, ""},
+		{"index with listing disabled", Options{DisableListing: true}, "/docs/", http.StatusOK, "<h1>docs</h1>"},
+		{"missing file", Options{}, "/nope", http.StatusNotFound, ""},
+		{"escape attempt", Options{}, "/../../etc/passwd", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(New(testFS, tt.opts), tt.target)
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("GET %s = %d\n%s\nwant %d containing %q", tt.target, rec.Code, rec.Body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSPAFallback(t *testing.T) {
+	h := New(testFS, Options{SPAFallback: true, DisableListing: true})
+	var tests = []struct {
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/plants/27", http.StatusOK, "<h1>home</h1>"},
+		{"/settings", http.StatusOK, "<h1>home</h1>"},
+		{"/missing.js", http.StatusNotFound, ""}, // a missing asset is still an error
+		{"/app.js", http.StatusOK, "console.log('hello')"},
+		{"/docs/", http.StatusOK, "<h1>docs</h1>"},
+	}
+	for _, tt := range tests {
+		rec := get(h, tt.target)
+		if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("GET %s = %d %q; want %d %q", tt.target, rec.Code, rec.Body, tt.wantStatus, tt.wantBody)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(testFS, Options{}).ServeHTTP(rec, httptest.NewRequest("POST", "/app.js", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST = %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
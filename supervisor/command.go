@@ -0,0 +1,72 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// RunArgs runs the supervise command, which keeps a command running until ctrl+c:
+//
+//	oneforall supervise [-name=n] [-restart=never|on-failure|always] [-max-restarts=0] [-backoff=1s] [-max-backoff=1m]
+//	                    [-grace=10s] [-health-cmd='...'] [-health-url=url] [-health-interval=10s] command [args...]
+//
+// the output of the command and the restarts are logged, see the -log flags of the launcher.
+// once it is over, the status of the process is printed to w
+func RunArgs(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("supervise", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the process in the log (default the program name)")
+	p := Process{Restart: RestartOnFailure}
+	fs.Func("restart", "restart policy: never, on-failure or always (default on-failure)", func(s string) (err error) {
+		p.Restart, err = ParsePolicy(s)
+		return err
+	})
+	fs.IntVar(&p.MaxRestarts, "max-restarts", 0, "restarts in a row before giving up, 0 for no limit")
+	fs.DurationVar(&p.Backoff.Initial, "backoff", DefaultBackoff.Initial, "delay before the first restart, doubled after each failure")
+	fs.DurationVar(&p.Backoff.Max, "max-backoff", DefaultBackoff.Max, "longest delay between restarts")
+	fs.DurationVar(&p.Grace, "grace", DefaultGrace, "time to exit after SIGTERM before SIGKILL")
+	healthCmd := fs.String("health-cmd", "", "shell command checking the health of the process")
+	var health HealthCheck
+	fs.StringVar(&health.URL, "health-url", "", "url checking the health of the process")
+	fs.DurationVar(&health.Interval, "health-interval", 10*time.Second, "time between the health checks")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("supervise: missing the command to run")
+	}
+	p.Command = fs.Args()
+	p.Name = *name
+	if p.Name == "" {
+		p.Name = filepath.Base(p.Command[0])
+	}
+	if *healthCmd != "" {
+		health.Command = []string{"sh", "-c", *healthCmd}
+	}
+	if health.URL != "" || len(health.Command) > 0 {
+		p.Health = &health
+	}
+	s, err := New(p)
+	if err != nil {
+		return err
+	}
+
+	// ctrl+c or a SIGTERM to the launcher stops the process, which gets its own SIGTERM then
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	err = s.Run(ctx)
+	for _, st := range s.Status() {
+		fmt.Fprintf(w, "%s: %s, pid %d, %d restarts", st.Name, st.State, st.PID, st.Restarts)
+		if st.LastErr != nil {
+			fmt.Fprintf(w, ", last run: %v", st.LastErr)
+		}
+		fmt.Fprintln(w)
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// HealthCheck tells whether a running process works, by running a command or by requesting a URL.
+// the process is restarted after Retries failed checks in a row
+type HealthCheck struct {
+	// Command passes when it exits with status 0
+	Command []string
+	// URL passes when a GET answers with a status below 400
+	URL      string
+	Interval time.Duration // between the checks, the first one comes after an interval. 10s if 0
+	Timeout  time.Duration // for one check, 5s if 0
+	Retries  int           // failed checks in a row before the restart, 3 if 0
+}
+
+func (h HealthCheck) validate() error {
+	if (len(h.Command) == 0) == (h.URL == "") {
+		return errors.New("a health check needs either a command or a url")
+	}
+	return nil
+}
+
+func (h HealthCheck) withDefaults() HealthCheck {
+	if h.Interval <= 0 {
+		h.Interval = 10 * time.Second
+	}
+	if h.Timeout <= 0 {
+		h.Timeout = 5 * time.Second
+	}
+	if h.Retries <= 0 {
+		h.Retries = 3
+	}
+	return h
+}
+
+// Check runs the check once
+func (h HealthCheck) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, h.withDefaults().Timeout)
+	defer cancel()
+	if len(h.Command) > 0 {
+		out, err := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...).CombinedOutput()
+		if out = bytes.TrimSpace(out); err != nil && len(out) > 0 {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s: %s", h.URL, resp.Status)
+	}
+	return nil
+}
+
+// watchHealth checks p until ctx is done. the channel returned gets the last error once the checks failed Retries times in a row
+func (s *Supervisor) watchHealth(ctx context.Context, p *proc, logger *slog.Logger) <-chan error {
+	unhealthy := make(chan error, 1)
+	h := p.Health.withDefaults()
+	go func() {
+		t := time.NewTicker(h.Interval)
+		defer t.Stop()
+		failures := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			err := h.Check(ctx)
+			if ctx.Err() != nil {
+				return // the process is being stopped, the check failing says nothing
+			}
+			if err == nil {
+				if failures > 0 || !s.healthy(p) {
+					logger.Info("health check passed")
+				}
+				failures = 0
+				s.update(p, func(st *Status) { st.Healthy = true })
+				continue
+			}
+			failures++
+			s.update(p, func(st *Status) { st.Healthy = false })
+			logger.Warn("health check failed", "err", err, "failures", failures)
+			if failures >= h.Retries {
+				unhealthy <- err
+				return
+			}
+		}
+	}()
+	return unhealthy
+}
+
+func (s *Supervisor) healthy(p *proc) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return p.status.Healthy
+}
@@ -0,0 +1,42 @@
+package supervisor
+
+import (
+	"bytes"
+)
+
+// maxLine is the longest line logged whole, a longer one is logged in pieces of this size
+const maxLine = 64 * 1024
+
+// lineWriter calls log for each line written to it, without the newline. exec.Cmd copies the output of the child into it
+type lineWriter struct {
+	log func(line string)
+	buf []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.log(string(bytes.TrimSuffix(l.buf[:i], []byte("\r"))))
+		l.buf = l.buf[i+1:]
+	}
+	for len(l.buf) >= maxLine {
+		l.log(string(l.buf[:maxLine]))
+		l.buf = l.buf[maxLine:]
+	}
+	if len(l.buf) == 0 {
+		l.buf = nil // lets the array of a long output go
+	}
+	return len(p), nil
+}
+
+// Flush logs the last line if it had no newline
+func (l *lineWriter) Flush() {
+	if len(l.buf) > 0 {
+		l.log(string(l.buf))
+		l.buf = nil
+	}
+}
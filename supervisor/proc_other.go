@@ -0,0 +1,21 @@
+//go:build !unix
+
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup does nothing, there are no process groups to signal
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate kills p, there is no SIGTERM to ask it to exit
+func terminate(p *os.Process) error {
+	return p.Kill()
+}
+
+// kill kills p
+func kill(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,357 @@
+// Package supervisor keeps long running child processes up, where the spawn example runs its commands once and gives up on the first error.
+// each Process has a restart policy with an exponential backoff between the restarts, its output goes to the logger a line at a time,
+// and an optional health check restarts it when it stops answering:
+//
+//	s, err := supervisor.New(supervisor.Process{
+//		Name:    "web",
+//		Command: []string{"python3", "-m", "http.server", "8000"},
+//		Restart: supervisor.RestartAlways,
+//		Health:  &supervisor.HealthCheck{URL: "http://localhost:8000/"},
+//	})
+//	err = s.Run(ctx) // until ctx is done
+//
+// when ctx is done the children get SIGTERM, and SIGKILL if they are still running after their grace period.
+// the signals go to the process group of the child, so that the processes it started itself stop too
+package supervisor
+
+import (
+	"RobotTask/logging"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy tells when a process that exited is started again
+type Policy int
+
+const (
+	RestartNever     Policy = iota
+	RestartOnFailure        // restart after a non-zero exit status or a signal, not after a clean exit
+	RestartAlways
+)
+
+var policyNames = []string{"never", "on-failure", "always"}
+
+func (p Policy) String() string {
+	if p < 0 || int(p) >= len(policyNames) {
+		return fmt.Sprintf("Policy(%d)", int(p))
+	}
+	return policyNames[p]
+}
+
+// ParsePolicy parses never, on-failure or always
+func ParsePolicy(s string) (Policy, error) {
+	for i, name := range policyNames {
+		if s == name {
+			return Policy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown restart policy %q, want %s", s, strings.Join(policyNames, ", "))
+}
+
+// Backoff is the delay before a restart: Initial, then multiplied by Multiplier after each failed run, up to Max
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// Reset is how long a run must last to count as a success: the next delay is Initial again
+	Reset time.Duration
+}
+
+// DefaultBackoff fills the zero fields of a Backoff
+var DefaultBackoff = Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 2, Reset: time.Minute}
+
+// DefaultGrace is how long a process gets to exit after SIGTERM when Process.Grace is 0
+const DefaultGrace = 10 * time.Second
+
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = DefaultBackoff.Initial
+	}
+	if b.Max <= 0 {
+		b.Max = max(DefaultBackoff.Max, b.Initial)
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = DefaultBackoff.Multiplier
+	}
+	if b.Reset <= 0 {
+		b.Reset = DefaultBackoff.Reset
+	}
+	return b
+}
+
+// Delay returns the delay before the restart following n failed runs in a row, n starting at 0
+func (b Backoff) Delay(n int) time.Duration {
+	b = b.withDefaults()
+	d := float64(b.Initial) * math.Pow(b.Multiplier, float64(n))
+	if d > float64(b.Max) {
+		return b.Max
+	}
+	return time.Duration(d)
+}
+
+// Process describes a child process to keep running
+type Process struct {
+	Name string
+	// Command is the program and its arguments, the program is looked up in PATH like with exec.Command
+	Command []string
+	Dir     string
+	Env     []string // nil for the environment of the supervisor
+	Restart Policy
+	// MaxRestarts is the number of restarts in a row, without a run lasting Backoff.Reset, after which the supervisor gives up. 0 for no limit
+	MaxRestarts int
+	Backoff     Backoff
+	// Grace is how long the process gets to exit after SIGTERM before it is killed, DefaultGrace if 0
+	Grace  time.Duration
+	Health *HealthCheck // nil for none
+	// Prefix starts the lines of output in the log, the name and a colon if empty
+	Prefix string
+}
+
+// State is where a process is in its life
+type State int
+
+const (
+	StateIdle    State = iota // not started yet
+	StateRunning              // started, and not exited yet
+	StateBackoff              // exited, waiting to be restarted
+	StateExited               // exited and not restarted, by its policy or after too many restarts
+	StateStopped              // stopped by the supervisor
+)
+
+var stateNames = []string{"idle", "running", "backoff", "exited", "stopped"}
+
+func (s State) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+	return stateNames[s]
+}
+
+// Status is a snapshot of a supervised process
+type Status struct {
+	Name     string
+	State    State
+	PID      int // of the last run, 0 before the first one
+	Started  time.Time
+	Restarts int
+	// Healthy is the result of the last health check, true for a running process without health check.
+	// it is false until the first check passes
+	Healthy bool
+	LastErr error // how the last run ended, nil for a clean exit
+}
+
+// Supervisor runs a set of processes
+type Supervisor struct {
+	procs []*proc
+	mu    sync.Mutex // guards the statuses
+}
+
+// proc is a supervised process and its status
+type proc struct {
+	Process
+	status Status
+}
+
+// New checks the processes and returns a supervisor for them, they start with Run
+func New(procs ...Process) (*Supervisor, error) {
+	s := &Supervisor{}
+	seen := map[string]bool{}
+	for _, p := range procs {
+		switch {
+		case p.Name == "":
+			return nil, errors.New("supervisor: a process has no name")
+		case seen[p.Name]:
+			return nil, fmt.Errorf("supervisor: two processes are named %s", p.Name)
+		case len(p.Command) == 0:
+			return nil, fmt.Errorf("supervisor: %s has no command", p.Name)
+		case p.Restart < RestartNever || p.Restart > RestartAlways:
+			return nil, fmt.Errorf("supervisor: %s: unknown restart policy %v", p.Name, p.Restart)
+		}
+		if p.Health != nil {
+			if err := p.Health.validate(); err != nil {
+				return nil, fmt.Errorf("supervisor: %s: %w", p.Name, err)
+			}
+		}
+		seen[p.Name] = true
+		s.procs = append(s.procs, &proc{Process: p, status: Status{Name: p.Name}})
+	}
+	return s, nil
+}
+
+// Status returns the status of the processes, in the order given to New
+func (s *Supervisor) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Status, len(s.procs))
+	for i, p := range s.procs {
+		out[i] = p.status
+	}
+	return out
+}
+
+// update changes the status of p under the lock
+func (s *Supervisor) update(p *proc, f func(*Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(&p.status)
+}
+
+// Run starts the processes and restarts them by their policy until ctx is done, then stops them all.
+// it logs to the logger of ctx. it returns once every process is stopped or has exited for good,
+// with the errors of the processes that failed and weren't restarted. it must be called once
+func (s *Supervisor) Run(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+	errs := make([]error, len(s.procs))
+	var wg sync.WaitGroup
+	for i, p := range s.procs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.supervise(ctx, p, logger.With("process", p.Name))
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// supervise runs p until ctx is done or its policy says it is over
+func (s *Supervisor) supervise(ctx context.Context, p *proc, logger *slog.Logger) error {
+	failures := 0 // runs in a row shorter than Backoff.Reset
+	for {
+		start := time.Now()
+		err := s.runOnce(ctx, p, logger)
+		if ctx.Err() != nil {
+			s.update(p, func(st *Status) { st.State = StateStopped; st.LastErr = err })
+			logger.Info("process stopped")
+			return nil
+		}
+		s.update(p, func(st *Status) { st.LastErr = err })
+		if err != nil {
+			logger.Warn("process exited", "err", err, "ran", time.Since(start).Round(time.Millisecond))
+		} else {
+			logger.Info("process exited", "ran", time.Since(start).Round(time.Millisecond))
+		}
+
+		if p.Restart == RestartNever || p.Restart == RestartOnFailure && err == nil {
+			s.update(p, func(st *Status) { st.State = StateExited })
+			if err != nil {
+				return fmt.Errorf("%s: %w", p.Name, err)
+			}
+			return nil
+		}
+		if time.Since(start) >= p.Backoff.withDefaults().Reset {
+			failures = 0
+		}
+		if p.MaxRestarts > 0 && failures >= p.MaxRestarts {
+			s.update(p, func(st *Status) { st.State = StateExited })
+			logger.Error("too many restarts, giving up", "restarts", failures)
+			return fmt.Errorf("%s: gave up after %d restarts: %w", p.Name, failures, exitReason(err))
+		}
+
+		delay := p.Backoff.Delay(failures)
+		failures++
+		s.update(p, func(st *Status) { st.State = StateBackoff })
+		logger.Info("restarting", "in", delay)
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			s.update(p, func(st *Status) { st.State = StateStopped })
+			return nil
+		case <-t.C:
+		}
+		s.update(p, func(st *Status) { st.Restarts++ })
+	}
+}
+
+// exitReason names a clean exit in the error of a process that keeps exiting
+func exitReason(err error) error {
+	if err == nil {
+		return errors.New("exited with status 0")
+	}
+	return err
+}
+
+// runOnce starts p and waits for it to exit, to be stopped when ctx is done, or to be stopped after failing its health checks
+func (s *Supervisor) runOnce(ctx context.Context, p *proc, logger *slog.Logger) error {
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	cmd.Dir, cmd.Env = p.Dir, p.Env
+	prefix := p.Prefix
+	if prefix == "" {
+		prefix = p.Name + ": "
+	}
+	stdout := &lineWriter{log: func(line string) { logger.Info(prefix+line, "stream", "stdout") }}
+	stderr := &lineWriter{log: func(line string) { logger.Warn(prefix+line, "stream", "stderr") }}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	grace := p.Grace
+	if grace <= 0 {
+		grace = DefaultGrace
+	}
+	// a grandchild keeping the output open doesn't block Wait for longer than this
+	cmd.WaitDelay = grace
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		s.update(p, func(st *Status) { st.PID, st.Healthy = 0, false })
+		return err
+	}
+	pid := cmd.Process.Pid
+	s.update(p, func(st *Status) {
+		st.State, st.PID, st.Started, st.Healthy = StateRunning, pid, time.Now(), p.Health == nil
+	})
+	logger.Info("process started", "pid", pid, "command", p.Command)
+
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- cmd.Wait()
+	}()
+	defer func() {
+		// Wait has returned on every path, the writers have all the output
+		stdout.Flush()
+		stderr.Flush()
+	}()
+
+	var unhealthy <-chan error
+	if p.Health != nil {
+		healthCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		unhealthy = s.watchHealth(healthCtx, p, logger)
+	}
+
+	select {
+	case err := <-waitErr:
+		return err
+	case <-ctx.Done():
+		return stop(cmd, waitErr, grace, logger)
+	case err := <-unhealthy:
+		logger.Error("process is unhealthy, restarting it", "err", err)
+		stop(cmd, waitErr, grace, logger)
+		return fmt.Errorf("unhealthy: %w", err)
+	}
+}
+
+// stop sends SIGTERM to the process group of cmd, and SIGKILL if it is still running after grace
+func stop(cmd *exec.Cmd, waitErr <-chan error, grace time.Duration, logger *slog.Logger) error {
+	if err := terminate(cmd.Process); err != nil {
+		logger.Debug("terminate", "err", err) // it has probably exited already
+	}
+	t := time.NewTimer(grace)
+	defer t.Stop()
+	select {
+	case err := <-waitErr:
+		return err
+	case <-t.C:
+	}
+	logger.Warn("process still running after the grace period, killing it", "grace", grace)
+	if err := kill(cmd.Process); err != nil {
+		logger.Debug("kill", "err", err)
+	}
+	return <-waitErr
+}
@@ -0,0 +1,290 @@
+package supervisor
+
+import (
+	"RobotTask/logging"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fast restarts the tests don't wait for
+var fast = Backoff{Initial: 10 * time.Millisecond, Max: 40 * time.Millisecond}
+
+// sh returns a command running script with sh, skipping the test without sh
+func sh(t *testing.T, script string) []string {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	return []string{"sh", "-c", script}
+}
+
+// start runs the supervisor on a goroutine with a recorded logger, the channel gets the error of Run
+func start(t *testing.T, ctx context.Context, procs ...Process) (*Supervisor, *logging.Recorder, <-chan error) {
+	t.Helper()
+	s, err := New(procs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := logging.NewRecorder(slog.LevelDebug)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(logging.NewContext(ctx, slog.New(rec)))
+	}()
+	return s, rec, done
+}
+
+// wait returns the error of Run, failing the test if it takes more than 10 seconds
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run didn't return")
+		return nil
+	}
+}
+
+// eventually polls cond until it is true, failing the test after 10 seconds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	var got []time.Duration
+	for n := range 6 {
+		got = append(got, b.Delay(n))
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	if !slices.Equal(got, want) {
+		t.Errorf("delays = %v; want %v", got, want)
+	}
+	if d := (Backoff{}).Delay(0); d != DefaultBackoff.Initial {
+		t.Errorf("zero backoff = %v; want %v", d, DefaultBackoff.Initial)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, p := range []Policy{RestartNever, RestartOnFailure, RestartAlways} {
+		if got, err := ParsePolicy(p.String()); err != nil || got != p {
+			t.Errorf("ParsePolicy(%q) = %v, %v", p, got, err)
+		}
+	}
+	if _, err := ParsePolicy("sometimes"); err == nil {
+		t.Error("sometimes accepted")
+	}
+}
+
+func TestNew(t *testing.T) {
+	var tests = []struct {
+		name  string
+		procs []Process
+		want  string
+	}{
+		{"no name", []Process{{Command: []string{"true"}}}, "no name"},
+		{"no command", []Process{{Name: "a"}}, "a has no command"},
+		{"same name", []Process{{Name: "a", Command: []string{"true"}}, {Name: "a", Command: []string{"true"}}}, "two processes are named a"},
+		{"bad policy", []Process{{Name: "a", Command: []string{"true"}, Restart: 7}}, "Policy(7)"},
+		{"empty health check", []Process{{Name: "a", Command: []string{"true"}, Health: &HealthCheck{}}}, "either a command or a url"},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.procs...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v; want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestRestartPolicies(t *testing.T) {
+	var tests = []struct {
+		name     string
+		policy   Policy
+		script   string
+		max      int
+		restarts int
+		wantErr  string
+	}{
+		{"never after a failure", RestartNever, "exit 1", 0, 0, "p: exit status 1"},
+		{"never after a success", RestartNever, "exit 0", 0, 0, ""},
+		{"on-failure after a success", RestartOnFailure, "exit 0", 0, 0, ""},
+		{"on-failure after failures", RestartOnFailure, "exit 2", 3, 3, "p: gave up after 3 restarts: exit status 2"},
+		{"always after a success", RestartAlways, "exit 0", 2, 2, "p: gave up after 2 restarts: exited with status 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, rec, done := start(t, context.Background(), Process{Name: "p", Command: sh(t, tt.script), Restart: tt.policy, MaxRestarts: tt.max, Backoff: fast})
+			err := wait(t, done)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("err = %v; want %q", err, tt.wantErr)
+			}
+			st := s.Status()[0]
+			if st.State != StateExited || st.Restarts != tt.restarts {
+				t.Errorf("status = %s with %d restarts; want exited with %d", st.State, st.Restarts, tt.restarts)
+			}
+			if n := strings.Count(strings.Join(rec.Messages(), "\n"), "process started"); n != tt.restarts+1 {
+				t.Errorf("started %d times; want %d", n, tt.restarts+1)
+			}
+		})
+	}
+}
+
+func TestOutput(t *testing.T) {
+	_, rec, done := start(t, context.Background(), Process{
+		Name:    "talker",
+		Command: sh(t, "echo one; echo two >&2; printf 'no newline'"),
+		Prefix:  "[talk] ",
+	})
+	if err := wait(t, done); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		msg    string
+		level  slog.Level
+		stream string
+	}{
+		{"[talk] one", slog.LevelInfo, "stdout"},
+		{"[talk] two", slog.LevelWarn, "stderr"},
+		{"[talk] no newline", slog.LevelInfo, "stdout"},
+	}
+	for _, tt := range tests {
+		r, ok := rec.Find(tt.msg)
+		if !ok {
+			t.Errorf("%q not logged in %q", tt.msg, rec.Messages())
+			continue
+		}
+		attrs := logging.AttrsOf(r)
+		if r.Level != tt.level || attrs["stream"].String() != tt.stream || attrs["process"].String() != "talker" {
+			t.Errorf("%q: level %v, attrs %v", tt.msg, r.Level, attrs)
+		}
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{log: func(line string) { lines = append(lines, line) }}
+	w.Write([]byte("a\r\nb"))
+	w.Write([]byte("c\n\n"))
+	w.Write([]byte(strings.Repeat("x", maxLine+10)))
+	w.Flush()
+	want := []string{"a", "bc", "", strings.Repeat("x", maxLine), "xxxxxxxxxx"}
+	if !slices.Equal(lines, want) {
+		t.Errorf("lines = %.40q; want %.40q", lines, want)
+	}
+}
+
+func TestStop(t *testing.T) {
+	t.Run("sigterm", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s, rec, done := start(t, ctx, Process{
+			Name:    "polite",
+			Command: sh(t, `trap 'echo terminated; exit 0' TERM; echo ready; while :; do sleep 0.05; done`),
+			Restart: RestartAlways,
+			Grace:   5 * time.Second,
+		})
+		eventually(t, "ready", func() bool { _, ok := rec.Find("polite: ready"); return ok })
+		begin := time.Now()
+		cancel()
+		if err := wait(t, done); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(begin); d > 3*time.Second {
+			t.Errorf("stopping took %v, the process was killed after the grace period", d)
+		}
+		if _, ok := rec.Find("polite: terminated"); !ok {
+			t.Errorf("the process didn't get SIGTERM: %q", rec.Messages())
+		}
+		if st := s.Status()[0]; st.State != StateStopped || st.Restarts != 0 {
+			t.Errorf("status = %+v", st)
+		}
+	})
+
+	t.Run("sigkill after the grace period", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, rec, done := start(t, ctx, Process{
+			Name:    "stubborn",
+			Command: sh(t, `trap '' TERM; echo ready; while :; do sleep 0.05; done`),
+			Grace:   200 * time.Millisecond,
+		})
+		eventually(t, "ready", func() bool { _, ok := rec.Find("stubborn: ready"); return ok })
+		begin := time.Now()
+		cancel()
+		if err := wait(t, done); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(begin); d < 200*time.Millisecond {
+			t.Errorf("stopped after %v, before the grace period", d)
+		}
+		if _, ok := rec.Find("process still running after the grace period, killing it"); !ok {
+			t.Errorf("not killed: %q", rec.Messages())
+		}
+	})
+}
+
+func TestHealthCheck(t *testing.T) {
+	t.Run("failing checks restart the process", func(t *testing.T) {
+		s, rec, done := start(t, context.Background(), Process{
+			Name:    "sick",
+			Command: sh(t, "sleep 30"),
+			Restart: RestartNever,
+			Grace:   time.Second,
+			Health:  &HealthCheck{Command: sh(t, "echo down; exit 1"), Interval: 20 * time.Millisecond, Retries: 2},
+		})
+		err := wait(t, done)
+		if err == nil || !strings.Contains(err.Error(), "unhealthy: exit status 1: down") {
+			t.Errorf("err = %v", err)
+		}
+		if st := s.Status()[0]; st.Healthy || st.State != StateExited {
+			t.Errorf("status = %+v", st)
+		}
+		if n := strings.Count(strings.Join(rec.Messages(), "\n"), "health check failed"); n != 2 {
+			t.Errorf("%d failed checks logged; want 2", n)
+		}
+	})
+
+	t.Run("passing checks", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s, _, done := start(t, ctx, Process{
+			Name:    "fine",
+			Command: sh(t, "sleep 30"),
+			Health:  &HealthCheck{Command: sh(t, "exit 0"), Interval: 20 * time.Millisecond},
+		})
+		eventually(t, "healthy", func() bool { return s.Status()[0].Healthy })
+		cancel()
+		if err := wait(t, done); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("url", func(t *testing.T) {
+		var up atomic.Bool
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !up.Load() {
+				http.Error(w, "starting", http.StatusServiceUnavailable)
+			}
+		}))
+		defer srv.Close()
+		h := HealthCheck{URL: srv.URL}
+		if err := h.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "503") {
+			t.Errorf("check while down = %v", err)
+		}
+		up.Store(true)
+		if err := h.Check(context.Background()); err != nil {
+			t.Errorf("check while up = %v", err)
+		}
+	})
+}
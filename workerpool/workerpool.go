@@ -0,0 +1,240 @@
+// Package workerpool is a reusable version of the worker pool pattern from the goroutine example:
+// a fixed number of workers receive jobs on a channel and send the results back on another one.
+// on top of that it handles errors, cancellation and, if asked, keeps results in submission order.
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned by Submit once the pool has been closed
+var ErrClosed = errors.New("workerpool: pool is closed")
+
+// Func is the work done for a single job. it should return early once ctx is done
+type Func[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Order decides how results are delivered on the Results channel
+type Order int
+
+const (
+	Unordered Order = iota // results are delivered as soon as a worker finishes them
+	Ordered                // results are delivered in the order the jobs were submitted
+)
+
+// Result is the output of a single successful job.
+// Seq is the position of the job in submission order, starting at 0
+type Result[Out any] struct {
+	Seq   int
+	Value Out
+}
+
+// job is an input tagged with its submission sequence number
+type job[In any] struct {
+	seq int
+	in  In
+}
+
+// outcome is what a worker reports back for every job, including failed and skipped ones,
+// so that the ordered collector knows when a sequence number will never produce a result
+type outcome[Out any] struct {
+	seq int
+	out Out
+	err error
+}
+
+// Pool runs jobs on a fixed number of goroutines.
+// the first job that fails cancels the context passed to all the other jobs,
+// jobs that haven't started yet are skipped and Wait reports that first error.
+// results must be drained from Results, otherwise the workers block once its buffer is full
+type Pool[In, Out any] struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	fn     Func[In, Out]
+
+	mu     sync.Mutex // guards seq and closed, and serializes sends on jobs
+	seq    int
+	closed bool
+
+	jobs     chan job[In]
+	outcomes chan outcome[Out]
+	results  chan Result[Out]
+	done     chan struct{} // closed once the collector has delivered every result
+
+	errOnce sync.Once
+	err     error
+
+	waitOnce sync.Once
+	waitErr  error
+}
+
+// New starts a pool of workers goroutines running fn.
+// the pool stops early when ctx is cancelled. workers less than 1 is treated as 1
+func New[In, Out any](ctx context.Context, workers int, order Order, fn Func[In, Out]) *Pool[In, Out] {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	p := &Pool[In, Out]{
+		ctx:      ctx,
+		cancel:   cancel,
+		fn:       fn,
+		jobs:     make(chan job[In], workers),
+		outcomes: make(chan outcome[Out], workers),
+		results:  make(chan Result[Out], workers),
+		done:     make(chan struct{}),
+	}
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work()
+		}()
+	}
+	// close outcomes once every worker has returned so the collector knows there is nothing left
+	go func() {
+		wg.Wait()
+		close(p.outcomes)
+	}()
+	go p.collect(order)
+	// once the pool is cancelled nothing more can run, so stop accepting jobs.
+	// this lets Results be drained to the end even if the producer never calls Close
+	go func() {
+		<-ctx.Done()
+		p.Close()
+	}()
+	return p
+}
+
+// work is the body of a single worker goroutine, like worker2 in the goroutine example
+func (p *Pool[In, Out]) work() {
+	for j := range p.jobs {
+		// keep draining jobs after a cancellation, but don't run them anymore
+		if err := p.ctx.Err(); err != nil {
+			p.outcomes <- outcome[Out]{seq: j.seq, err: err}
+			continue
+		}
+		out, err := p.fn(p.ctx, j.in)
+		if err != nil {
+			p.fail(err)
+		}
+		p.outcomes <- outcome[Out]{seq: j.seq, out: out, err: err}
+	}
+}
+
+// fail records the first error and cancels the remaining work
+func (p *Pool[In, Out]) fail(err error) {
+	p.errOnce.Do(func() {
+		p.err = err
+		p.cancel(err)
+	})
+}
+
+// collect forwards successful outcomes to the results channel.
+// in Ordered mode, outcomes that arrive early are held back until all the previous ones are in
+func (p *Pool[In, Out]) collect(order Order) {
+	defer close(p.done)
+	defer close(p.results)
+
+	next := 0
+	pending := make(map[int]outcome[Out])
+	for o := range p.outcomes {
+		if order == Unordered {
+			p.deliver(o)
+			continue
+		}
+		pending[o.seq] = o
+		for {
+			o, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			p.deliver(o)
+			next++
+		}
+	}
+}
+
+func (p *Pool[In, Out]) deliver(o outcome[Out]) {
+	if o.err == nil {
+		p.results <- Result[Out]{Seq: o.seq, Value: o.out}
+	}
+}
+
+// Submit queues in to be processed, blocking while all the workers are busy.
+// it returns ErrClosed after Close, or the cancellation cause once the pool has stopped
+func (p *Pool[In, Out]) Submit(in In) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	if p.ctx.Err() != nil {
+		return context.Cause(p.ctx)
+	}
+	select {
+	case p.jobs <- job[In]{seq: p.seq, in: in}:
+		p.seq++
+		return nil
+	case <-p.ctx.Done():
+		return context.Cause(p.ctx)
+	}
+}
+
+// Results returns the channel results are delivered on. it is closed once every job is done
+func (p *Pool[In, Out]) Results() <-chan Result[Out] {
+	return p.results
+}
+
+// Close stops the pool from accepting new jobs, jobs already submitted still run.
+// it is safe to call Close more than once
+func (p *Pool[In, Out]) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+}
+
+// Wait closes the pool and blocks until every submitted job is done and its result delivered.
+// it returns the first error returned by a job, or the context error if the parent context was cancelled
+func (p *Pool[In, Out]) Wait() error {
+	p.Close()
+	<-p.done
+	p.waitOnce.Do(func() {
+		p.waitErr = p.err
+		if p.waitErr == nil && p.ctx.Err() != nil {
+			p.waitErr = context.Cause(p.ctx)
+		}
+		p.cancel(nil) // release the context resources
+	})
+	return p.waitErr
+}
+
+// Map runs fn over every input with the given number of workers and returns the outputs in input order.
+// it stops at the first error and returns it
+func Map[In, Out any](ctx context.Context, workers int, inputs []In, fn Func[In, Out]) ([]Out, error) {
+	p := New(ctx, workers, Ordered, fn)
+	go func() {
+		defer p.Close()
+		for _, in := range inputs {
+			if p.Submit(in) != nil {
+				return
+			}
+		}
+	}()
+
+	outs := make([]Out, 0, len(inputs))
+	for r := range p.Results() {
+		outs = append(outs, r.Value)
+	}
+	if err := p.Wait(); err != nil {
+		return nil, err
+	}
+	return outs, nil
+}
@@ -0,0 +1,162 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// double is the job from the goroutine example, the delay makes later jobs finish first
+func double(ctx context.Context, n int) (int, error) {
+	select {
+	case <-time.After(time.Duration(10-n) * time.Millisecond):
+		return n * 2, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func TestOrdered(t *testing.T) {
+	p := New(context.Background(), 3, Ordered, double)
+	go func() {
+		for n := range 10 {
+			if err := p.Submit(n); err != nil {
+				t.Errorf("Submit(%d): %v", n, err)
+			}
+		}
+		p.Close()
+	}()
+
+	var got []int
+	for r := range p.Results() {
+		if r.Value != r.Seq*2 {
+			t.Errorf("result %d has seq %d", r.Value, r.Seq)
+		}
+		got = append(got, r.Value)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	want := []int{0, 2, 4, 6, 8, 10, 12, 14, 16, 18}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestUnordered(t *testing.T) {
+	p := New(context.Background(), 4, Unordered, double)
+	go func() {
+		for n := range 8 {
+			p.Submit(n)
+		}
+		p.Close()
+	}()
+
+	var got []int
+	for r := range p.Results() {
+		got = append(got, r.Value)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	// every result arrives exactly once, in whatever order the workers finish
+	slices.Sort(got)
+	if want := []int{0, 2, 4, 6, 8, 10, 12, 14}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFirstErrorCancelsRemainingWork(t *testing.T) {
+	errBoom := errors.New("boom")
+	var started atomic.Int32
+	p := New(context.Background(), 2, Unordered, func(ctx context.Context, n int) (int, error) {
+		started.Add(1)
+		if n == 0 {
+			return 0, errBoom
+		}
+		// the other jobs wait for the cancellation caused by job 0
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return n, nil
+		}
+	})
+
+	go func() {
+		defer p.Close()
+		for n := range 100 {
+			if err := p.Submit(n); err != nil {
+				// once the pool is cancelled, Submit reports the error that caused it
+				if !errors.Is(err, errBoom) {
+					t.Errorf("Submit returned %v; want %v", err, errBoom)
+				}
+				return
+			}
+		}
+	}()
+
+	for r := range p.Results() {
+		t.Errorf("unexpected result %v", r)
+	}
+	if err := p.Wait(); !errors.Is(err, errBoom) {
+		t.Errorf("Wait = %v; want %v", err, errBoom)
+	}
+	if n := started.Load(); n >= 100 {
+		t.Errorf("%d jobs started, the error should have skipped most of them", n)
+	}
+}
+
+func TestParentContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx, 2, Ordered, double)
+	cancel()
+	if err := p.Submit(1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Submit after cancel = %v; want %v", err, context.Canceled)
+	}
+	for range p.Results() {
+	}
+	if err := p.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestSubmitAfterClose(t *testing.T) {
+	p := New(context.Background(), 1, Unordered, double)
+	p.Close()
+	p.Close() // closing twice is fine
+	if err := p.Submit(1); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Close = %v; want %v", err, ErrClosed)
+	}
+	if err := p.Wait(); err != nil {
+		t.Errorf("Wait = %v", err)
+	}
+	// Wait keeps returning the same answer
+	if err := p.Wait(); err != nil {
+		t.Errorf("second Wait = %v", err)
+	}
+}
+
+func TestMap(t *testing.T) {
+	got, err := Map(context.Background(), 3, []int{1, 2, 3, 4, 5}, double)
+	if err != nil {
+		t.Fatalf("Map: %v", err)
+	}
+	if want := []int{2, 4, 6, 8, 10}; !slices.Equal(got, want) {
+		t.Errorf("Map = %v; want %v", got, want)
+	}
+
+	errOdd := errors.New("odd")
+	_, err = Map(context.Background(), 3, []int{2, 4, 5, 6}, func(ctx context.Context, n int) (int, error) {
+		if n%2 == 1 {
+			return 0, errOdd
+		}
+		return n, nil
+	})
+	if !errors.Is(err, errOdd) {
+		t.Errorf("Map error = %v; want %v", err, errOdd)
+	}
+}